@@ -0,0 +1,324 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"infinity/rms/migrations"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FormatVersion is bumped whenever the archive layout changes. Restore
+// reads archives up to this version.
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Skipped collections aren't backed up: the outbox and webhook deliveries
+// are work in flight, webhook subscriptions point at the old environment
+// and the migration records belong to the target database.
+var Skipped = []string{"outbox", "webhook", "webhookDelivery", "schemaMigration"}
+
+// Redacted fields are left out of the archive. A Replace restore keeps
+// their current value, restored users without one can't log in until
+// their password is reset with rmsctl reset-password.
+var Redacted = map[string][]string{
+	"users": {"password", "token", "refresh_token"},
+}
+
+// Manifest comes first in an archive and describes the rest
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Database      string    `json:"database"`
+	SchemaVersion int       `json:"schema_version"`
+	Collections   []Entry   `json:"collections"`
+}
+
+// Entry is one collection in the archive
+type Entry struct {
+	Name      string   `json:"name"`
+	File      string   `json:"file"`
+	Documents int      `json:"documents"`
+	SHA256    string   `json:"sha256"`
+	Redacted  []string `json:"redacted,omitempty"`
+}
+
+// Export writes every collection of db to w as a tar.gz archive: the
+// manifest followed by a JSON Lines file per collection. Collections are
+// read one after another, so the copy is only consistent if nothing
+// writes while it runs.
+func Export(ctx context.Context, db *mongo.Database, w io.Writer) (Manifest, error) {
+	createdAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	manifest := Manifest{FormatVersion: FormatVersion, CreatedAt: createdAt, Database: db.Name(), Collections: []Entry{}}
+
+	version, err := migrations.New(db).Version(ctx)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.SchemaVersion = version
+
+	names, err := db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return manifest, err
+	}
+	sort.Strings(names)
+
+	// tar needs each file's size up front, so collections are spooled to
+	// temporary files first
+	spools := map[string]*os.File{}
+	defer func() {
+		for _, spool := range spools {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+	}()
+	for _, name := range names {
+		if contains(Skipped, name) || strings.HasPrefix(name, "system.") {
+			continue
+		}
+		spool, err := os.CreateTemp("", "rms-backup-*.jsonl")
+		if err != nil {
+			return manifest, err
+		}
+		spools[name] = spool
+
+		hash := sha256.New()
+		count, err := DumpCollection(ctx, db.Collection(name), io.MultiWriter(spool, hash), Redacted[name])
+		if err != nil {
+			return manifest, fmt.Errorf("backup: %s: %w", name, err)
+		}
+		manifest.Collections = append(manifest.Collections, Entry{
+			Name:      name,
+			File:      "collections/" + name + ".jsonl",
+			Documents: count,
+			SHA256:    hex.EncodeToString(hash.Sum(nil)),
+			Redacted:  Redacted[name],
+		})
+	}
+
+	return manifest, writeArchive(w, manifest, spools)
+}
+
+// writeArchive writes the manifest and then every collection's spool, in
+// manifest order
+func writeArchive(w io.Writer, manifest Manifest, spools map[string]*os.File) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	manifestData, _ := json.MarshalIndent(manifest, "", "  ")
+	if err := writeFile(archive, manifestName, int64(len(manifestData)), strings.NewReader(string(manifestData)), manifest.CreatedAt); err != nil {
+		return err
+	}
+	for _, entry := range manifest.Collections {
+		spool := spools[entry.Name]
+		info, err := spool.Stat()
+		if err != nil {
+			return err
+		}
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := writeFile(archive, entry.File, info.Size(), spool, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeFile(archive *tar.Writer, name string, size int64, r io.Reader, modified time.Time) error {
+	err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modified, Typeflag: tar.TypeReg})
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(archive, r, size)
+	return err
+}
+
+// readArchive reads the manifest and hands every collection file to fn,
+// in archive order
+func readArchive(r io.Reader, fn func(manifest Manifest, entry Entry, r io.Reader) error) (Manifest, error) {
+	var manifest Manifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, fmt.Errorf("backup: not a tar.gz archive: %w", err)
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+
+	header, err := archive.Next()
+	if err != nil || header.Name != manifestName {
+		return manifest, errors.New("backup: archive doesn't start with a manifest")
+	}
+	if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("backup: bad manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return manifest, fmt.Errorf("backup: archive format %d, this build reads up to %d", manifest.FormatVersion, FormatVersion)
+	}
+	entries := map[string]Entry{}
+	for _, entry := range manifest.Collections {
+		entries[entry.File] = entry
+	}
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return manifest, nil
+		}
+		if err != nil {
+			return manifest, err
+		}
+		entry, ok := entries[path.Clean(header.Name)]
+		if !ok {
+			return manifest, fmt.Errorf("backup: %s is not in the manifest", header.Name)
+		}
+		if err := fn(manifest, entry, archive); err != nil {
+			return manifest, err
+		}
+	}
+}
+
+// Verify checks an archive against its manifest: the format, every
+// collection's checksum and document count, and that none is missing
+func Verify(r io.Reader) (Manifest, error) {
+	seen := map[string]bool{}
+	manifest, err := readArchive(r, func(manifest Manifest, entry Entry, r io.Reader) error {
+		if contains(Skipped, entry.Name) {
+			return fmt.Errorf("backup: %s is never restored", entry.Name)
+		}
+		hash := sha256.New()
+		count := 0
+		err := eachLine(io.TeeReader(r, hash), func(line int, doc bson.D) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("backup: %s: %w", entry.Name, err)
+		}
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != entry.SHA256 {
+			return fmt.Errorf("backup: %s checksum is %s, the manifest says %s", entry.Name, sum, entry.SHA256)
+		}
+		if count != entry.Documents {
+			return fmt.Errorf("backup: %s has %d documents, the manifest says %d", entry.Name, count, entry.Documents)
+		}
+		seen[entry.Name] = true
+		return nil
+	})
+	if err != nil {
+		return manifest, err
+	}
+	for _, entry := range manifest.Collections {
+		if !seen[entry.Name] {
+			return manifest, fmt.Errorf("backup: %s is missing from the archive", entry.Name)
+		}
+	}
+	return manifest, nil
+}
+
+// Report is what a restore did to each collection
+type Report struct {
+	Manifest    Manifest              `json:"manifest"`
+	Collections map[string]LoadResult `json:"collections"`
+}
+
+// Restore loads the archive at file into db. The whole archive is
+// verified first, and with Fail any _id that is already taken stops it
+// before anything is written. Migrations newer than the archive are run
+// again over the restored data.
+func Restore(ctx context.Context, db *mongo.Database, file string, policy Policy) (Report, error) {
+	report := Report{Collections: map[string]LoadResult{}}
+	migrator := migrations.New(db)
+
+	manifest, err := withFile(file, Verify)
+	if err != nil {
+		return report, err
+	}
+	report.Manifest = manifest
+	if manifest.SchemaVersion > migrator.Latest() {
+		return report, fmt.Errorf("backup: archive has schema version %d, this build knows up to %d", manifest.SchemaVersion, migrator.Latest())
+	}
+	if policy == Fail {
+		if err := checkConflicts(ctx, db, file); err != nil {
+			return report, err
+		}
+	}
+
+	_, err = withFile(file, func(r io.Reader) (Manifest, error) {
+		return readArchive(r, func(manifest Manifest, entry Entry, r io.Reader) error {
+			result, err := LoadCollection(ctx, db.Collection(entry.Name), r, policy, len(entry.Redacted) > 0)
+			report.Collections[entry.Name] = result
+			if err != nil {
+				return fmt.Errorf("backup: restoring %s: %w", entry.Name, err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return report, err
+	}
+	return report, migrator.Rerun(ctx, manifest.SchemaVersion)
+}
+
+// checkConflicts fails on the first collection holding an _id that is
+// also in the archive
+func checkConflicts(ctx context.Context, db *mongo.Database, file string) error {
+	_, err := withFile(file, func(r io.Reader) (Manifest, error) {
+		return readArchive(r, func(manifest Manifest, entry Entry, r io.Reader) error {
+			ids := bson.A{}
+			check := func() error {
+				if len(ids) == 0 {
+					return nil
+				}
+				count, err := db.Collection(entry.Name).CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}})
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					return fmt.Errorf("backup: %d documents in %s already exist, restore with skip or replace", count, entry.Name)
+				}
+				ids = bson.A{}
+				return nil
+			}
+			err := eachLine(r, func(line int, doc bson.D) error {
+				if id, ok := doc.Map()["_id"]; ok {
+					ids = append(ids, id)
+				}
+				if len(ids) == batchSize {
+					return check()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return check()
+		})
+	})
+	return err
+}
+
+func withFile(file string, fn func(r io.Reader) (Manifest, error)) (Manifest, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer f.Close()
+	return fn(f)
+}
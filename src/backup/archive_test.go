@@ -0,0 +1,137 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCollections = map[string]string{
+	"food":  `{"_id":{"$oid":"64b7f0c2a1b2c3d4e5f60718"},"name":"Soup","price":{"$numberDouble":"4.5"}}` + "\n" + `{"_id":{"$oid":"64b7f0c2a1b2c3d4e5f60719"},"name":"Bread"}` + "\n",
+	"users": `{"_id":{"$oid":"64b7f0c2a1b2c3d4e5f6071a"},"email":"a@example.com","created_at":{"$date":{"$numberLong":"1689710000000"}}}` + "\n",
+}
+
+// testManifest describes testCollections the way Export would
+func testManifest() Manifest {
+	createdAt, _ := time.Parse(time.RFC3339, "2023-07-19T10:00:00Z")
+	manifest := Manifest{FormatVersion: FormatVersion, CreatedAt: createdAt, Database: "rms", SchemaVersion: 2}
+	for _, name := range []string{"food", "users"} {
+		sum := sha256.Sum256([]byte(testCollections[name]))
+		manifest.Collections = append(manifest.Collections, Entry{
+			Name:      name,
+			File:      "collections/" + name + ".jsonl",
+			Documents: strings.Count(testCollections[name], "\n"),
+			SHA256:    hex.EncodeToString(sum[:]),
+			Redacted:  Redacted[name],
+		})
+	}
+	return manifest
+}
+
+func writeTestArchive(t *testing.T, manifest Manifest) []byte {
+	t.Helper()
+	spools := map[string]*os.File{}
+	for _, entry := range manifest.Collections {
+		spool, err := os.CreateTemp(t.TempDir(), "*.jsonl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer spool.Close()
+		spool.WriteString(testCollections[entry.Name])
+		spools[entry.Name] = spool
+	}
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, manifest, spools); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	manifest := testManifest()
+	data := writeTestArchive(t, manifest)
+
+	files := map[string]string{}
+	read, err := readArchive(bytes.NewReader(data), func(manifest Manifest, entry Entry, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		files[entry.Name] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(read)
+	want, _ := json.Marshal(manifest)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("manifest came back as %s, want %s", got, want)
+	}
+	for name, content := range testCollections {
+		if files[name] != content {
+			t.Errorf("%s came back as %q, want %q", name, files[name], content)
+		}
+	}
+
+	if _, err := Verify(bytes.NewReader(data)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+}
+
+func TestVerifyDetectsChecksumMismatch(t *testing.T) {
+	manifest := testManifest()
+	manifest.Collections[1].SHA256 = strings.Repeat("0", 64)
+	_, err := Verify(bytes.NewReader(writeTestArchive(t, manifest)))
+	if err == nil || !strings.Contains(err.Error(), "users checksum") {
+		t.Fatalf("got %v, want a users checksum error", err)
+	}
+}
+
+func TestVerifyDetectsDocumentCountMismatch(t *testing.T) {
+	manifest := testManifest()
+	manifest.Collections[0].Documents = 3
+	_, err := Verify(bytes.NewReader(writeTestArchive(t, manifest)))
+	if err == nil || !strings.Contains(err.Error(), "food has 2 documents") {
+		t.Fatalf("got %v, want a document count error", err)
+	}
+}
+
+func TestVerifyDetectsMissingCollection(t *testing.T) {
+	manifest := testManifest()
+
+	// the manifest lists users but the archive stops after food
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	manifestData, _ := json.Marshal(manifest)
+	writeFile(archive, manifestName, int64(len(manifestData)), bytes.NewReader(manifestData), manifest.CreatedAt)
+	food := testCollections["food"]
+	writeFile(archive, manifest.Collections[0].File, int64(len(food)), strings.NewReader(food), manifest.CreatedAt)
+	archive.Close()
+	gz.Close()
+
+	_, err := Verify(&buf)
+	if err == nil || !strings.Contains(err.Error(), "users is missing") {
+		t.Fatalf("got %v, want users to be missing", err)
+	}
+}
+
+func TestVerifyRejectsNewerFormat(t *testing.T) {
+	manifest := testManifest()
+	manifest.FormatVersion = FormatVersion + 1
+	if _, err := Verify(bytes.NewReader(writeTestArchive(t, manifest))); err == nil {
+		t.Fatal("read an archive of a newer format")
+	}
+}
+
+func TestVerifyRejectsOtherFiles(t *testing.T) {
+	if _, err := Verify(strings.NewReader("not an archive")); err == nil {
+		t.Fatal("verified something that isn't a tar.gz")
+	}
+}
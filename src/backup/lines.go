@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Policy says what to do with a document whose _id is already taken
+type Policy string
+
+const (
+	Skip    Policy = "skip"
+	Replace Policy = "replace"
+	Fail    Policy = "fail"
+)
+
+func ParsePolicy(value string) (Policy, error) {
+	switch Policy(value) {
+	case Skip, Replace, Fail:
+		return Policy(value), nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q, use skip, replace or fail", value)
+}
+
+const batchSize = 500
+
+// LoadResult counts what loading a collection did
+type LoadResult struct {
+	Inserted int64 `json:"inserted"`
+	Replaced int64 `json:"replaced"`
+	Skipped  int64 `json:"skipped"`
+}
+
+// DumpCollection writes every document of collection to w as canonical
+// extended JSON, one per line, so dates and ids keep their type. Fields in
+// redact are left out. It returns the number of documents written.
+func DumpCollection(ctx context.Context, collection *mongo.Collection, w io.Writer, redact []string) (int, error) {
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	buffered := bufio.NewWriter(w)
+	count := 0
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return count, err
+		}
+		line, err := bson.MarshalExtJSON(without(doc, redact), true, false)
+		if err != nil {
+			return count, err
+		}
+		buffered.Write(line)
+		if err := buffered.WriteByte('\n'); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	return count, buffered.Flush()
+}
+
+// LoadCollection writes the documents DumpCollection wrote into
+// collection. With merge, a Replace only sets the fields in the line, so
+// fields that were redacted on the way out keep their current value.
+func LoadCollection(ctx context.Context, collection *mongo.Collection, r io.Reader, policy Policy, merge bool) (LoadResult, error) {
+	result := LoadResult{}
+	writes := []mongo.WriteModel{}
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		// a skip carries on past documents that clash on another unique index
+		bulk, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(policy != Skip))
+		if bulk != nil {
+			if policy == Skip {
+				result.Inserted += bulk.UpsertedCount
+				result.Skipped += int64(len(writes)) - bulk.UpsertedCount
+			} else {
+				result.Inserted += bulk.InsertedCount + bulk.UpsertedCount
+				result.Replaced += bulk.MatchedCount
+			}
+		}
+		if err != nil && !(policy == Skip && onlyDuplicates(err)) {
+			return err
+		}
+		writes = writes[:0]
+		return nil
+	}
+
+	err := eachLine(r, func(line int, doc bson.D) error {
+		id, hasId := doc.Map()["_id"]
+		switch {
+		case !hasId || policy == Fail:
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(doc))
+		case policy == Skip:
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{"$setOnInsert": doc}).
+				SetUpsert(true))
+		case merge:
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{"$set": without(doc, []string{"_id"})}).
+				SetUpsert(true))
+		default:
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetReplacement(doc).
+				SetUpsert(true))
+		}
+		if len(writes) == batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, flush()
+}
+
+// eachLine decodes every non-empty line of r, numbering lines from 1
+func eachLine(r io.Reader, fn func(line int, doc bson.D) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &doc); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(line, doc); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func without(doc bson.D, fields []string) bson.D {
+	if len(fields) == 0 {
+		return doc
+	}
+	kept := bson.D{}
+	for _, element := range doc {
+		if !contains(fields, element.Key) {
+			kept = append(kept, element)
+		}
+	}
+	return kept
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func onlyDuplicates(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
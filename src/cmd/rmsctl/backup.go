@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"infinity/rms/backup"
+	"infinity/rms/database"
+)
+
+func backupCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := flags.String("o", "", "archive to write, rms-<time>.tar.gz by default")
+	flags.Parse(args)
+	if *output == "" {
+		*output = "rms-" + time.Now().Format("20060102-150405") + ".tar.gz"
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.Export(ctx, database.OpenDatabase(database.Client), file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+	for _, entry := range manifest.Collections {
+		fmt.Printf("%-24s %d\n", entry.Name, entry.Documents)
+	}
+	fmt.Printf("wrote %s at schema version %d\n", *output, manifest.SchemaVersion)
+	return nil
+}
+
+func restoreCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	onConflict := flags.String("on-conflict", "fail", "skip, replace or fail")
+	verifyOnly := flags.Bool("verify", false, "only check the archive")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("restore needs an archive")
+	}
+	policy, err := backup.ParsePolicy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	if *verifyOnly {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		manifest, err := backup.Verify(file)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is intact: %d collections from %s, schema version %d\n",
+			flags.Arg(0), len(manifest.Collections), manifest.CreatedAt.Format(time.RFC3339), manifest.SchemaVersion)
+		return nil
+	}
+
+	report, err := backup.Restore(ctx, database.OpenDatabase(database.Client), flags.Arg(0), policy)
+	names := []string{}
+	for name := range report.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result := report.Collections[name]
+		fmt.Printf("%-24s %d inserted, %d replaced, %d skipped\n", name, result.Inserted, result.Replaced, result.Skipped)
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range report.Manifest.Collections {
+		if len(entry.Redacted) > 0 {
+			fmt.Printf("%s came without %v, new ones need reset-password\n", entry.Name, entry.Redacted)
+		}
+	}
+	return nil
+}
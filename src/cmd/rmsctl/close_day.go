@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	controller "infinity/rms/controllers"
+)
+
+func closeDay(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("close-day", flag.ExitOnError)
+	date := flags.String("date", "", "day to close as YYYY-MM-DD, today by default")
+	closedBy := flags.String("by", os.Getenv("USER"), "who is closing the day")
+	flags.Parse(args)
+
+	day := time.Now()
+	if *date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", *date, time.Local)
+		if err != nil {
+			return fmt.Errorf("bad -date %q", *date)
+		}
+		day = parsed
+	}
+
+	businessDay, err := controller.CloseBusinessDay(ctx, day, *closedBy)
+	if err != nil {
+		return err
+	}
+	out, _ := json.MarshalIndent(businessDay, "", "  ")
+	fmt.Println(string(out))
+	if len(businessDay.OpenOrders) > 0 {
+		fmt.Fprintf(os.Stderr, "%d orders are still unpaid\n", len(businessDay.OpenOrders))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"infinity/rms/backup"
+	"infinity/rms/database"
+)
+
+// export writes a collection as one canonical extended JSON document per
+// line, which keeps dates and ids their type on import
+func export(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "", "file to write, stdout by default")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("export needs a collection name")
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	count, err := backup.DumpCollection(ctx, database.OpenCollection(database.Client, flags.Arg(0)), w, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d documents from %s\n", count, flags.Arg(0))
+	return nil
+}
+
+// importCollection loads what export wrote. -on-conflict says what to do
+// with a document whose _id is taken: skip it, replace it or fail.
+func importCollection(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	input := flags.String("i", "", "file to read, stdin by default")
+	onConflict := flags.String("on-conflict", "fail", "skip, replace or fail")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("import needs a collection name")
+	}
+	policy, err := backup.ParsePolicy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	result, err := backup.LoadCollection(ctx, database.OpenCollection(database.Client, flags.Arg(0)), r, policy, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d inserted, %d replaced, %d skipped\n", flags.Arg(0), result.Inserted, result.Replaced, result.Skipped)
+	return nil
+}
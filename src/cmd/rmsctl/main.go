@@ -0,0 +1,60 @@
+// rmsctl runs maintenance tasks against the restaurant database
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"infinity/rms/database"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate":        {"migrate up [version] | down <version> | status | indexes", migrate},
+	"create-admin":   {"create-admin -email <email> [-phone <phone>] [-first-name <name>] [-last-name <name>]", createAdmin},
+	"reset-password": {"reset-password -email <email>", resetPassword},
+	"seed":           {"seed [-tables <count>]", seed},
+	"export":         {"export [-o <file>] <collection>", export},
+	"import":         {"import [-i <file>] [-on-conflict skip|replace|fail] <collection>", importCollection},
+	"close-day":      {"close-day [-date YYYY-MM-DD] [-by <name>]", closeDay},
+	"backup":         {"backup [-o <archive>]", backupCommand},
+	"restore":        {"restore [-on-conflict skip|replace|fail] [-verify] <archive>", restoreCommand},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: rmsctl <command> [arguments]")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  rmsctl "+commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := database.RequireTransactions(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, "rmsctl:", err)
+		os.Exit(1)
+	}
+	if err := cmd.run(context.Background(), os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "rmsctl:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"infinity/rms/database"
+	"infinity/rms/migrations"
+)
+
+func migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate needs up, down, status or indexes")
+	}
+	migrator := migrations.New(database.OpenDatabase(database.Client))
+
+	switch args[0] {
+	case "up":
+		target := 0
+		if len(args) > 1 {
+			version, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("bad version %q", args[1])
+			}
+			target = version
+		}
+		return migrator.Up(ctx, target)
+	case "down":
+		if len(args) < 2 {
+			return errors.New("migrate down needs the version to go back to, 0 for none")
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("bad version %q", args[1])
+		}
+		return migrator.Down(ctx, target)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tAPPLIED\tREVERSIBLE\tDESCRIPTION")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%t\t%s\n", status.Version, applied, status.Reversible, status.Description)
+		}
+		return w.Flush()
+	case "indexes":
+		return migrator.EnsureIndexes(ctx)
+	}
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
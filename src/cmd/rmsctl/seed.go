@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	controller "infinity/rms/controllers"
+	"infinity/rms/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var demoMenu = []controller.MenuImportRow{
+	{MenuSKU: "DEMO-STARTERS", MenuName: "Starters", MenuCategory: "Starters", SKU: "DEMO-BRUSCHETTA", Name: "Bruschetta", Price: 6.5, Allergens: []string{"GLUTEN"}, DietaryTags: []string{"VEGAN"}},
+	{MenuSKU: "DEMO-STARTERS", MenuName: "Starters", MenuCategory: "Starters", SKU: "DEMO-SOUP", Name: "Tomato soup", Price: 5.5, Allergens: []string{"CELERY"}, DietaryTags: []string{"VEGETARIAN"}},
+	{MenuSKU: "DEMO-STARTERS", MenuName: "Starters", MenuCategory: "Starters", SKU: "DEMO-CALAMARI", Name: "Fried calamari", Price: 8, Allergens: []string{"MOLLUSCS", "GLUTEN", "EGGS"}},
+	{MenuSKU: "DEMO-MAINS", MenuName: "Mains", MenuCategory: "Mains", SKU: "DEMO-BURGER", Name: "Cheeseburger", Price: 14, Allergens: []string{"GLUTEN", "MILK", "SESAME"}},
+	{MenuSKU: "DEMO-MAINS", MenuName: "Mains", MenuCategory: "Mains", SKU: "DEMO-RISOTTO", Name: "Mushroom risotto", Price: 13.5, Allergens: []string{"MILK"}, DietaryTags: []string{"VEGETARIAN", "GLUTEN_FREE"}},
+	{MenuSKU: "DEMO-MAINS", MenuName: "Mains", MenuCategory: "Mains", SKU: "DEMO-SALMON", Name: "Grilled salmon", Price: 18, Allergens: []string{"FISH"}, DietaryTags: []string{"GLUTEN_FREE", "DAIRY_FREE"}},
+	{MenuSKU: "DEMO-DESSERTS", MenuName: "Desserts", MenuCategory: "Desserts", SKU: "DEMO-TIRAMISU", Name: "Tiramisu", Price: 7, Allergens: []string{"EGGS", "MILK", "GLUTEN"}, DietaryTags: []string{"VEGETARIAN"}},
+	{MenuSKU: "DEMO-DESSERTS", MenuName: "Desserts", MenuCategory: "Desserts", SKU: "DEMO-SORBET", Name: "Lemon sorbet", Price: 5, DietaryTags: []string{"VEGAN", "GLUTEN_FREE"}},
+	{MenuSKU: "DEMO-DRINKS", MenuName: "Drinks", MenuCategory: "Drinks", SKU: "DEMO-LEMONADE", Name: "Lemonade", Price: 3.5, DietaryTags: []string{"VEGAN"}},
+	{MenuSKU: "DEMO-DRINKS", MenuName: "Drinks", MenuCategory: "Drinks", SKU: "DEMO-ESPRESSO", Name: "Espresso", Price: 2.5, DietaryTags: []string{"VEGAN"}},
+}
+
+// seed adds a demo menu and tables. Running it again updates the demo
+// foods by SKU and only adds the table numbers that are missing.
+func seed(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	tables := flags.Int("tables", 8, "number of tables")
+	flags.Parse(args)
+
+	result, err := controller.ImportMenuRows(ctx, demoMenu, false)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("demo menu row %d: %s", result.Errors[0].Row, result.Errors[0].Error)
+	}
+	fmt.Printf("menus: %d created, %d updated\n", result.MenusCreated, result.MenusUpdated)
+	fmt.Printf("foods: %d created, %d updated\n", result.FoodsCreated, result.FoodsUpdated)
+
+	createdAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	writes := []mongo.WriteModel{}
+	for number := 1; number <= *tables; number++ {
+		id := primitive.NewObjectID()
+		seats := 2
+		if number%2 == 0 {
+			seats = 4
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"table_number": number}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{
+				"_id":              id,
+				"table_id":         id.Hex(),
+				"table_number":     number,
+				"number_of_guests": seats,
+				"seats":            seats,
+				"created_at":       createdAt,
+				"updated_at":       createdAt,
+			}}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	tableResult, err := database.OpenCollection(database.Client, "table").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("tables: %d created\n", tableResult.UpsertedCount)
+	return nil
+}
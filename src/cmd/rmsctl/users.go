@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	controller "infinity/rms/controllers"
+	"infinity/rms/database"
+	"infinity/rms/models"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validate = validator.New()
+
+// readPassword reads one line from stdin. It is echoed when typed, so
+// piping it in keeps it off the screen and out of the shell history.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given on stdin")
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) < 8 {
+		return "", errors.New("password must be at least 8 characters")
+	}
+	return password, nil
+}
+
+// createAdmin adds the first user. Every signed in user can manage the
+// restaurant, so it refuses once any user exists.
+func createAdmin(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := flags.String("email", "", "email to sign in with")
+	phone := flags.String("phone", "", "phone number")
+	firstName := flags.String("first-name", "Admin", "first name")
+	lastName := flags.String("last-name", "", "last name")
+	flags.Parse(args)
+
+	*email = strings.TrimSpace(*email)
+	if err := validate.Var(*email, "required,email"); err != nil {
+		return errors.New("create-admin needs a valid -email")
+	}
+	users := database.OpenCollection(database.Client, "users")
+	count, err := users.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("there already are users, use reset-password to get back in")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	hashed := controller.HashPassword(password)
+	user := models.User{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		Password:  &hashed,
+	}
+	if *phone != "" {
+		user.Phone = phone
+	}
+	user.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.UpdatedAt = user.CreatedAt
+	user.ID = primitive.NewObjectID()
+	user.UserID = user.ID.Hex()
+	if _, err := users.InsertOne(ctx, user); err != nil {
+		return err
+	}
+	fmt.Printf("created %s with user id %s\n", *email, user.UserID)
+	return nil
+}
+
+func resetPassword(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	email := flags.String("email", "", "email of the user")
+	flags.Parse(args)
+	if *email == "" {
+		return errors.New("reset-password needs -email")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := database.OpenCollection(database.Client, "users").UpdateOne(ctx,
+		bson.M{"email": strings.TrimSpace(*email)},
+		bson.M{"$set": bson.M{"password": controller.HashPassword(password), "updated_at": updatedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no user with email %s", *email)
+	}
+	fmt.Printf("password of %s was reset\n", *email)
+	return nil
+}
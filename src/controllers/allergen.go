@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AllergenWarning struct {
+	FoodID    string   `json:"food_id"`
+	FoodName  string   `json:"food_name"`
+	Allergens []string `json:"allergens"`
+}
+
+var allergenCollection *mongo.Collection = database.OpenCollection(database.Client, "allergen")
+
+// ALLERGEN_POLICY=block rejects conflicting order items instead of warning
+func allergenPolicyBlocks() bool {
+	return strings.EqualFold(os.Getenv("ALLERGEN_POLICY"), "block")
+}
+
+func GetAllergens() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := allergenCollection.Find(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching allergens",
+			})
+			return
+		}
+
+		var custom []bson.M
+		if err = result.All(curCtx, &custom); err != nil {
+			log.Fatal(err)
+		}
+
+		locales := requestLocales(ctx)
+		for _, allergen := range custom {
+			localizeDoc(allergen, locales, "name")
+		}
+		standard := []gin.H{}
+		for _, code := range models.StandardAllergens {
+			standard = append(standard, gin.H{
+				"code": code,
+				"name": helpers.Translate(models.StandardAllergenNames[code], locales),
+			})
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"standard":     standard,
+			"custom":       custom,
+			"dietary_tags": models.DietaryTags,
+		})
+	}
+}
+
+func CreateAllergen() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var allergen models.Allergen
+
+		if err := ctx.BindJSON(&allergen); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(allergen)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(*allergen.Code))
+		allergen.Code = &code
+
+		known, err := knownAllergens(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while checking allergens",
+			})
+			return
+		}
+		if known[code] {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Allergen %s already exists", code),
+			})
+			return
+		}
+
+		allergen.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		allergen.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		allergen.ID = primitive.NewObjectID()
+		allergen.AllergenID = allergen.ID.Hex()
+
+		result, insertErr := allergenCollection.InsertOne(curCtx, allergen)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Allergen was not created",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToUpper(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// splitTags reads a comma separated query value like "milk,eggs"
+func splitTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return normalizeTags(strings.Split(value, ","))
+}
+
+func knownAllergens(curCtx context.Context) (map[string]bool, error) {
+	known := map[string]bool{}
+	for _, code := range models.StandardAllergens {
+		known[code] = true
+	}
+
+	result, err := allergenCollection.Find(curCtx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var custom []models.Allergen
+	if err = result.All(curCtx, &custom); err != nil {
+		return nil, err
+	}
+	for _, allergen := range custom {
+		if allergen.Code != nil {
+			known[*allergen.Code] = true
+		}
+	}
+	return known, nil
+}
+
+func checkAllergens(curCtx context.Context, codes []string) error {
+	known, err := knownAllergens(curCtx)
+	if err != nil {
+		return err
+	}
+	for _, code := range codes {
+		if !known[code] {
+			return fmt.Errorf("Unknown allergen %s", code)
+		}
+	}
+	return nil
+}
+
+func checkDietaryTags(tags []string) error {
+	for _, tag := range tags {
+		found := false
+		for _, known := range models.DietaryTags {
+			if tag == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown dietary tag %s", tag)
+		}
+	}
+	return nil
+}
+
+func allergenConflicts(food models.Food, allergies []string) []string {
+	conflicts := []string{}
+	for _, allergen := range food.Allergens {
+		for _, allergy := range allergies {
+			if allergen == allergy {
+				conflicts = append(conflicts, allergen)
+			}
+		}
+	}
+	return conflicts
+}
+
+// allergenWarnings looks up every ordered food and reports the ones
+// containing an allergen the guests declared
+func allergenWarnings(curCtx context.Context, orderItems []models.OrderItem, allergies []string) ([]AllergenWarning, error) {
+	warnings := []AllergenWarning{}
+	if len(allergies) == 0 {
+		return warnings, nil
+	}
+
+	foodIds := []string{}
+	for _, orderItem := range orderItems {
+		if orderItem.FoodID != nil {
+			foodIds = append(foodIds, *orderItem.FoodID)
+		}
+	}
+
+	result, err := foodCollection.Find(curCtx, bson.M{"food_id": bson.M{"$in": foodIds}})
+	if err != nil {
+		return nil, err
+	}
+	var foods []models.Food
+	if err = result.All(curCtx, &foods); err != nil {
+		return nil, err
+	}
+
+	for _, food := range foods {
+		conflicts := allergenConflicts(food, allergies)
+		if len(conflicts) == 0 {
+			continue
+		}
+		name := ""
+		if food.Name != nil {
+			name = *food.Name
+		}
+		warnings = append(warnings, AllergenWarning{
+			FoodID:    food.FoodId,
+			FoodName:  name,
+			Allergens: conflicts,
+		})
+	}
+	return warnings, nil
+}
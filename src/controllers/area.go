@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var areaCollection *mongo.Collection = database.OpenCollection(database.Client, "area")
+
+func GetAreas() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := areaCollection.Find(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching areas",
+			})
+			return
+		}
+
+		var allAreas []bson.M
+		if err = result.All(curCtx, &allAreas); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allAreas)
+	}
+}
+
+func CreateArea() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var area models.Area
+
+		if err := ctx.BindJSON(&area); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(area)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		area.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		area.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		area.ID = primitive.NewObjectID()
+		area.AreaID = area.ID.Hex()
+
+		result, insertErr := areaCollection.InsertOne(curCtx, area)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Area was not created",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateArea() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var area models.Area
+
+		if err := ctx.BindJSON(&area); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		var updatedObj primitive.D
+
+		if area.Name != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "name", Value: area.Name})
+		}
+		if area.Width != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "width", Value: area.Width})
+		}
+		if area.Height != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "height", Value: area.Height})
+		}
+		if area.SortOrder != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "sort_order", Value: area.SortOrder})
+		}
+
+		area.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: area.UpdatedAt})
+
+		filter := bson.M{"area_id": ctx.Param("area_id")}
+		result, err := areaCollection.UpdateOne(curCtx, filter, bson.D{
+			{Key: "$set", Value: updatedObj},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Area updation failed",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var auditCollection *mongo.Collection = database.OpenCollection(database.Client, "auditLog")
+
+// parseTimeQuery accepts either a date or a full RFC3339 time
+func parseTimeQuery(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// GetAuditLog lists audit entries newest first. It can be filtered by
+// entity, entity_id, actor, user_id, action, request_id and a from/to
+// range.
+func GetAuditLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		for _, key := range []string{"entity", "entity_id", "actor", "user_id", "action", "request_id"} {
+			if value := ctx.Query(key); value != "" {
+				filter[key] = value
+			}
+		}
+
+		createdAt := bson.M{}
+		if value := ctx.Query("from"); value != "" {
+			from, err := parseTimeQuery(value)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date or an RFC3339 time"})
+				return
+			}
+			createdAt["$gte"] = from
+		}
+		if value := ctx.Query("to"); value != "" {
+			to, err := parseTimeQuery(value)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date or an RFC3339 time"})
+				return
+			}
+			// a bare date includes the whole day
+			if len(value) == len("2006-01-02") {
+				to = to.AddDate(0, 0, 1)
+			}
+			createdAt["$lt"] = to
+		}
+		if len(createdAt) > 0 {
+			filter["created_at"] = createdAt
+		}
+
+		recordPerPage, err := strconv.Atoi(ctx.Query("recordPerPage"))
+		if err != nil || recordPerPage < 1 || recordPerPage > 500 {
+			recordPerPage = 50
+		}
+		page, err := strconv.Atoi(ctx.Query("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		total, err := auditCollection.CountDocuments(curCtx, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the audit log"})
+			return
+		}
+
+		opts := options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip(int64((page - 1) * recordPerPage)).
+			SetLimit(int64(recordPerPage))
+		cursor, err := auditCollection.Find(curCtx, filter, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the audit log"})
+			return
+		}
+		entries := []bson.M{}
+		if err = cursor.All(curCtx, &entries); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the audit log"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"total_count": total,
+			"page":        page,
+			"entries":     entries,
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var businessDayCollection *mongo.Collection = database.OpenCollection(database.Client, "businessDay")
+
+// CloseBusinessDay closes the local day that day falls on. Staff still
+// on the clock are clocked out, parties still waiting are taken off the
+// waitlist and the day's takings are recorded. Unpaid orders are listed
+// but left alone. A day can only be closed once.
+func CloseBusinessDay(curCtx context.Context, day time.Time, closedBy string) (models.BusinessDay, error) {
+	day = day.In(time.Local)
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 1)
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	if from.After(now) {
+		return models.BusinessDay{}, requestError{http.StatusBadRequest, "The day hasn't started yet"}
+	}
+	closeAt := now
+	if to.Before(now) {
+		closeAt = to
+	}
+
+	businessDay := models.BusinessDay{
+		Date:          dayKey(from),
+		SalesByMethod: map[string]float64{},
+		OpenOrders:    []string{},
+		ClockedOut:    []string{},
+		ClosedBy:      closedBy,
+		ClosedAt:      now,
+	}
+	businessDay.ID = primitive.NewObjectID()
+	businessDay.BusinessDayID = businessDay.ID.Hex()
+
+	if err := dayTakings(curCtx, &businessDay, from, to); err != nil {
+		return businessDay, err
+	}
+
+	err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+		count, err := businessDayCollection.CountDocuments(sessCtx, bson.M{"date": businessDay.Date})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return requestError{http.StatusConflict, fmt.Sprintf("Day %s is already closed", businessDay.Date)}
+		}
+
+		cursor, err := timeEntryCollection.Find(sessCtx, bson.M{"clock_out": nil, "clock_in": bson.M{"$lt": to}})
+		if err != nil {
+			return err
+		}
+		var entries []models.TimeEntry
+		if err = cursor.All(sessCtx, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			for i := range entry.Breaks {
+				if entry.Breaks[i].End == nil {
+					entry.Breaks[i].End = &closeAt
+				}
+			}
+			_, err := timeEntryCollection.UpdateOne(sessCtx, bson.M{"time_entry_id": entry.TimeEntryID}, bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "clock_out", Value: closeAt},
+					{Key: "breaks", Value: entry.Breaks},
+					{Key: "updated_at", Value: now},
+				}},
+			})
+			if err != nil {
+				return err
+			}
+			businessDay.ClockedOut = append(businessDay.ClockedOut, entry.StaffID)
+		}
+
+		result, err := waitlistCollection.UpdateMany(sessCtx,
+			bson.M{"status": bson.M{"$in": bson.A{"WAITING", "NOTIFIED"}}, "created_at": bson.M{"$lt": to}},
+			bson.M{"$set": bson.M{"status": "CANCELLED", "updated_at": now}},
+		)
+		if err != nil {
+			return err
+		}
+		businessDay.WaitlistCancelled = result.ModifiedCount
+		if err := renumberWaitlist(sessCtx); err != nil {
+			return err
+		}
+
+		_, err = businessDayCollection.InsertOne(sessCtx, businessDay)
+		return err
+	})
+	return businessDay, err
+}
+
+// dayTakings fills in the orders, sales and voids of the day
+func dayTakings(curCtx context.Context, businessDay *models.BusinessDay, from time.Time, to time.Time) error {
+	created := bson.M{"$gte": from, "$lt": to}
+
+	orderCount, err := orderCollection.CountDocuments(curCtx, bson.M{"created_at": created, "merged_into": nil})
+	if err != nil {
+		return err
+	}
+	businessDay.Orders = orderCount
+
+	invoices, err := paidInvoices(curCtx, from, to)
+	if err != nil {
+		return err
+	}
+	for _, paid := range invoices {
+		businessDay.PaidInvoices++
+		businessDay.Sales += paid.Total
+		if paid.Invoice.Tip != nil {
+			businessDay.Tips += *paid.Invoice.Tip
+		}
+		rest := paid.Total
+		for _, payment := range paid.Invoice.Payments {
+			businessDay.SalesByMethod[payment.Method] += payment.Amount
+			rest -= payment.Amount
+		}
+		method := "UNSPECIFIED"
+		if paid.Invoice.PaymentMethod != nil && *paid.Invoice.PaymentMethod != "" {
+			method = *paid.Invoice.PaymentMethod
+		}
+		if rest > 0.005 {
+			businessDay.SalesByMethod[method] += rest
+		}
+	}
+	businessDay.Sales = toFixed(businessDay.Sales, 2)
+	businessDay.Tips = toFixed(businessDay.Tips, 2)
+	for method, amount := range businessDay.SalesByMethod {
+		businessDay.SalesByMethod[method] = toFixed(amount, 2)
+	}
+
+	voided, err := orderItemCollection.CountDocuments(curCtx, bson.M{"status": "VOIDED", "voided_at": created})
+	if err != nil {
+		return err
+	}
+	businessDay.VoidedItems = voided
+
+	cursor, err := orderCollection.Find(curCtx, bson.M{
+		"created_at":  created,
+		"merged_into": nil,
+		"status":      bson.M{"$ne": "CANCELLED"},
+	})
+	if err != nil {
+		return err
+	}
+	var orders []models.Order
+	if err = cursor.All(curCtx, &orders); err != nil {
+		return err
+	}
+	orderIds := []string{}
+	for _, order := range orders {
+		orderIds = append(orderIds, order.OrderID)
+	}
+	paidOrders, err := invoiceCollection.Distinct(curCtx, "order_id", bson.M{"order_id": bson.M{"$in": orderIds}, "payment_status": "PAID"})
+	if err != nil {
+		return err
+	}
+	paid := map[string]bool{}
+	for _, orderId := range paidOrders {
+		if id, ok := orderId.(string); ok {
+			paid[id] = true
+		}
+	}
+	for _, orderId := range orderIds {
+		if !paid[orderId] {
+			businessDay.OpenOrders = append(businessDay.OpenOrders, orderId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var courses = []string{"STARTER", "MAIN", "DESSERT"}
+
+func courseRank(course string) int {
+	for i, name := range courses {
+		if name == course {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// autoFireRules reads COURSE_AUTO_FIRE, pairs like "STARTER:MAIN" meaning
+// mains are fired once the starters are bumped. "none" turns it off.
+func autoFireRules() map[string]string {
+	value, ok := os.LookupEnv("COURSE_AUTO_FIRE")
+	if !ok {
+		value = "STARTER:MAIN"
+	}
+	rules := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		from, to, found := strings.Cut(strings.ToUpper(strings.TrimSpace(pair)), ":")
+		if found && courseRank(from) > 0 && courseRank(to) > 0 {
+			rules[from] = to
+		}
+	}
+	return rules
+}
+
+// itemFired is true for items the kitchen should cook. Items from before
+// coursing have neither a course nor a fire time and count as fired.
+func itemFired(item models.OrderItem) bool {
+	return item.FiredAt != nil || item.Course == ""
+}
+
+func orderItemsOf(curCtx context.Context, orderId string) ([]models.OrderItem, error) {
+	cursor, err := orderItemCollection.Find(curCtx, bson.M{"order_id": orderId, "status": bson.M{"$ne": "VOIDED"}})
+	if err != nil {
+		return nil, err
+	}
+	var items []models.OrderItem
+	err = cursor.All(curCtx, &items)
+	return items, err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitingConfirmation is true for guest items staff haven't accepted
+// yet. They don't go to the kitchen and don't hold other items back.
+func awaitingConfirmation(item models.OrderItem) bool {
+	return item.Status == "PENDING_CONFIRMATION"
+}
+
+// prepareKitchenItems gives new items their food's course, station and
+// prep time and decides which of them go to the kitchen now
+func prepareKitchenItems(curCtx context.Context, order models.Order, items []models.OrderItem) error {
+	for i := range items {
+		if items[i].FoodID == nil {
+			continue
+		}
+		var food models.Food
+		if err := foodCollection.FindOne(curCtx, bson.M{"food_id": items[i].FoodID}).Decode(&food); err != nil {
+			continue
+		}
+		if items[i].Course == "" && food.Course != nil {
+			items[i].Course = *food.Course
+		}
+		routeItem(&items[i], food)
+	}
+	return fireNewItems(curCtx, order, items)
+}
+
+// fireNewItems sets the fire time of the items that go to the kitchen
+// now. A course fires straight away unless the waiter holds it or an
+// earlier course is still being cooked. Takeaway and delivery orders are
+// never coursed, and items awaiting confirmation wait for it.
+func fireNewItems(curCtx context.Context, order models.Order, items []models.OrderItem) error {
+	existing := []models.OrderItem{}
+	if order.OrderID != "" {
+		stored, err := orderItemsOf(curCtx, order.OrderID)
+		if err != nil {
+			return err
+		}
+		for _, item := range stored {
+			if !awaitingConfirmation(item) {
+				existing = append(existing, item)
+			}
+		}
+	}
+
+	// courses already waiting to be fired keep their new items waiting too
+	waiting := map[string]bool{}
+	for _, item := range existing {
+		if !itemFired(item) && item.BumpedAt == nil {
+			waiting[item.Course] = true
+		}
+	}
+
+	// the earliest course with anything left to cook
+	open := 0
+	for _, item := range append(existing, items...) {
+		rank := courseRank(item.Course)
+		if rank > 0 && item.BumpedAt == nil && !awaitingConfirmation(item) && (open == 0 || rank < open) {
+			open = rank
+		}
+	}
+
+	// scheduled orders are fired when they are released
+	if order.ScheduledFor != nil && order.ReleasedAt == nil {
+		return nil
+	}
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	for i := range items {
+		if awaitingConfirmation(items[i]) {
+			continue
+		}
+		held := contains(order.HeldCourses, items[i].Course) || waiting[items[i].Course]
+		if offPremises(order) || items[i].Course == "" || (!held && courseRank(items[i].Course) <= open) {
+			items[i].FiredAt = &now
+		}
+	}
+	return nil
+}
+
+// fireCourse sends a course's waiting items to the kitchen
+func fireCourse(curCtx context.Context, orderId string, course string) (int64, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := orderItemCollection.UpdateMany(curCtx,
+		bson.M{"order_id": orderId, "course": course, "fired_at": nil, "status": bson.M{"$nin": bson.A{"PENDING_CONFIRMATION", "VOIDED"}}},
+		bson.M{"$set": bson.M{"fired_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// fireOpenCourse fires the items of a just released order that would have
+// fired had it been placed now
+func fireOpenCourse(curCtx context.Context, order models.Order) error {
+	items, err := orderItemsOf(curCtx, order.OrderID)
+	if err != nil {
+		return err
+	}
+	open := 0
+	for _, item := range items {
+		rank := courseRank(item.Course)
+		if rank > 0 && item.BumpedAt == nil && !awaitingConfirmation(item) && (open == 0 || rank < open) {
+			open = rank
+		}
+	}
+	ids := []string{}
+	for _, item := range items {
+		if item.FiredAt != nil || item.BumpedAt != nil || awaitingConfirmation(item) || contains(order.HeldCourses, item.Course) {
+			continue
+		}
+		if item.Course == "" || courseRank(item.Course) == open {
+			ids = append(ids, item.OrderItemID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	_, err = orderItemCollection.UpdateMany(curCtx, bson.M{"order_item_id": bson.M{"$in": ids}}, bson.M{
+		"$set": bson.M{"fired_at": now, "updated_at": now},
+	})
+	return err
+}
+
+func courseParam(ctx *gin.Context) (string, bool) {
+	course := strings.ToUpper(ctx.Param("course"))
+	if courseRank(course) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("course must be one of %s", strings.Join(courses, ", "))})
+		return "", false
+	}
+	return course, true
+}
+
+// FireCourse is the waiter calling a course away
+func FireCourse() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		course, ok := courseParam(ctx)
+		if !ok {
+			return
+		}
+		order, err := findOrder(curCtx, ctx.Param("order_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching order")
+			return
+		}
+
+		// a scheduled order only has the hold lifted; the course goes to the
+		// kitchen with the rest of the order when it is released
+		var fired int64
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			if _, err := orderCollection.UpdateOne(sessCtx, bson.M{"order_id": order.OrderID}, bson.M{
+				"$pull": bson.M{"held_courses": course},
+			}); err != nil {
+				return err
+			}
+			if order.ScheduledFor != nil && order.ReleasedAt == nil {
+				return nil
+			}
+			fired, err = fireCourse(sessCtx, order.OrderID, course)
+			return err
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Order updation failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": order.OrderID, "course": course, "fired": fired})
+	}
+}
+
+// HoldCourse keeps a course back until the waiter fires it. Items the
+// kitchen already has but hasn't finished are taken back.
+func HoldCourse() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		course, ok := courseParam(ctx)
+		if !ok {
+			return
+		}
+		order, err := findOrder(curCtx, ctx.Param("order_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching order")
+			return
+		}
+
+		var held int64
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			if _, err := orderCollection.UpdateOne(sessCtx, bson.M{"order_id": order.OrderID}, bson.M{
+				"$addToSet": bson.M{"held_courses": course},
+			}); err != nil {
+				return err
+			}
+			now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			result, err := orderItemCollection.UpdateMany(sessCtx,
+				bson.M{"order_id": order.OrderID, "course": course, "bumped_at": nil},
+				bson.M{"$set": bson.M{"fired_at": nil, "updated_at": now}},
+			)
+			if err != nil {
+				return err
+			}
+			held = result.MatchedCount
+			return nil
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Order updation failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": order.OrderID, "course": course, "held": held})
+	}
+}
+
+// BumpKitchenTicket marks fired items as done: all of them, just the
+// order_item_ids given or just those of ?station=. A course that is
+// finished fires the next one as set in COURSE_AUTO_FIRE.
+func BumpKitchenTicket() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			OrderItemIDs []string `json:"order_item_ids"`
+		}
+		if ctx.Request.ContentLength > 0 {
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		order, err := findOrder(curCtx, ctx.Param("order_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching order")
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		filter := bson.M{
+			"order_id":  order.OrderID,
+			"bumped_at": nil,
+			"status":    bson.M{"$nin": bson.A{"PENDING_CONFIRMATION", "VOIDED"}},
+			"$or":       bson.A{bson.M{"fired_at": bson.M{"$ne": nil}}, bson.M{"course": nil}},
+		}
+		if len(body.OrderItemIDs) > 0 {
+			filter["order_item_id"] = bson.M{"$in": body.OrderItemIDs}
+		}
+		if station := ctx.Query("station"); station != "" {
+			filter["station"] = stationFilter(normalizeStation(station))
+		}
+		result, err := orderItemCollection.UpdateMany(curCtx, filter, bson.M{
+			"$set": bson.M{"bumped_at": now, "updated_at": now},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Order Item updation failed"})
+			return
+		}
+
+		items, err := orderItemsOf(curCtx, order.OrderID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+		unfinished := map[string]bool{}
+		for _, item := range items {
+			if item.Course != "" && item.BumpedAt == nil {
+				unfinished[item.Course] = true
+			}
+		}
+		fired := []string{}
+		for from, to := range autoFireRules() {
+			if unfinished[from] || !unfinished[to] || contains(order.HeldCourses, to) {
+				continue
+			}
+			// only courses that were actually on the order trigger the next
+			started := false
+			for _, item := range items {
+				started = started || (item.Course == from && item.BumpedAt != nil)
+			}
+			if !started {
+				continue
+			}
+			count, err := fireCourse(curCtx, order.OrderID, to)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Order Item updation failed"})
+				return
+			}
+			if count > 0 {
+				fired = append(fired, to)
+			}
+		}
+		ctx.JSON(http.StatusOK, gin.H{"bumped": result.ModifiedCount, "fired_courses": fired})
+	}
+}
@@ -0,0 +1,672 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var customerCollection *mongo.Collection = database.OpenCollection(database.Client, "customer")
+
+type CustomerStats struct {
+	Visits       int        `json:"visits"`
+	PaidVisits   int        `json:"paid_visits"`
+	TotalSpend   float64    `json:"total_spend"`
+	AverageSpend float64    `json:"average_spend"`
+	FirstVisit   *time.Time `json:"first_visit,omitempty"`
+	LastVisit    *time.Time `json:"last_visit,omitempty"`
+}
+
+type CustomerVisit struct {
+	OrderID       string    `json:"order_id"`
+	OrderDate     time.Time `json:"order_date"`
+	TableID       *string   `json:"table_id,omitempty"`
+	Covers        *int      `json:"covers,omitempty"`
+	Items         int       `json:"items"`
+	Total         float64   `json:"total"`
+	PaymentStatus string    `json:"payment_status"`
+}
+
+var activeCustomers = bson.M{"merged_into": nil}
+
+// normalizePhone keeps the digits and a leading +, so that "+1 (555)
+// 010-9999" and "+15550109999" are the same customer
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if unicode.IsDigit(r) || (i == 0 && r == '+') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func normalizeCustomer(customer *models.Customer) {
+	if customer.Phone != nil {
+		phone := normalizePhone(*customer.Phone)
+		customer.Phone = &phone
+	}
+	if customer.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*customer.Email))
+		customer.Email = &email
+	}
+	if customer.Allergies != nil {
+		customer.Allergies = normalizeTags(customer.Allergies)
+	}
+}
+
+// findDuplicate looks for another customer with the same phone or email
+func findDuplicate(curCtx context.Context, customer models.Customer, exceptId string) (*models.Customer, error) {
+	or := bson.A{}
+	if customer.Phone != nil && *customer.Phone != "" {
+		or = append(or, bson.M{"phone": customer.Phone})
+	}
+	if customer.Email != nil && *customer.Email != "" {
+		or = append(or, bson.M{"email": customer.Email})
+	}
+	if len(or) == 0 {
+		return nil, nil
+	}
+	var existing models.Customer
+	err := customerCollection.FindOne(curCtx, bson.M{
+		"$or":         or,
+		"merged_into": nil,
+		"customer_id": bson.M{"$ne": exceptId},
+	}).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func findCustomer(curCtx context.Context, customerId string) (models.Customer, error) {
+	var customer models.Customer
+	err := customerCollection.FindOne(curCtx, bson.M{"customer_id": customerId}).Decode(&customer)
+	if err == mongo.ErrNoDocuments {
+		return customer, requestError{http.StatusNotFound, "Customer was not found"}
+	}
+	return customer, err
+}
+
+// customerAllergies adds the customer's known allergies to the ones
+// declared on the order
+func customerAllergies(curCtx context.Context, customerId string, allergies []string) ([]string, error) {
+	customer, err := findCustomer(curCtx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeTags(append(allergies, customer.Allergies...)), nil
+}
+
+// customerOrders lists the orders matching the filter with their item
+// count, payment status and total as the receipt shows it
+func customerOrders(curCtx context.Context, match bson.M) ([]bson.M, error) {
+	cursor, err := orderCollection.Aggregate(curCtx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "invoice"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "invoice"},
+		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "orderItem"},
+			{Key: "let", Value: bson.D{{Key: "order_id", Value: "$order_id"}}},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{
+					{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$order_id", "$$order_id"}}}},
+					{Key: "status", Value: bson.D{{Key: "$ne", Value: "VOIDED"}}},
+				}}},
+				{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "items"},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "customer_id", Value: 1},
+			{Key: "order_id", Value: 1},
+			{Key: "order_date", Value: 1},
+			{Key: "table_id", Value: 1},
+			{Key: "covers", Value: 1},
+			{Key: "items", Value: bson.D{{Key: "$size", Value: "$items"}}},
+			{Key: "discount", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$map", Value: bson.D{
+				{Key: "input", Value: "$invoice"},
+				{Key: "as", Value: "invoice"},
+				{Key: "in", Value: bson.D{{Key: "$sum", Value: "$$invoice.discounts.amount"}}},
+			}}}}}},
+			{Key: "paid", Value: bson.D{{Key: "$in", Value: bson.A{"PAID", "$invoice.payment_status"}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "order_date", Value: -1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	orders := []bson.M{}
+	if err = cursor.All(curCtx, &orders); err != nil {
+		return nil, err
+	}
+
+	orderIds := []string{}
+	for _, order := range orders {
+		if orderId, ok := order["order_id"].(string); ok {
+			orderIds = append(orderIds, orderId)
+		}
+	}
+	totals, err := orderTotals(curCtx, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		orderId, _ := order["order_id"].(string)
+		order["total"] = toFixed(totals[orderId]-numberValue(order["discount"]), 2)
+	}
+	return orders, nil
+}
+
+func numberValue(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// customerStats sums the visits and paid spend of each customer
+func customerStats(curCtx context.Context, customerIds []string) (map[string]*CustomerStats, error) {
+	stats := map[string]*CustomerStats{}
+	if len(customerIds) == 0 {
+		return stats, nil
+	}
+	orders, err := customerOrders(curCtx, bson.M{
+		"customer_id": bson.M{"$in": customerIds},
+		"merged_into": nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		customerId, _ := order["customer_id"].(string)
+		stat, ok := stats[customerId]
+		if !ok {
+			stat = &CustomerStats{}
+			stats[customerId] = stat
+		}
+		stat.Visits++
+		if paid, _ := order["paid"].(bool); paid {
+			stat.PaidVisits++
+			stat.TotalSpend += numberValue(order["total"])
+		}
+		if date, ok := order["order_date"].(primitive.DateTime); ok {
+			visit := date.Time()
+			if stat.FirstVisit == nil || visit.Before(*stat.FirstVisit) {
+				stat.FirstVisit = &visit
+			}
+			if stat.LastVisit == nil || visit.After(*stat.LastVisit) {
+				stat.LastVisit = &visit
+			}
+		}
+	}
+	for _, stat := range stats {
+		if stat.PaidVisits > 0 {
+			stat.AverageSpend = toFixed(stat.TotalSpend/float64(stat.PaidVisits), 2)
+		}
+		stat.TotalSpend = toFixed(stat.TotalSpend, 2)
+	}
+	return stats, nil
+}
+
+// birthdaySoon is true when the birthday falls in the next week
+func birthdaySoon(birthday *string, now time.Time) bool {
+	if birthday == nil {
+		return false
+	}
+	date, err := time.Parse("2006-01-02", *birthday)
+	if err != nil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, year := range []int{now.Year(), now.Year() + 1} {
+		next := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		if days := next.Sub(today).Hours() / 24; days >= 0 && days <= 7 {
+			return true
+		}
+	}
+	return false
+}
+
+func customerSummaries(curCtx context.Context, customers []models.Customer) ([]gin.H, error) {
+	ids := []string{}
+	for _, customer := range customers {
+		ids = append(ids, customer.CustomerID)
+	}
+	stats, err := customerStats(curCtx, ids)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := []gin.H{}
+	for _, customer := range customers {
+		stat := stats[customer.CustomerID]
+		if stat == nil {
+			stat = &CustomerStats{}
+		}
+		result = append(result, gin.H{
+			"customer":      customer,
+			"stats":         stat,
+			"regular":       stat.Visits >= 3,
+			"birthday_soon": birthdaySoon(customer.Birthday, now),
+		})
+	}
+	return result, nil
+}
+
+func GetCustomers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		opts := options.Find().SetSort(bson.D{{Key: "first_name", Value: 1}, {Key: "last_name", Value: 1}})
+		cursor, err := customerCollection.Find(curCtx, activeCustomers, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+			return
+		}
+		customers := []models.Customer{}
+		if err = cursor.All(curCtx, &customers); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+			return
+		}
+		ctx.JSON(http.StatusOK, customers)
+	}
+}
+
+// SearchCustomers finds customers by name, phone or email so a host can
+// recognise a regular at the door
+func SearchCustomers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		query := strings.TrimSpace(ctx.Query("q"))
+		if len(query) < 2 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "q must be at least 2 characters"})
+			return
+		}
+
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+		or := bson.A{
+			bson.M{"first_name": pattern},
+			bson.M{"last_name": pattern},
+			bson.M{"email": pattern},
+		}
+		if phone := normalizePhone(query); len(phone) >= 3 {
+			or = append(or, bson.M{"phone": primitive.Regex{Pattern: regexp.QuoteMeta(phone)}})
+		}
+		// "Jane Doe" should match on both names
+		if parts := strings.Fields(query); len(parts) == 2 {
+			or = append(or, bson.M{
+				"first_name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(parts[0]), Options: "i"},
+				"last_name":  primitive.Regex{Pattern: "^" + regexp.QuoteMeta(parts[1]), Options: "i"},
+			})
+		}
+
+		opts := options.Find().SetLimit(20)
+		cursor, err := customerCollection.Find(curCtx, bson.M{"$or": or, "merged_into": nil}, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+			return
+		}
+		customers := []models.Customer{}
+		if err = cursor.All(curCtx, &customers); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+			return
+		}
+
+		result, err := customerSummaries(curCtx, customers)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching visits"})
+			return
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i]["stats"].(*CustomerStats).Visits > result[j]["stats"].(*CustomerStats).Visits
+		})
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func GetCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		customer, err := findCustomer(curCtx, ctx.Param("customer_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching customer")
+			return
+		}
+		result, err := customerSummaries(curCtx, []models.Customer{customer})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching visits"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result[0])
+	}
+}
+
+func GetCustomerVisits() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		customer, err := findCustomer(curCtx, ctx.Param("customer_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching customer")
+			return
+		}
+		orders, err := customerOrders(curCtx, bson.M{"customer_id": customer.CustomerID, "merged_into": nil})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching visits"})
+			return
+		}
+
+		visits := []CustomerVisit{}
+		for _, order := range orders {
+			visit := CustomerVisit{PaymentStatus: "PENDING"}
+			visit.OrderID, _ = order["order_id"].(string)
+			if date, ok := order["order_date"].(primitive.DateTime); ok {
+				visit.OrderDate = date.Time()
+			}
+			if tableId, ok := order["table_id"].(string); ok {
+				visit.TableID = &tableId
+			}
+			if covers := int(numberValue(order["covers"])); covers > 0 {
+				visit.Covers = &covers
+			}
+			visit.Items = int(numberValue(order["items"]))
+			visit.Total = toFixed(numberValue(order["total"]), 2)
+			if paid, _ := order["paid"].(bool); paid {
+				visit.PaymentStatus = "PAID"
+			}
+			visits = append(visits, visit)
+		}
+		ctx.JSON(http.StatusOK, visits)
+	}
+}
+
+func CreateCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var customer models.Customer
+		if err := ctx.BindJSON(&customer); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		normalizeCustomer(&customer)
+		if validationErr := validate.Struct(customer); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		existing, err := findDuplicate(curCtx, customer, "")
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+			return
+		}
+		if existing != nil {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":       "A customer with this phone or email already exists",
+				"customer_id": existing.CustomerID,
+			})
+			return
+		}
+
+		customer.MergedInto = nil
+		customer.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		customer.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		customer.ID = primitive.NewObjectID()
+		customer.CustomerID = customer.ID.Hex()
+
+		result, insertErr := customerCollection.InsertOne(curCtx, customer)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Customer was not created"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var customer models.Customer
+		if err := ctx.BindJSON(&customer); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		normalizeCustomer(&customer)
+
+		customerId := ctx.Param("customer_id")
+		existing, err := findDuplicate(curCtx, customer, customerId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+			return
+		}
+		if existing != nil {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":       "A customer with this phone or email already exists",
+				"customer_id": existing.CustomerID,
+			})
+			return
+		}
+
+		var updatedObj primitive.D
+		if customer.FirstName != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "first_name", Value: customer.FirstName})
+		}
+		if customer.LastName != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "last_name", Value: customer.LastName})
+		}
+		if customer.Phone != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "phone", Value: customer.Phone})
+		}
+		if customer.Email != nil {
+			if err := validate.Var(*customer.Email, "omitempty,email"); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "email", Value: customer.Email})
+		}
+		if customer.Birthday != nil {
+			if err := validate.Var(*customer.Birthday, "omitempty,datetime=2006-01-02"); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "birthday must be a date like 2006-01-02"})
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "birthday", Value: customer.Birthday})
+		}
+		if customer.Preferences != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "preferences", Value: customer.Preferences})
+		}
+		if customer.Allergies != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "allergies", Value: customer.Allergies})
+		}
+		if customer.Notes != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "notes", Value: customer.Notes})
+		}
+
+		customer.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: customer.UpdatedAt})
+
+		result, err := customerCollection.UpdateOne(curCtx, bson.M{"customer_id": customerId}, bson.D{
+			{Key: "$set", Value: updatedObj},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Customer updation failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Customer was not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// GetDuplicateCustomers groups the customers that share a phone or email
+func GetDuplicateCustomers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		duplicates := []gin.H{}
+		for _, field := range []string{"phone", "email"} {
+			cursor, err := customerCollection.Aggregate(curCtx, mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"merged_into": nil, field: bson.M{"$nin": bson.A{nil, ""}}}}},
+				{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$" + field},
+					{Key: "customer_ids", Value: bson.D{{Key: "$push", Value: "$customer_id"}}},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				}}},
+				{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+			})
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+				return
+			}
+			var groups []bson.M
+			if err = cursor.All(curCtx, &groups); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching customers"})
+				return
+			}
+			for _, group := range groups {
+				duplicates = append(duplicates, gin.H{
+					"field":        field,
+					"value":        group["_id"],
+					"customer_ids": group["customer_ids"],
+				})
+			}
+		}
+		ctx.JSON(http.StatusOK, duplicates)
+	}
+}
+
+// MergeCustomer folds a duplicate into this customer. Orders, invoices
+// and waitlist entries move over and the duplicate is kept, marked as
+// merged, so old references still resolve.
+func MergeCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			CustomerID string `json:"customer_id" validate:"required"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		targetId := ctx.Param("customer_id")
+		if body.CustomerID == targetId {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "A customer can not be merged into itself"})
+			return
+		}
+
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			target, err := findCustomer(sessCtx, targetId)
+			if err != nil {
+				return err
+			}
+			source, err := findCustomer(sessCtx, body.CustomerID)
+			if err != nil {
+				return err
+			}
+			if target.MergedInto != nil || source.MergedInto != nil {
+				return requestError{http.StatusConflict, "Customer was already merged"}
+			}
+
+			updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			var updatedObj primitive.D
+			if target.LastName == nil && source.LastName != nil {
+				updatedObj = append(updatedObj, bson.E{Key: "last_name", Value: source.LastName})
+			}
+			if target.Phone == nil && source.Phone != nil {
+				updatedObj = append(updatedObj, bson.E{Key: "phone", Value: source.Phone})
+			}
+			if target.Email == nil && source.Email != nil {
+				updatedObj = append(updatedObj, bson.E{Key: "email", Value: source.Email})
+			}
+			if target.Birthday == nil && source.Birthday != nil {
+				updatedObj = append(updatedObj, bson.E{Key: "birthday", Value: source.Birthday})
+			}
+			if target.Notes == nil && source.Notes != nil {
+				updatedObj = append(updatedObj, bson.E{Key: "notes", Value: source.Notes})
+			}
+			preferences := append(target.Preferences, source.Preferences...)
+			seen := map[string]bool{}
+			merged := []string{}
+			for _, preference := range preferences {
+				if !seen[preference] {
+					seen[preference] = true
+					merged = append(merged, preference)
+				}
+			}
+			updatedObj = append(updatedObj,
+				bson.E{Key: "preferences", Value: merged},
+				bson.E{Key: "allergies", Value: normalizeTags(append(target.Allergies, source.Allergies...))},
+				bson.E{Key: "updated_at", Value: updatedAt},
+			)
+			_, err = customerCollection.UpdateOne(sessCtx, bson.M{"customer_id": targetId}, bson.D{
+				{Key: "$set", Value: updatedObj},
+			})
+			if err != nil {
+				return err
+			}
+
+			relink := bson.D{{Key: "$set", Value: bson.D{{Key: "customer_id", Value: targetId}}}}
+			for _, collection := range []*mongo.Collection{orderCollection, invoiceCollection, waitlistCollection, loyaltyLedgerCollection, giftCardCollection} {
+				if _, err := collection.UpdateMany(sessCtx, bson.M{"customer_id": source.CustomerID}, relink); err != nil {
+					return err
+				}
+			}
+
+			_, err = customerCollection.UpdateOne(sessCtx, bson.M{"customer_id": source.CustomerID}, bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "merged_into", Value: targetId},
+					{Key: "updated_at", Value: updatedAt},
+				}},
+			})
+			return err
+		})
+		if err != nil {
+			respondError(ctx, err, "Customer merge failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"customer_id": targetId, "merged_customer_id": body.CustomerID})
+	}
+}
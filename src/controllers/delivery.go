@@ -0,0 +1,473 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var deliveryZoneCollection *mongo.Collection = database.OpenCollection(database.Client, "deliveryZone")
+
+// orderStatusFlow lists the statuses each takeaway or delivery status can
+// move on to
+var orderStatusFlow = map[string]map[string][]string{
+	"TAKEAWAY": {
+		"RECEIVED":         {"PREPARING", "CANCELLED"},
+		"PREPARING":        {"READY_FOR_PICKUP", "CANCELLED"},
+		"READY_FOR_PICKUP": {"PICKED_UP", "CANCELLED"},
+	},
+	"DELIVERY": {
+		"RECEIVED":         {"PREPARING", "CANCELLED"},
+		"PREPARING":        {"READY_FOR_PICKUP", "CANCELLED"},
+		"READY_FOR_PICKUP": {"OUT_FOR_DELIVERY", "CANCELLED"},
+		"OUT_FOR_DELIVERY": {"DELIVERED"},
+	},
+}
+
+var finishedOrderStatuses = []string{"PICKED_UP", "DELIVERED", "CANCELLED"}
+
+func offPremises(order models.Order) bool {
+	return order.Type == "TAKEAWAY" || order.Type == "DELIVERY"
+}
+
+// deliveryFee is waived once the items reach the zone's free delivery amount
+func deliveryFee(order models.Order, subtotal float64) float64 {
+	if order.Type != "DELIVERY" || order.DeliveryFee == nil {
+		return 0
+	}
+	if order.FreeDeliveryAbove != nil && subtotal >= *order.FreeDeliveryAbove {
+		return 0
+	}
+	return toFixed(*order.DeliveryFee, 2)
+}
+
+func normalizePostalCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+// zoneForPostalCode picks the active zone with the longest matching prefix
+func zoneForPostalCode(curCtx context.Context, postalCode string) (*models.DeliveryZone, error) {
+	cursor, err := deliveryZoneCollection.Find(curCtx, bson.M{"active": bson.M{"$ne": false}})
+	if err != nil {
+		return nil, err
+	}
+	var zones []models.DeliveryZone
+	if err = cursor.All(curCtx, &zones); err != nil {
+		return nil, err
+	}
+
+	code := normalizePostalCode(postalCode)
+	var match *models.DeliveryZone
+	longest := 0
+	for i, zone := range zones {
+		for _, prefix := range zone.PostalCodes {
+			prefix = normalizePostalCode(prefix)
+			if prefix != "" && strings.HasPrefix(code, prefix) && len(prefix) > longest {
+				match = &zones[i]
+				longest = len(prefix)
+			}
+		}
+	}
+	if match == nil {
+		return nil, requestError{http.StatusBadRequest, fmt.Sprintf("We don't deliver to %s", postalCode)}
+	}
+	return match, nil
+}
+
+// checkPickupTime allows a minute for the clocks of the devices taking
+// orders to be off
+func checkPickupTime(pickupTime *time.Time) error {
+	if pickupTime != nil && pickupTime.Before(time.Now().Add(-time.Minute)) {
+		return requestError{http.StatusBadRequest, "pickup_time must be in the future"}
+	}
+	return nil
+}
+
+// prepareOffPremisesOrder checks the contact and address details of a
+// takeaway or delivery order and prices its delivery
+func prepareOffPremisesOrder(curCtx context.Context, order *models.Order) error {
+	if order.ContactName == nil || strings.TrimSpace(*order.ContactName) == "" ||
+		order.ContactPhone == nil || strings.TrimSpace(*order.ContactPhone) == "" {
+		return requestError{http.StatusBadRequest, "contact_name and contact_phone are required"}
+	}
+	phone := normalizePhone(*order.ContactPhone)
+	order.ContactPhone = &phone
+	if err := checkPickupTime(order.PickupTime); err != nil {
+		return err
+	}
+	order.TableID = nil
+	order.Covers = nil
+
+	if order.Type == "DELIVERY" {
+		if order.DeliveryAddress == nil {
+			return requestError{http.StatusBadRequest, "delivery_address is required"}
+		}
+		if err := validate.Struct(order.DeliveryAddress); err != nil {
+			return requestError{http.StatusBadRequest, err.Error()}
+		}
+		var zone *models.DeliveryZone
+		if order.DeliveryZoneID != nil {
+			var found models.DeliveryZone
+			err := deliveryZoneCollection.FindOne(curCtx, bson.M{"zone_id": order.DeliveryZoneID}).Decode(&found)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return requestError{http.StatusNotFound, "Delivery zone was not found"}
+			}
+			if err != nil {
+				return err
+			}
+			zone = &found
+		} else {
+			found, err := zoneForPostalCode(curCtx, order.DeliveryAddress.PostalCode)
+			if err != nil {
+				return err
+			}
+			zone = found
+		}
+		order.DeliveryZoneID = &zone.ZoneID
+		order.DeliveryFee = zone.Fee
+		order.FreeDeliveryAbove = zone.FreeAbove
+	} else {
+		order.DeliveryAddress = nil
+		order.DeliveryZoneID = nil
+		order.DeliveryFee = nil
+		order.FreeDeliveryAbove = nil
+	}
+
+	status := "RECEIVED"
+	order.Status = &status
+	order.StatusHistory = []models.OrderStatusChange{{Status: status, At: time.Now()}}
+	return nil
+}
+
+func GetDeliveryZones() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := deliveryZoneCollection.Find(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching delivery zones",
+			})
+			return
+		}
+
+		var allZones []bson.M
+		if err = result.All(curCtx, &allZones); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allZones)
+	}
+}
+
+// GetDeliveryQuote tells the caller which zone and fee apply to a postal code
+func GetDeliveryQuote() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		zone, err := zoneForPostalCode(curCtx, ctx.Query("postal_code"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching delivery zones")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"zone_id":           zone.ZoneID,
+			"name":              zone.Name,
+			"fee":               zone.Fee,
+			"free_above":        zone.FreeAbove,
+			"estimated_minutes": zone.EstimatedMins,
+		})
+	}
+}
+
+func CreateDeliveryZone() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var zone models.DeliveryZone
+
+		if err := ctx.BindJSON(&zone); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(zone)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		for i, code := range zone.PostalCodes {
+			zone.PostalCodes[i] = normalizePostalCode(code)
+		}
+		if zone.Active == nil {
+			active := true
+			zone.Active = &active
+		}
+		zone.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		zone.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		zone.ID = primitive.NewObjectID()
+		zone.ZoneID = zone.ID.Hex()
+
+		result, insertErr := deliveryZoneCollection.InsertOne(curCtx, zone)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Delivery zone was not created",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateDeliveryZone() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var zone models.DeliveryZone
+
+		if err := ctx.BindJSON(&zone); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var updatedObj primitive.D
+		var fields []string
+
+		if zone.Name != nil {
+			fields = append(fields, "Name")
+			updatedObj = append(updatedObj, bson.E{Key: "name", Value: zone.Name})
+		}
+		if zone.PostalCodes != nil {
+			for i, code := range zone.PostalCodes {
+				zone.PostalCodes[i] = normalizePostalCode(code)
+			}
+			fields = append(fields, "PostalCodes")
+			updatedObj = append(updatedObj, bson.E{Key: "postal_codes", Value: zone.PostalCodes})
+		}
+		if zone.Fee != nil {
+			fields = append(fields, "Fee")
+			updatedObj = append(updatedObj, bson.E{Key: "fee", Value: zone.Fee})
+		}
+		if zone.FreeAbove != nil {
+			fields = append(fields, "FreeAbove")
+			updatedObj = append(updatedObj, bson.E{Key: "free_above", Value: zone.FreeAbove})
+		}
+		if zone.EstimatedMins != nil {
+			fields = append(fields, "EstimatedMins")
+			updatedObj = append(updatedObj, bson.E{Key: "estimated_minutes", Value: zone.EstimatedMins})
+		}
+		if zone.Active != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "active", Value: zone.Active})
+		}
+		if len(fields) > 0 {
+			if err := validate.StructPartial(zone, fields...); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+
+		zone.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: zone.UpdatedAt})
+
+		result, err := deliveryZoneCollection.UpdateOne(curCtx, bson.M{"zone_id": ctx.Param("zone_id")}, bson.D{
+			{Key: "$set", Value: updatedObj},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Delivery zone updation failed",
+			})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Delivery zone was not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func DeleteDeliveryZone() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := deliveryZoneCollection.DeleteOne(curCtx, bson.M{"zone_id": ctx.Param("zone_id")})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Delivery zone deletion failed",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateOrderStatus moves a takeaway or delivery order along its lifecycle
+func UpdateOrderStatus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Status string `json:"status" validate:"required"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		order, err := findOrder(curCtx, ctx.Param("order_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching order")
+			return
+		}
+		if !offPremises(order) || order.Status == nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "Only takeaway and delivery orders have a status",
+			})
+			return
+		}
+
+		next := strings.ToUpper(strings.TrimSpace(body.Status))
+		allowed := false
+		for _, status := range orderStatusFlow[order.Type][*order.Status] {
+			allowed = allowed || status == next
+		}
+		if !allowed {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("Order can't move from %s to %s", *order.Status, next),
+			})
+			return
+		}
+
+		change := models.OrderStatusChange{Status: next, At: time.Now()}
+		if uid := currentUser(ctx); uid != nil {
+			change.UserID = *uid
+		}
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			// the status guard keeps two concurrent updates from both applying
+			result, err := orderCollection.UpdateOne(sessCtx,
+				bson.M{"order_id": order.OrderID, "status": order.Status},
+				bson.M{
+					"$set":  bson.M{"status": next, "updated_at": updatedAt},
+					"$push": bson.M{"status_history": change},
+				},
+			)
+			if err != nil {
+				return err
+			}
+			if result.MatchedCount == 0 {
+				return requestError{http.StatusConflict, "Order status changed in the meantime"}
+			}
+			return publish(sessCtx, events.OrderStatusChanged, order.OrderID, orderStatusChange{
+				OrderID:         order.OrderID,
+				Type:            order.Type,
+				Platform:        order.Platform,
+				ExternalOrderID: order.ExternalOrderID,
+				PreviousStatus:  *order.Status,
+				Status:          next,
+				At:              change.At,
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Order updation failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": order.OrderID, "status": next})
+	}
+}
+
+// GetOrderQueue lists open takeaway and delivery orders grouped by status,
+// soonest pickup first
+func GetOrderQueue() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		types := []string{"TAKEAWAY", "DELIVERY"}
+		if orderType := strings.ToUpper(ctx.Query("type")); orderType != "" {
+			if _, ok := orderStatusFlow[orderType]; !ok {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": "type must be TAKEAWAY or DELIVERY",
+				})
+				return
+			}
+			types = []string{orderType}
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "order_date", Value: 1}})
+		cursor, err := orderCollection.Find(curCtx, bson.M{
+			"type":   bson.M{"$in": types},
+			"status": bson.M{"$nin": finishedOrderStatuses},
+			"$or":    releasedOrders,
+		}, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching orders",
+			})
+			return
+		}
+		var orders []models.Order
+		if err = cursor.All(curCtx, &orders); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching orders",
+			})
+			return
+		}
+
+		// orders without a pickup time are wanted as soon as possible
+		dueAt := func(order models.Order) time.Time {
+			if order.PickupTime != nil {
+				return *order.PickupTime
+			}
+			return order.OrderDate
+		}
+		sort.SliceStable(orders, func(i, j int) bool {
+			return dueAt(orders[i]).Before(dueAt(orders[j]))
+		})
+
+		queue := map[string][]models.Order{}
+		for _, orderType := range types {
+			for status := range orderStatusFlow[orderType] {
+				queue[status] = []models.Order{}
+			}
+		}
+		for _, order := range orders {
+			if order.Status != nil {
+				queue[*order.Status] = append(queue[*order.Status], order)
+			}
+		}
+		ctx.JSON(http.StatusOK, queue)
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var outboxCollection *mongo.Collection = database.OpenCollection(database.Client, "outbox")
+
+// bus carries domain events from the handlers that change state to the
+// side effects that follow from them
+var bus = newEventBus(events.NewMongoOutbox(outboxCollection))
+
+func newEventBus(outbox events.Outbox) *events.Bus {
+	bus := events.NewBus(outbox)
+	bus.Subscribe("*", "webhooks", queueWebhooks)
+	bus.Subscribe(events.OrderStatusChanged, "platform-status", pushPlatformStatus)
+	return bus
+}
+
+// publish records an event in the outbox. Pass the unit of work's context
+// so it is only kept if the change commits.
+func publish(curCtx context.Context, name string, key string, data interface{}) error {
+	return bus.Publish(curCtx, name, key, data)
+}
+
+// publishItemsAdded publishes OrderItemAdded for each of a batch of new
+// order items
+func publishItemsAdded(curCtx context.Context, items []interface{}) error {
+	for _, item := range items {
+		orderItem, _ := item.(models.OrderItem)
+		if err := publish(curCtx, events.OrderItemAdded, orderItem.OrderItemID, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DispatchEvents hands the outbox to its subscribers. It runs in the
+// background from main.
+func DispatchEvents(curCtx context.Context) error {
+	return bus.Dispatch(curCtx)
+}
+
+// orderStatusChange is the data of an OrderStatusChanged event
+type orderStatusChange struct {
+	OrderID         string    `json:"order_id"`
+	Type            string    `json:"type"`
+	Platform        *string   `json:"platform,omitempty"`
+	ExternalOrderID *string   `json:"external_order_id,omitempty"`
+	PreviousStatus  string    `json:"previous_status"`
+	Status          string    `json:"status"`
+	At              time.Time `json:"at"`
+}
+
+// tableStatusChange is the data of a TableStatusChanged event
+type tableStatusChange struct {
+	TableID        string    `json:"table_id"`
+	TableNumber    *int      `json:"table_number,omitempty"`
+	PreviousStatus *string   `json:"previous_status"`
+	Status         string    `json:"status"`
+	At             time.Time `json:"at"`
+}
+
+// GetOutboxEvents lists outbox events newest first, filtered by name, key
+// and status, e.g. ?status=FAILED for those that ran out of attempts
+func GetOutboxEvents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		for _, key := range []string{"name", "key", "status"} {
+			if value := ctx.Query(key); value != "" {
+				filter[key] = value
+			}
+		}
+		recordPerPage, err := strconv.Atoi(ctx.Query("recordPerPage"))
+		if err != nil || recordPerPage < 1 || recordPerPage > 500 {
+			recordPerPage = 50
+		}
+		page, err := strconv.Atoi(ctx.Query("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		total, err := outboxCollection.CountDocuments(curCtx, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching events"})
+			return
+		}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "occurred_at", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip(int64((page - 1) * recordPerPage)).
+			SetLimit(int64(recordPerPage))
+		cursor, err := outboxCollection.Find(curCtx, filter, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching events"})
+			return
+		}
+		entries := []bson.M{}
+		if err = cursor.All(curCtx, &entries); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching events"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"total_count": total,
+			"page":        page,
+			"events":      entries,
+		})
+	}
+}
+
+// RetryOutboxEvent gives a failed event a fresh set of attempts. Only the
+// subscribers that haven't handled it yet get it again.
+func RetryOutboxEvent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := outboxCollection.UpdateOne(curCtx,
+			bson.M{"event_id": ctx.Param("event_id"), "status": events.Failed},
+			bson.M{"$set": bson.M{"status": events.Pending, "attempts": 0, "next_attempt_at": now}},
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Event was not retried"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No failed event with that id"})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, gin.H{"event_id": ctx.Param("event_id"), "status": events.Pending})
+	}
+}
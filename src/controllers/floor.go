@@ -0,0 +1,449 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TableState is a table as the floor sees it right now
+type TableState struct {
+	TableID        string     `json:"table_id"`
+	TableNumber    *int       `json:"table_number"`
+	AreaID         *string    `json:"area_id,omitempty"`
+	Section        *string    `json:"section,omitempty"`
+	X              *float64   `json:"x,omitempty"`
+	Y              *float64   `json:"y,omitempty"`
+	Shape          *string    `json:"shape,omitempty"`
+	Seats          *int       `json:"seats,omitempty"`
+	NumberOfGuests *int       `json:"number_of_guests,omitempty"`
+	Status         string     `json:"status"`
+	ServerID       *string    `json:"server_id,omitempty"`
+	ServerName     string     `json:"server_name,omitempty"`
+	OrderID        string     `json:"order_id,omitempty"`
+	SeatedAt       *time.Time `json:"seated_at,omitempty"`
+	ElapsedMinutes int        `json:"elapsed_minutes,omitempty"`
+}
+
+// deriveTableStatus works the status out from the table's latest order:
+// an unpaid order means the table is in use, a freshly paid one means it
+// needs cleaning, otherwise the status staff last set applies
+func deriveTableStatus(table models.Table, order *models.Order, itemCount int64, invoice *models.Invoice) string {
+	paid := invoice != nil && invoice.PaymentStatus != nil && *invoice.PaymentStatus == "PAID"
+
+	if order != nil && !paid {
+		if invoice != nil {
+			return "AWAITING_PAYMENT"
+		}
+		if itemCount > 0 {
+			return "ORDERED"
+		}
+		return "SEATED"
+	}
+
+	if paid && (table.StatusAt == nil || invoice.UpdatedAt.After(*table.StatusAt)) {
+		return "NEEDS_CLEANING"
+	}
+	if table.Status != nil {
+		return *table.Status
+	}
+	return "AVAILABLE"
+}
+
+func tableStates(curCtx context.Context, tables []models.Table) ([]TableState, error) {
+	tableIds := []string{}
+	serverIds := []string{}
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+		if table.ServerID != nil {
+			serverIds = append(serverIds, *table.ServerID)
+		}
+	}
+
+	cursor, err := orderCollection.Aggregate(curCtx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "table_id", Value: bson.D{{Key: "$in", Value: tableIds}}},
+			{Key: "merged_into", Value: bson.D{{Key: "$exists", Value: false}}},
+			{Key: "$or", Value: releasedOrders},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$table_id"},
+			{Key: "order", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var latest []struct {
+		TableID string       `bson:"_id"`
+		Order   models.Order `bson:"order"`
+	}
+	if err = cursor.All(curCtx, &latest); err != nil {
+		return nil, err
+	}
+
+	orders := map[string]*models.Order{}
+	orderIds := []string{}
+	for i := range latest {
+		orders[latest[i].TableID] = &latest[i].Order
+		orderIds = append(orderIds, latest[i].Order.OrderID)
+	}
+
+	invoices := map[string]*models.Invoice{}
+	cursor, err = invoiceCollection.Find(curCtx, bson.M{"order_id": bson.M{"$in": orderIds}})
+	if err != nil {
+		return nil, err
+	}
+	var allInvoices []models.Invoice
+	if err = cursor.All(curCtx, &allInvoices); err != nil {
+		return nil, err
+	}
+	for i := range allInvoices {
+		invoices[allInvoices[i].OrderId] = &allInvoices[i]
+	}
+
+	itemCounts := map[string]int64{}
+	cursor, err = orderItemCollection.Aggregate(curCtx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "order_id", Value: bson.D{{Key: "$in", Value: orderIds}}}, {Key: "status", Value: bson.D{{Key: "$ne", Value: "VOIDED"}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$order_id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		OrderID string `bson:"_id"`
+		Count   int64  `bson:"count"`
+	}
+	if err = cursor.All(curCtx, &counts); err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		itemCounts[count.OrderID] = count.Count
+	}
+
+	serverNames, err := userNames(curCtx, serverIds)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	states := []TableState{}
+	for _, table := range tables {
+		order := orders[table.TableID]
+		var invoice *models.Invoice
+		var itemCount int64
+		if order != nil {
+			invoice = invoices[order.OrderID]
+			itemCount = itemCounts[order.OrderID]
+		}
+
+		state := TableState{
+			TableID:        table.TableID,
+			TableNumber:    table.TableNumber,
+			AreaID:         table.AreaID,
+			Section:        table.Section,
+			X:              table.X,
+			Y:              table.Y,
+			Shape:          table.Shape,
+			Seats:          table.Seats,
+			NumberOfGuests: table.NumberOfGuests,
+			ServerID:       table.ServerID,
+			Status:         deriveTableStatus(table, order, itemCount, invoice),
+		}
+		if table.ServerID != nil {
+			state.ServerName = serverNames[*table.ServerID]
+		}
+		switch state.Status {
+		case "SEATED", "ORDERED", "AWAITING_PAYMENT":
+			seatedAt := order.CreatedAt
+			state.OrderID = order.OrderID
+			state.SeatedAt = &seatedAt
+			state.ElapsedMinutes = int(now.Sub(seatedAt).Minutes())
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func userNames(curCtx context.Context, userIds []string) (map[string]string, error) {
+	names := map[string]string{}
+	if len(userIds) == 0 {
+		return names, nil
+	}
+	cursor, err := userCollection.Find(curCtx, bson.M{"user_id": bson.M{"$in": userIds}})
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err = cursor.All(curCtx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		name := ""
+		if user.FirstName != nil {
+			name = *user.FirstName
+		}
+		if user.LastName != nil {
+			name += " " + *user.LastName
+		}
+		names[user.UserID] = name
+	}
+	return names, nil
+}
+
+func GetFloor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		areaFilter := bson.M{}
+		tableFilter := bson.M{}
+		if areaId := ctx.Query("area_id"); areaId != "" {
+			areaFilter["area_id"] = areaId
+			tableFilter["area_id"] = areaId
+		}
+		if ctx.Query("mine") == "true" {
+			mine, err := myTablesFilter(curCtx, ctx.GetString("uid"))
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching section assignments"})
+				return
+			}
+			for key, value := range mine {
+				tableFilter[key] = value
+			}
+		}
+
+		cursor, err := areaCollection.Find(curCtx, areaFilter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching areas"})
+			return
+		}
+		var areas []models.Area
+		if err = cursor.All(curCtx, &areas); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching areas"})
+			return
+		}
+		sort.SliceStable(areas, func(i, j int) bool {
+			return sortOrder(areas[i].SortOrder) < sortOrder(areas[j].SortOrder)
+		})
+
+		cursor, err = tableCollection.Find(curCtx, tableFilter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching all tables"})
+			return
+		}
+		var tables []models.Table
+		if err = cursor.All(curCtx, &tables); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching all tables"})
+			return
+		}
+
+		states, err := tableStates(curCtx, tables)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching table status"})
+			return
+		}
+
+		byArea := map[string][]TableState{}
+		summary := map[string]int{}
+		for _, state := range states {
+			areaId := ""
+			if state.AreaID != nil {
+				areaId = *state.AreaID
+			}
+			byArea[areaId] = append(byArea[areaId], state)
+			summary[state.Status]++
+		}
+
+		floor := []gin.H{}
+		for _, area := range areas {
+			tables := byArea[area.AreaID]
+			if tables == nil {
+				tables = []TableState{}
+			}
+			floor = append(floor, gin.H{
+				"area_id": area.AreaID,
+				"name":    area.Name,
+				"width":   area.Width,
+				"height":  area.Height,
+				"tables":  tables,
+			})
+			delete(byArea, area.AreaID)
+		}
+
+		// tables whose area is missing or was deleted
+		unassigned := []TableState{}
+		for _, tables := range byArea {
+			unassigned = append(unassigned, tables...)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"areas":      floor,
+			"unassigned": unassigned,
+			"summary":    summary,
+			"as_of":      time.Now().Format(time.RFC3339),
+		})
+	}
+}
+
+func sortOrder(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// updateTableStatus runs change and publishes TableStatusChanged for
+// each of the tables whose status it moved. Call it inside the unit of
+// work, so both statuses are read in the same transaction as the change
+// and the events only commit with it.
+func updateTableStatus(sessCtx context.Context, tableIds []string, change func() error) error {
+	if len(tableIds) == 0 {
+		return change()
+	}
+	before, err := tableStatesByID(sessCtx, tableIds)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := tableStatesByID(sessCtx, tableIds)
+	if err != nil {
+		return err
+	}
+
+	at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	for _, tableId := range tableIds {
+		state, ok := after[tableId]
+		if !ok {
+			continue
+		}
+		var previous *string
+		if old, ok := before[tableId]; ok {
+			if old.Status == state.Status {
+				continue
+			}
+			previous = &old.Status
+		}
+		err := publish(sessCtx, events.TableStatusChanged, tableId, tableStatusChange{
+			TableID:        tableId,
+			TableNumber:    state.TableNumber,
+			PreviousStatus: previous,
+			Status:         state.Status,
+			At:             at,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableStatesByID(curCtx context.Context, tableIds []string) (map[string]TableState, error) {
+	cursor, err := tableCollection.Find(curCtx, bson.M{"table_id": bson.M{"$in": tableIds}})
+	if err != nil {
+		return nil, err
+	}
+	var tables []models.Table
+	if err = cursor.All(curCtx, &tables); err != nil {
+		return nil, err
+	}
+	states, err := tableStates(curCtx, tables)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[string]TableState{}
+	for _, state := range states {
+		byId[state.TableID] = state
+	}
+	return byId, nil
+}
+
+// tableIdsOf lists the tables that are set, once each
+func tableIdsOf(tableIds ...*string) []string {
+	ids := []string{}
+	for _, tableId := range tableIds {
+		if tableId != nil && !contains(ids, *tableId) {
+			ids = append(ids, *tableId)
+		}
+	}
+	return ids
+}
+
+// orderTables is the table of an order, none if it has no table or the
+// order doesn't exist
+func orderTables(curCtx context.Context, orderId string) ([]string, error) {
+	var order models.Order
+	err := orderCollection.FindOne(curCtx, bson.M{"order_id": orderId}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tableIdsOf(order.TableID), nil
+}
+
+// UpdateTableStatus sets the statuses staff control by hand; the others
+// follow from orders and invoices
+func UpdateTableStatus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Status *string `json:"status" validate:"required,eq=AVAILABLE|eq=NEEDS_CLEANING|eq=RESERVED"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		tableId := ctx.Param("table_id")
+		var result *mongo.UpdateResult
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, []string{tableId}, func() error {
+				updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				var err error
+				result, err = tableCollection.UpdateOne(sessCtx,
+					bson.M{"table_id": tableId},
+					bson.D{{Key: "$set", Value: bson.D{
+						{Key: "status", Value: body.Status},
+						{Key: "status_at", Value: updatedAt},
+						{Key: "updated_at", Value: updatedAt},
+					}}},
+				)
+				if err != nil {
+					return err
+				}
+				if result.MatchedCount == 0 {
+					return requestError{http.StatusNotFound, "Table was not found"}
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Table updation failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"infinity/rms/database"
+	"infinity/rms/helpers"
 	"infinity/rms/models"
 	"log"
 	"math"
@@ -42,12 +43,24 @@ func GetFoods() gin.HandlerFunc {
 		startIndex := (page - 1) * recordPerPage
 		startIndex, err = strconv.Atoi(ctx.Query("startIndex"))
 
+		// menu filters by menu, allergens to leave out and dietary tags to require
+		filter := bson.D{}
+		if menuId := ctx.Query("menu_id"); menuId != "" {
+			filter = append(filter, bson.E{Key: "menu_id", Value: menuId})
+		}
+		if exclude := splitTags(ctx.Query("exclude_allergens")); len(exclude) > 0 {
+			filter = append(filter, bson.E{Key: "allergens", Value: bson.D{{Key: "$nin", Value: exclude}}})
+		}
+		if dietary := splitTags(ctx.Query("dietary")); len(dietary) > 0 {
+			filter = append(filter, bson.E{Key: "dietary_tags", Value: bson.D{{Key: "$all", Value: dietary}}})
+		}
+
 		// match stage, group stage, project stage
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
+		matchStage := bson.D{{Key: "$match", Value: filter}}
 		groupStage := bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "_id", Value: "null"}}}, {Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}}, {Key: "data", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}}}}}
 		projectStage := bson.D{
 			{
-				Key: "$projeValue: ct", Value: bson.D{
+				Key: "$project", Value: bson.D{
 					{Key: "_id", Value: 0},
 					{Key: "total_count", Value: 1},
 					{
@@ -77,6 +90,15 @@ func GetFoods() gin.HandlerFunc {
 		if err = result.All(c, &foods); err != nil {
 			log.Fatal(err)
 		}
+		locales := requestLocales(ctx)
+		for _, page := range foods {
+			items, _ := page["food_items"].(bson.A)
+			for _, item := range items {
+				if food, ok := item.(bson.M); ok {
+					localizeDoc(food, locales, "name")
+				}
+			}
+		}
 		ctx.JSON(http.StatusOK, foods)
 	}
 }
@@ -97,6 +119,11 @@ func GetFood() gin.HandlerFunc {
 			})
 		}
 
+		if food.Name != nil {
+			name := helpers.Localize(food.NameTranslations, *food.Name, requestLocales(ctx))
+			food.Name = &name
+		}
+
 		ctx.JSON(http.StatusOK, food)
 	}
 }
@@ -130,6 +157,25 @@ func CreateFood() gin.HandlerFunc {
 			})
 		}
 
+		food.Allergens = normalizeTags(food.Allergens)
+		food.DietaryTags = normalizeTags(food.DietaryTags)
+		if food.Station != nil {
+			station := normalizeStation(*food.Station)
+			food.Station = &station
+		}
+		if err := checkAllergens(curCtx, food.Allergens); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := checkDietaryTags(food.DietaryTags); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
 		food.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
@@ -157,10 +203,11 @@ func UpdateFood() gin.HandlerFunc {
 		var menu models.Menu
 		var food models.Food
 
-		if err := ctx.BindJSON(&menu); err != nil {
+		if err := ctx.BindJSON(&food); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{
 				"error": err.Error(),
 			})
+			return
 		}
 		foodId := ctx.Param("food_id")
 
@@ -177,6 +224,9 @@ func UpdateFood() gin.HandlerFunc {
 		if food.FoodImage != nil {
 			updateObj = append(updateObj, bson.E{Key: "food_image", Value: food.FoodImage})
 		}
+		if food.SKU != nil {
+			updateObj = append(updateObj, bson.E{Key: "sku", Value: food.SKU})
+		}
 		if food.MenuId != nil {
 			err := menuCollection.FindOne(curCtx, bson.M{"menu_id": food.MenuId}).Decode(&menu)
 			if err != nil {
@@ -186,6 +236,47 @@ func UpdateFood() gin.HandlerFunc {
 			}
 			updateObj = append(updateObj, bson.E{Key: "menu_id", Value: food.MenuId})
 		}
+		if food.Allergens != nil {
+			food.Allergens = normalizeTags(food.Allergens)
+			if err := checkAllergens(curCtx, food.Allergens); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "allergens", Value: food.Allergens})
+		}
+		if food.DietaryTags != nil {
+			food.DietaryTags = normalizeTags(food.DietaryTags)
+			if err := checkDietaryTags(food.DietaryTags); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "dietary_tags", Value: food.DietaryTags})
+		}
+		if food.PackagingFee != nil {
+			if *food.PackagingFee < 0 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "packaging_fee can't be negative"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "packaging_fee", Value: food.PackagingFee})
+		}
+		if food.Course != nil {
+			if err := validate.StructPartial(food, "Course"); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "course", Value: food.Course})
+		}
+		if food.Station != nil {
+			station := normalizeStation(*food.Station)
+			updateObj = append(updateObj, bson.E{Key: "station", Value: station})
+		}
+		if food.PrepMinutes != nil {
+			if *food.PrepMinutes < 1 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "prep_minutes must be at least 1"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "prep_minutes", Value: food.PrepMinutes})
+		}
 
 		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
@@ -196,7 +287,7 @@ func UpdateFood() gin.HandlerFunc {
 		opt := options.UpdateOptions{
 			Upsert: &upsert,
 		}
-		result, err := menuCollection.UpdateOne(
+		result, err := foodCollection.UpdateOne(
 			curCtx, filter, bson.D{
 				{Key: "$set", Value: updateObj},
 			},
@@ -214,7 +305,7 @@ func UpdateFood() gin.HandlerFunc {
 }
 
 func round(num float64) int {
-	return int(num * math.Copysign(0.5, num))
+	return int(num + math.Copysign(0.5, num))
 }
 
 func toFixed(num float64, precision int) float64 {
@@ -0,0 +1,602 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"infinity/rms/database"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var giftCardCollection *mongo.Collection = database.OpenCollection(database.Client, "giftCard")
+var giftCardTransactionCollection *mongo.Collection = database.OpenCollection(database.Client, "giftCardTransaction")
+
+// no 0/O or 1/I, so codes survive being read out over the phone
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generateGiftCardCode() (string, error) {
+	var b strings.Builder
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(giftCardAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(giftCardAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+func findGiftCard(curCtx context.Context, code string) (models.GiftCard, error) {
+	var card models.GiftCard
+	err := giftCardCollection.FindOne(curCtx, bson.M{"code": helpers.NormalizeGiftCardCode(code)}).Decode(&card)
+	if err == mongo.ErrNoDocuments {
+		return card, requestError{http.StatusNotFound, "Gift card was not found"}
+	}
+	return card, err
+}
+
+func usableGiftCard(card models.GiftCard) error {
+	if card.Status != "ACTIVE" {
+		return requestError{http.StatusConflict, "Gift card is void"}
+	}
+	if card.ExpiresAt != nil && card.ExpiresAt.Before(time.Now()) {
+		return requestError{http.StatusConflict, "Gift card has expired"}
+	}
+	return nil
+}
+
+func insertGiftCardTransaction(curCtx context.Context, transaction models.GiftCardTransaction) (models.GiftCardTransaction, error) {
+	transaction.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	transaction.ID = primitive.NewObjectID()
+	transaction.TransactionID = transaction.ID.Hex()
+	_, err := giftCardTransactionCollection.InsertOne(curCtx, transaction)
+	return transaction, err
+}
+
+// moveGiftCardBalance changes the balance by amount, refusing to take it
+// below zero, and logs the transaction. Both happen in one unit of work,
+// the caller's if it has one.
+func moveGiftCardBalance(curCtx context.Context, card models.GiftCard, transaction models.GiftCardTransaction) (models.GiftCardTransaction, error) {
+	filter := bson.M{"gift_card_id": card.GiftCardID}
+	if transaction.Amount < 0 {
+		filter["balance"] = bson.M{"$gte": -transaction.Amount - 0.005}
+	}
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	var logged models.GiftCardTransaction
+	err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+		var updated models.GiftCard
+		err := giftCardCollection.FindOneAndUpdate(sessCtx, filter, bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "balance", Value: transaction.Amount}}},
+			{Key: "$set", Value: bson.D{{Key: "updated_at", Value: updatedAt}}},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			return requestError{http.StatusConflict, "Gift card balance is too low"}
+		}
+		if err != nil {
+			return err
+		}
+		// keep the stored balance to cents
+		if rounded := toFixed(updated.Balance, 2); rounded != updated.Balance {
+			_, err := giftCardCollection.UpdateOne(sessCtx, bson.M{"gift_card_id": card.GiftCardID}, bson.D{
+				{Key: "$set", Value: bson.D{{Key: "balance", Value: rounded}}},
+			})
+			if err != nil {
+				return err
+			}
+			updated.Balance = rounded
+		}
+		logged = transaction
+		logged.GiftCardID = card.GiftCardID
+		logged.BalanceAfter = updated.Balance
+		logged, err = insertGiftCardTransaction(sessCtx, logged)
+		return err
+	})
+	if err != nil {
+		return transaction, err
+	}
+	return logged, nil
+}
+
+// invoiceDue is what is left to pay on the invoice after discounts and
+// part payments, including the tip
+func invoiceDue(curCtx context.Context, invoice models.Invoice) (float64, error) {
+	_, subtotal, err := receiptLines(curCtx, invoice.OrderId, nil)
+	if err != nil {
+		return 0, err
+	}
+	due := subtotal - discountTotal(invoice)
+	if invoice.Tip != nil {
+		due += *invoice.Tip
+	}
+	for _, payment := range invoice.Payments {
+		due -= payment.Amount
+	}
+	if due < 0 {
+		due = 0
+	}
+	return toFixed(due, 2), nil
+}
+
+// refundGiftCardPayments puts the gift card part of a refunded invoice
+// back on the cards, once
+func refundGiftCardPayments(curCtx context.Context, invoice models.Invoice) error {
+	invoiceId := invoice.InvoiceId
+	cursor, err := giftCardTransactionCollection.Find(curCtx, bson.M{"invoice_id": invoiceId, "type": "REFUND"})
+	if err != nil {
+		return err
+	}
+	var refunds []models.GiftCardTransaction
+	if err = cursor.All(curCtx, &refunds); err != nil {
+		return err
+	}
+
+	for _, payment := range unrefundedPayments(invoice.Payments, refunds) {
+		var card models.GiftCard
+		if err := giftCardCollection.FindOne(curCtx, bson.M{"gift_card_id": payment.GiftCardID}).Decode(&card); err != nil {
+			return err
+		}
+		_, err := moveGiftCardBalance(curCtx, card, models.GiftCardTransaction{
+			Type:      "REFUND",
+			Amount:    payment.Amount,
+			InvoiceID: &invoiceId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unrefundedPayments are the gift card payments whose card has had no
+// refund for the invoice yet. An invoice is refunded in one go, so a card
+// with a refund has had all of its payments back.
+func unrefundedPayments(payments []models.InvoicePayment, refunds []models.GiftCardTransaction) []models.InvoicePayment {
+	refunded := map[string]bool{}
+	for _, refund := range refunds {
+		refunded[refund.GiftCardID] = true
+	}
+	pending := []models.InvoicePayment{}
+	for _, payment := range payments {
+		if payment.Method != "GIFT_CARD" || payment.GiftCardID == nil || refunded[*payment.GiftCardID] {
+			continue
+		}
+		pending = append(pending, payment)
+	}
+	return pending
+}
+
+func GetGiftCards() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if status := ctx.Query("status"); status != "" {
+			filter["status"] = strings.ToUpper(status)
+		}
+		if customerId := ctx.Query("customer_id"); customerId != "" {
+			filter["customer_id"] = customerId
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		cursor, err := giftCardCollection.Find(curCtx, filter, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift cards"})
+			return
+		}
+		cards := []models.GiftCard{}
+		if err = cursor.All(curCtx, &cards); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift cards"})
+			return
+		}
+		ctx.JSON(http.StatusOK, cards)
+	}
+}
+
+// GetGiftCard is the balance enquiry, with the card's history
+func GetGiftCard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		card, err := findGiftCard(curCtx, ctx.Param("code"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching gift card")
+			return
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		cursor, err := giftCardTransactionCollection.Find(curCtx, bson.M{"gift_card_id": card.GiftCardID}, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift card"})
+			return
+		}
+		transactions := []models.GiftCardTransaction{}
+		if err = cursor.All(curCtx, &transactions); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift card"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"gift_card":    card,
+			"usable":       usableGiftCard(card) == nil,
+			"transactions": transactions,
+		})
+	}
+}
+
+// IssueGiftCard sells a new card loaded with amount
+func IssueGiftCard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Amount        float64    `json:"amount" validate:"gt=0"`
+			PaymentMethod string     `json:"payment_method" validate:"eq=CARD|eq=CASH"`
+			CustomerID    *string    `json:"customer_id"`
+			ExpiresAt     *time.Time `json:"expires_at"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if body.ExpiresAt != nil && body.ExpiresAt.Before(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		var card models.GiftCard
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			if body.CustomerID != nil {
+				if _, err := findCustomer(sessCtx, *body.CustomerID); err != nil {
+					return err
+				}
+			}
+
+			card = models.GiftCard{
+				InitialBalance: toFixed(body.Amount, 2),
+				Balance:        toFixed(body.Amount, 2),
+				Status:         "ACTIVE",
+				CustomerID:     body.CustomerID,
+				ExpiresAt:      body.ExpiresAt,
+			}
+			for {
+				code, err := generateGiftCardCode()
+				if err != nil {
+					return err
+				}
+				count, err := giftCardCollection.CountDocuments(sessCtx, bson.M{"code": code})
+				if err != nil {
+					return err
+				}
+				if count == 0 {
+					card.Code = code
+					break
+				}
+			}
+			card.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			card.UpdatedAt = card.CreatedAt
+			card.ID = primitive.NewObjectID()
+			card.GiftCardID = card.ID.Hex()
+			if _, err := giftCardCollection.InsertOne(sessCtx, card); err != nil {
+				return err
+			}
+
+			_, err := insertGiftCardTransaction(sessCtx, models.GiftCardTransaction{
+				GiftCardID:    card.GiftCardID,
+				Type:          "ISSUE",
+				Amount:        card.Balance,
+				BalanceAfter:  card.Balance,
+				PaymentMethod: &body.PaymentMethod,
+				UserID:        ctx.GetString("uid"),
+			})
+			return err
+		})
+		if err != nil {
+			respondError(ctx, err, "Gift card was not issued")
+			return
+		}
+		ctx.JSON(http.StatusOK, card)
+	}
+}
+
+func TopUpGiftCard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Amount        float64 `json:"amount" validate:"gt=0"`
+			PaymentMethod string  `json:"payment_method" validate:"eq=CARD|eq=CASH"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var transaction models.GiftCardTransaction
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			card, err := findGiftCard(sessCtx, ctx.Param("code"))
+			if err != nil {
+				return err
+			}
+			if err := usableGiftCard(card); err != nil {
+				return err
+			}
+			transaction, err = moveGiftCardBalance(sessCtx, card, models.GiftCardTransaction{
+				Type:          "TOP_UP",
+				Amount:        toFixed(body.Amount, 2),
+				PaymentMethod: &body.PaymentMethod,
+				UserID:        ctx.GetString("uid"),
+			})
+			return err
+		})
+		if err != nil {
+			respondError(ctx, err, "Gift card top up failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, transaction)
+	}
+}
+
+// VoidGiftCard cancels a card, writing off what is left on it
+func VoidGiftCard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var transaction models.GiftCardTransaction
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			card, err := findGiftCard(sessCtx, ctx.Param("code"))
+			if err != nil {
+				return err
+			}
+			if card.Status == "VOID" {
+				return requestError{http.StatusConflict, "Gift card is void"}
+			}
+			transaction, err = moveGiftCardBalance(sessCtx, card, models.GiftCardTransaction{
+				Type:   "VOID",
+				Amount: -card.Balance,
+				UserID: ctx.GetString("uid"),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = giftCardCollection.UpdateOne(sessCtx, bson.M{"gift_card_id": card.GiftCardID}, bson.D{
+				{Key: "$set", Value: bson.D{{Key: "status", Value: "VOID"}}},
+			})
+			return err
+		})
+		if err != nil {
+			respondError(ctx, err, "Gift card void failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, transaction)
+	}
+}
+
+// PayWithGiftCard tenders a gift card against an invoice. Without an
+// amount it takes as much as the card and the bill allow; the invoice is
+// marked paid once nothing is left to pay.
+func PayWithGiftCard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Code   string   `json:"code" validate:"required"`
+			Amount *float64 `json:"amount" validate:"omitempty,gt=0"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var payment models.InvoicePayment
+		var due float64
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			var invoice models.Invoice
+			err := invoiceCollection.FindOne(sessCtx, bson.M{"invoice_id": ctx.Param("invoice_id")}).Decode(&invoice)
+			if err == mongo.ErrNoDocuments {
+				return requestError{http.StatusNotFound, "Invoice was not found"}
+			}
+			if err != nil {
+				return err
+			}
+			if invoice.PaymentStatus == nil || *invoice.PaymentStatus != "PENDING" {
+				return requestError{http.StatusConflict, "Invoice is not awaiting payment"}
+			}
+			card, err := findGiftCard(sessCtx, body.Code)
+			if err != nil {
+				return err
+			}
+			if err := usableGiftCard(card); err != nil {
+				return err
+			}
+
+			due, err = invoiceDue(sessCtx, invoice)
+			if err != nil {
+				return err
+			}
+			amount := due
+			if body.Amount != nil {
+				amount = toFixed(*body.Amount, 2)
+			}
+			if amount > due {
+				return requestError{http.StatusBadRequest, "Amount is more than the amount due"}
+			}
+			if body.Amount == nil && card.Balance < amount {
+				amount = card.Balance
+			}
+			if amount <= 0 {
+				return requestError{http.StatusBadRequest, "Nothing to pay"}
+			}
+
+			invoiceId := invoice.InvoiceId
+			transaction, err := moveGiftCardBalance(sessCtx, card, models.GiftCardTransaction{
+				Type:      "REDEEM",
+				Amount:    -amount,
+				InvoiceID: &invoiceId,
+				UserID:    ctx.GetString("uid"),
+			})
+			if err != nil {
+				return err
+			}
+
+			payment = models.InvoicePayment{
+				Method:        "GIFT_CARD",
+				Amount:        amount,
+				GiftCardID:    &card.GiftCardID,
+				TransactionID: transaction.TransactionID,
+			}
+			payment.PaidAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			due = toFixed(due-amount, 2)
+
+			set := bson.D{{Key: "updated_at", Value: payment.PaidAt}}
+			if due == 0 {
+				set = append(set, bson.E{Key: "payment_status", Value: "PAID"})
+				if invoice.PaymentMethod == nil || *invoice.PaymentMethod == "" {
+					set = append(set, bson.E{Key: "payment_method", Value: "GIFT_CARD"})
+				}
+			}
+			tables, err := orderTables(sessCtx, invoice.OrderId)
+			if err != nil {
+				return err
+			}
+			return updateTableStatus(sessCtx, tables, func() error {
+				_, err := invoiceCollection.UpdateOne(sessCtx, bson.M{"invoice_id": invoiceId}, bson.D{
+					{Key: "$push", Value: bson.D{{Key: "payments", Value: payment}}},
+					{Key: "$set", Value: set},
+				})
+				if err != nil {
+					return err
+				}
+
+				if due == 0 {
+					paid := "PAID"
+					invoice.PaymentStatus = &paid
+					invoice.Payments = append(invoice.Payments, payment)
+					return applyInvoiceStatus(sessCtx, invoice, "PENDING")
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Gift card payment failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"payment": payment, "amount_due": due})
+	}
+}
+
+// GetGiftCardReport shows the outstanding balances, which the restaurant
+// owes as a liability, and the card activity in the range
+func GetGiftCardReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+
+		cursor, err := giftCardCollection.Aggregate(curCtx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"status": "ACTIVE", "balance": bson.M{"$gt": 0}}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "cards", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "outstanding", Value: bson.D{{Key: "$sum", Value: "$balance"}}},
+				{Key: "expired", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+					bson.D{{Key: "$and", Value: bson.A{
+						bson.D{{Key: "$gt", Value: bson.A{"$expires_at", nil}}},
+						bson.D{{Key: "$lt", Value: bson.A{"$expires_at", time.Now()}}},
+					}}}, "$balance", 0,
+				}}}}}},
+			}}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift cards"})
+			return
+		}
+		var liability []struct {
+			Cards       int     `bson:"cards"`
+			Outstanding float64 `bson:"outstanding"`
+			Expired     float64 `bson:"expired"`
+		}
+		if err = cursor.All(curCtx, &liability); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift cards"})
+			return
+		}
+
+		cursor, err = giftCardTransactionCollection.Aggregate(curCtx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$type"},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			}}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift card transactions"})
+			return
+		}
+		var activity []struct {
+			Type   string  `bson:"_id"`
+			Count  int     `bson:"count"`
+			Amount float64 `bson:"amount"`
+		}
+		if err = cursor.All(curCtx, &activity); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching gift card transactions"})
+			return
+		}
+
+		report := gin.H{"cards": 0, "outstanding": 0.0, "expired_outstanding": 0.0}
+		if len(liability) > 0 {
+			report["cards"] = liability[0].Cards
+			report["outstanding"] = toFixed(liability[0].Outstanding, 2)
+			report["expired_outstanding"] = toFixed(liability[0].Expired, 2)
+		}
+		movements := gin.H{}
+		for _, kind := range []string{"ISSUE", "TOP_UP", "REDEEM", "REFUND", "VOID"} {
+			movements[kind] = gin.H{"count": 0, "amount": 0.0}
+		}
+		for _, row := range activity {
+			movements[row.Type] = gin.H{"count": row.Count, "amount": toFixed(row.Amount, 2)}
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"from":      dayKey(from),
+			"to":        dayKey(to.AddDate(0, 0, -1)),
+			"liability": report,
+			"activity":  movements,
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"infinity/rms/models"
+)
+
+func giftCardPayment(cardId string, amount float64) models.InvoicePayment {
+	return models.InvoicePayment{Method: "GIFT_CARD", Amount: amount, GiftCardID: &cardId}
+}
+
+func TestGiftCardRefundRunsOnce(t *testing.T) {
+	payments := []models.InvoicePayment{
+		giftCardPayment("card-a", 10),
+		giftCardPayment("card-a", 5),
+		giftCardPayment("card-b", 7),
+	}
+
+	// the first refund puts every payment back
+	pending := unrefundedPayments(payments, nil)
+	if len(pending) != 3 {
+		t.Fatalf("first refund covers %d payments, want 3", len(pending))
+	}
+	refunds := []models.GiftCardTransaction{}
+	for _, payment := range pending {
+		refunds = append(refunds, models.GiftCardTransaction{GiftCardID: *payment.GiftCardID, Type: "REFUND", Amount: payment.Amount})
+	}
+
+	// paying the refunded invoice again is refused
+	var reqErr requestError
+	if err := checkPaymentStatusChange("REFUNDED", "PAID"); !errors.As(err, &reqErr) || reqErr.status != http.StatusConflict {
+		t.Fatalf("re-pay after refund: got %v, want a 409", err)
+	}
+
+	// and refunding it again puts nothing back
+	if pending := unrefundedPayments(payments, refunds); len(pending) != 0 {
+		t.Fatalf("second refund covers %v, want nothing", pending)
+	}
+}
+
+func TestPaymentStatusChanges(t *testing.T) {
+	cases := []struct {
+		previous, status string
+		allowed          bool
+	}{
+		{"", "PENDING", true},
+		{"PENDING", "PAID", true},
+		{"PAID", "REFUNDED", true},
+		{"REFUNDED", "REFUNDED", true},
+		{"REFUNDED", "PAID", false},
+		{"REFUNDED", "PENDING", false},
+	}
+	for _, c := range cases {
+		err := checkPaymentStatusChange(c.previous, c.status)
+		if (err == nil) != c.allowed {
+			t.Errorf("%q to %q: got %v", c.previous, c.status, err)
+		}
+	}
+}
+
+func TestUnrefundedPaymentsSkipsOtherTenders(t *testing.T) {
+	payments := []models.InvoicePayment{{Method: "GIFT_CARD", Amount: 3}, giftCardPayment("card-a", 4)}
+	pending := unrefundedPayments(payments, []models.GiftCardTransaction{{GiftCardID: "card-b", Type: "REFUND"}})
+	if len(pending) != 1 || *pending[0].GiftCardID != "card-a" {
+		t.Fatalf("got %v, want only the card-a payment", pending)
+	}
+}
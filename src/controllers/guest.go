@@ -0,0 +1,482 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"infinity/rms/database"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"infinity/rms/qrcode"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GuestOrderItem struct {
+	FoodID   string `json:"food_id" validate:"required"`
+	Quantity string `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
+}
+
+type GuestOrderRequest struct {
+	Allergies  []string         `json:"allergies"`
+	OrderItems []GuestOrderItem `json:"order_items" validate:"required,min=1,max=50,dive"`
+}
+
+func guestSessionTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("GUEST_SESSION_TTL"))
+	if err != nil || ttl <= 0 {
+		return 3 * time.Hour
+	}
+	return ttl
+}
+
+// GUEST_ORDER_CONFIRMATION=false sends guest orders straight to the kitchen
+func guestOrdersNeedConfirmation() bool {
+	return !strings.EqualFold(os.Getenv("GUEST_ORDER_CONFIRMATION"), "false")
+}
+
+func guestOrderURL(token string) string {
+	base := os.Getenv("GUEST_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8000"
+	}
+	return strings.TrimRight(base, "/") + "/guest?t=" + token
+}
+
+func GetTableQRCode() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var table models.Table
+		err := tableCollection.FindOne(curCtx, bson.M{"table_id": ctx.Param("table_id")}).Decode(&table)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Table was not found"})
+			return
+		}
+
+		url := guestOrderURL(helpers.TableQRToken(table.TableID, table.QRVersion))
+		code, err := qrcode.Encode([]byte(url))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "QR code could not be generated"})
+			return
+		}
+
+		switch ctx.DefaultQuery("format", "png") {
+		case "svg":
+			ctx.Data(http.StatusOK, "image/svg+xml", code.SVG(8))
+		case "json":
+			ctx.JSON(http.StatusOK, gin.H{"table_id": table.TableID, "url": url})
+		default:
+			data, err := code.PNG(8)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "QR code could not be generated"})
+				return
+			}
+			ctx.Data(http.StatusOK, "image/png", data)
+		}
+	}
+}
+
+// RotateTableQRCode invalidates the printed QR code of a table
+func RotateTableQRCode() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := tableCollection.UpdateOne(curCtx,
+			bson.M{"table_id": ctx.Param("table_id")},
+			bson.D{
+				{Key: "$inc", Value: bson.D{{Key: "qr_version", Value: 1}}},
+				{Key: "$set", Value: bson.D{{Key: "updated_at", Value: updatedAt}}},
+			},
+		)
+		if err != nil || result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Table was not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func CreateGuestSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			QRToken string `json:"qr_token" validate:"required"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tableId, version, err := helpers.ParseTableQRToken(body.QRToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		var table models.Table
+		err = tableCollection.FindOne(curCtx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil || table.QRVersion != version {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "The QR code is invalid"})
+			return
+		}
+
+		ttl := guestSessionTTL()
+		token, err := helpers.GenerateGuestToken(table.TableID, table.QRVersion, ttl)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Guest session could not be created"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"guest_token":  token,
+			"table_id":     table.TableID,
+			"table_number": table.TableNumber,
+			"expires_at":   time.Now().Add(ttl).Format(time.RFC3339),
+		})
+	}
+}
+
+func menuIsActive(menu models.Menu, at time.Time) bool {
+	if menu.StartDate != nil && at.Before(*menu.StartDate) {
+		return false
+	}
+	if menu.EndDate != nil && at.After(*menu.EndDate) {
+		return false
+	}
+	return true
+}
+
+func GetGuestMenu() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		locales := requestLocales(ctx)
+
+		cursor, err := menuCollection.Find(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the menu"})
+			return
+		}
+		var menus []models.Menu
+		if err = cursor.All(curCtx, &menus); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the menu"})
+			return
+		}
+
+		filter := bson.M{}
+		if exclude := splitTags(ctx.Query("exclude_allergens")); len(exclude) > 0 {
+			filter["allergens"] = bson.M{"$nin": exclude}
+		}
+		if dietary := splitTags(ctx.Query("dietary")); len(dietary) > 0 {
+			filter["dietary_tags"] = bson.M{"$all": dietary}
+		}
+
+		result := []gin.H{}
+		now := time.Now()
+		for _, menu := range menus {
+			if !menuIsActive(menu, now) {
+				continue
+			}
+			filter["menu_id"] = menu.MenuId
+			cursor, err := foodCollection.Find(curCtx, filter)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the menu"})
+				return
+			}
+			var foods []models.Food
+			if err = cursor.All(curCtx, &foods); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the menu"})
+				return
+			}
+
+			items := []gin.H{}
+			for _, food := range foods {
+				// guests can't order what has no price
+				if food.Price == nil {
+					continue
+				}
+				name := ""
+				if food.Name != nil {
+					name = helpers.Localize(food.NameTranslations, *food.Name, locales)
+				}
+				items = append(items, gin.H{
+					"food_id":      food.FoodId,
+					"name":         name,
+					"price":        food.Price,
+					"food_image":   food.FoodImage,
+					"allergens":    food.Allergens,
+					"dietary_tags": food.DietaryTags,
+				})
+			}
+			result = append(result, gin.H{
+				"menu_id":  menu.MenuId,
+				"name":     helpers.Localize(menu.NameTranslations, menu.Name, locales),
+				"category": helpers.Localize(menu.CategoryTranslations, menu.Category, locales),
+				"foods":    items,
+			})
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// openOrderForTable returns the table's latest order unless it has
+// already been paid, or nil when the table has no open order
+func openOrderForTable(curCtx context.Context, tableId string) (*models.Order, error) {
+	var order models.Order
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	filter := bson.M{"table_id": tableId, "merged_into": bson.M{"$exists": false}}
+	err := orderCollection.FindOne(curCtx, filter, opts).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	paid, err := invoiceCollection.CountDocuments(curCtx, bson.M{"order_id": order.OrderID, "payment_status": "PAID"})
+	if err != nil {
+		return nil, err
+	}
+	if paid > 0 {
+		return nil, nil
+	}
+	return &order, nil
+}
+
+func CreateGuestOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tableId := ctx.GetString("guest_table_id")
+
+		var request GuestOrderRequest
+		if err := ctx.BindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(request); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		// guests never set prices, every item is charged at the menu price,
+		// and they can only order what the guest menu shows right now
+		orderItems := []models.OrderItem{}
+		activeMenus := map[string]bool{}
+		now := time.Now()
+		for _, item := range request.OrderItems {
+			var food models.Food
+			err := foodCollection.FindOne(curCtx, bson.M{"food_id": item.FoodID}).Decode(&food)
+			if err != nil || food.Price == nil || food.MenuId == nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Food was not found"})
+				return
+			}
+			active, checked := activeMenus[*food.MenuId]
+			if !checked {
+				var menu models.Menu
+				err := menuCollection.FindOne(curCtx, bson.M{"menu_id": food.MenuId}).Decode(&menu)
+				active = err == nil && menuIsActive(menu, now)
+				activeMenus[*food.MenuId] = active
+			}
+			if !active {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Food is not on the menu right now"})
+				return
+			}
+			foodId, quantity, price := food.FoodId, item.Quantity, *food.Price
+			orderItems = append(orderItems, models.OrderItem{
+				FoodID:    &foodId,
+				Quantity:  &quantity,
+				UnitPrice: &price,
+			})
+		}
+
+		allergies := normalizeTags(request.Allergies)
+		warnings, err := allergenWarnings(curCtx, orderItems, allergies)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while checking allergens"})
+			return
+		}
+		if len(warnings) > 0 && allergenPolicyBlocks() {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":             "Order items conflict with declared allergies",
+				"allergen_warnings": warnings,
+			})
+			return
+		}
+
+		order, err := openOrderForTable(curCtx, tableId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+			return
+		}
+		var newOrder models.Order
+		courseOrder := models.Order{}
+		if order == nil {
+			newOrder.TableID = &tableId
+			newOrder.Allergies = allergies
+			newOrder.OrderDate, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			var table models.Table
+			if err := tableCollection.FindOne(curCtx, bson.M{"table_id": tableId}).Decode(&table); err == nil {
+				newOrder.ServerID = serverForTable(curCtx, table, newOrder.OrderDate)
+				newOrder.Covers = table.NumberOfGuests
+			}
+		} else {
+			courseOrder = *order
+		}
+
+		status := "CONFIRMED"
+		if guestOrdersNeedConfirmation() {
+			status = "PENDING_CONFIRMATION"
+		}
+		for i := range orderItems {
+			orderItems[i].Status = status
+		}
+
+		if err := prepareKitchenItems(curCtx, courseOrder, orderItems); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+			return
+		}
+
+		// the order, its allergies and the items are written together
+		var orderId string
+		orderItemsToBeInserted := []interface{}{}
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, []string{tableId}, func() error {
+				if order == nil {
+					var err error
+					if orderId, err = OrderItemOrderCreator(sessCtx, newOrder); err != nil {
+						return err
+					}
+				} else {
+					orderId = order.OrderID
+					if len(allergies) > 0 {
+						_, err := orderCollection.UpdateOne(sessCtx, bson.M{"order_id": orderId}, bson.D{
+							{Key: "$addToSet", Value: bson.D{{Key: "allergies", Value: bson.D{{Key: "$each", Value: allergies}}}}},
+						})
+						if err != nil {
+							return err
+						}
+					}
+				}
+
+				orderItemsToBeInserted = []interface{}{}
+				for _, orderItem := range orderItems {
+					orderItem.ID = primitive.NewObjectID()
+					orderItem.OrderItemID = orderItem.ID.Hex()
+					orderItem.OrderID = orderId
+					orderItem.Source = "GUEST"
+					orderItem.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+					orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+					orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+				}
+				if _, err := orderItemCollection.InsertMany(sessCtx, orderItemsToBeInserted); err != nil {
+					return err
+				}
+				return publishItemsAdded(sessCtx, orderItemsToBeInserted)
+			})
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order items"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"order_id":          orderId,
+			"status":            status,
+			"order_items":       orderItemsToBeInserted,
+			"allergen_warnings": warnings,
+		})
+	}
+}
+
+func GetGuestOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		order, err := openOrderForTable(curCtx, ctx.GetString("guest_table_id"))
+		if err != nil || order == nil {
+			ctx.JSON(http.StatusOK, gin.H{"order_id": nil, "order_items": []models.OrderItem{}})
+			return
+		}
+
+		lines, total, err := receiptLines(curCtx, order.OrderID, requestLocales(ctx))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"order_id":    order.OrderID,
+			"order_items": lines,
+			"total":       total,
+		})
+	}
+}
+
+// ConfirmGuestItems is the waiter accepting what guests ordered. The
+// items go to the kitchen from now on, following the order's courses the
+// same way items the waiter enters do.
+func ConfirmGuestItems() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result := &mongo.UpdateResult{}
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			order, err := findOrder(sessCtx, ctx.Param("order_id"))
+			if err != nil {
+				return err
+			}
+			cursor, err := orderItemCollection.Find(sessCtx, bson.M{"order_id": order.OrderID, "status": "PENDING_CONFIRMATION"})
+			if err != nil {
+				return err
+			}
+			var items []models.OrderItem
+			if err = cursor.All(sessCtx, &items); err != nil {
+				return err
+			}
+			for i := range items {
+				items[i].Status = "CONFIRMED"
+			}
+			if err := fireNewItems(sessCtx, order, items); err != nil {
+				return err
+			}
+
+			updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			for _, item := range items {
+				updated, err := orderItemCollection.UpdateOne(sessCtx,
+					bson.M{"order_item_id": item.OrderItemID, "status": "PENDING_CONFIRMATION"},
+					bson.D{{Key: "$set", Value: bson.D{
+						{Key: "status", Value: item.Status},
+						{Key: "fired_at", Value: item.FiredAt},
+						{Key: "updated_at", Value: updatedAt},
+					}}},
+				)
+				if err != nil {
+					return err
+				}
+				result.MatchedCount += updated.MatchedCount
+				result.ModifiedCount += updated.ModifiedCount
+			}
+			return nil
+		})
+		if err != nil {
+			respondError(ctx, err, "Order Item updation failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"infinity/rms/storage"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var blobStore storage.BlobStore = storage.NewFromEnv()
+
+func maxImageBytes() int64 {
+	limit, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_BYTES"), 10, 64)
+	if err != nil || limit < 1 {
+		return 5 << 20
+	}
+	return limit
+}
+
+func UploadFoodImage() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		foodId := ctx.Param("food_id")
+
+		var food models.Food
+		err := foodCollection.FindOne(curCtx, bson.M{"food_id": foodId}).Decode(&food)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Food was not found"})
+			return
+		}
+
+		urls, ok := receiveImage(ctx, curCtx, "foods/"+foodId)
+		if !ok {
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		_, err = foodCollection.UpdateOne(curCtx, bson.M{"food_id": foodId}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "food_image", Value: urls["original"]},
+				{Key: "updated_at", Value: updatedAt},
+			}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Food update failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"food_image": urls["original"], "images": urls})
+	}
+}
+
+func UploadAvatar() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := ctx.Param("user_id")
+
+		count, err := userCollection.CountDocuments(curCtx, bson.M{"user_id": userId})
+		if err != nil || count == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User was not found"})
+			return
+		}
+
+		urls, ok := receiveImage(ctx, curCtx, "avatars/"+userId)
+		if !ok {
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		_, err = userCollection.UpdateOne(curCtx, bson.M{"user_id": userId}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "avatar", Value: urls["original"]},
+				{Key: "updated_at", Value: updatedAt},
+			}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "User update failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"avatar": urls["original"], "images": urls})
+	}
+}
+
+// receiveImage validates the multipart "image" field, stores the original
+// and its thumbnails under prefix and returns their public urls. It writes
+// the error response itself and reports false when the upload is rejected.
+func receiveImage(ctx *gin.Context, curCtx context.Context, prefix string) (map[string]string, bool) {
+	limit := maxImageBytes()
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit+1<<20)
+
+	fileHeader, err := ctx.FormFile("image")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The image field is required"})
+		return nil, false
+	}
+	if fileHeader.Size > limit {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Image is larger than %d bytes", limit),
+		})
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Image could not be read"})
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil || int64(len(data)) > limit {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Image is larger than %d bytes", limit),
+		})
+		return nil, false
+	}
+
+	processed, err := helpers.ProcessImage(data)
+	if err != nil {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// keys are content addressed so served images can be cached forever
+	sum := sha256.Sum256(data)
+	base := prefix + "/" + hex.EncodeToString(sum[:8])
+
+	urls := map[string]string{}
+	key := base + "/original" + processed.Extension
+	if err := blobStore.Put(curCtx, key, bytes.NewReader(processed.Original), processed.ContentType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Image could not be stored"})
+		return nil, false
+	}
+	urls["original"] = "/images/" + key
+
+	for width, thumb := range processed.Thumbnails {
+		key := fmt.Sprintf("%s/%d%s", base, width, processed.Extension)
+		if err := blobStore.Put(curCtx, key, bytes.NewReader(thumb), processed.ContentType); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Image could not be stored"})
+			return nil, false
+		}
+		urls[strconv.Itoa(width)] = "/images/" + key
+	}
+	return urls, true
+}
+
+func ServeImage() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+		body, info, err := blobStore.Get(curCtx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Image was not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Image could not be read"})
+			return
+		}
+		defer body.Close()
+
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+		if info.ETag != "" {
+			ctx.Header("ETag", info.ETag)
+			if ctx.GetHeader("If-None-Match") == info.ETag {
+				ctx.Status(http.StatusNotModified)
+				return
+			}
+		}
+		if !info.LastModified.IsZero() {
+			ctx.Header("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+		}
+
+		contentType := info.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		ctx.DataFromReader(http.StatusOK, info.Size, contentType, body, nil)
+	}
+}
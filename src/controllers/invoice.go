@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"infinity/rms/database"
+	"infinity/rms/events"
 	"infinity/rms/models"
 	"log"
 	"net/http"
@@ -122,17 +123,34 @@ func CreateInvoice() gin.HandlerFunc {
 		}
 
 		// setting up the values
+		if invoice.CustomerID == nil {
+			invoice.CustomerID = order.CustomerID
+		}
 		status := "PENDING"
 		if invoice.PaymentStatus == nil {
 			invoice.PaymentStatus = &status
 		}
+		// discounts and part payments are only added through redemptions
+		invoice.Discounts = nil
+		invoice.Payments = nil
+		invoice.PaidAt = nil
 		invoice.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.PaymentDueData, _ = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
 		invoice.ID = primitive.NewObjectID()
 		invoice.InvoiceId = invoice.ID.Hex()
 
-		result, insertErr := invoiceCollection.InsertOne(curCtx, invoice)
+		var result *mongo.InsertOneResult
+		insertErr := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, tableIdsOf(order.TableID), func() error {
+				var err error
+				result, err = invoiceCollection.InsertOne(sessCtx, invoice)
+				if err != nil {
+					return err
+				}
+				return applyInvoiceStatus(sessCtx, invoice, "")
+			})
+		})
 		if insertErr != nil {
 			msg := fmt.Sprintf("Failed to create an order")
 			ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -157,6 +175,22 @@ func UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
+		fields := []string{}
+		if invoice.PaymentMethod != nil {
+			fields = append(fields, "PaymentMethod")
+		}
+		if invoice.PaymentStatus != nil {
+			fields = append(fields, "PaymentStatus")
+		}
+		if len(fields) > 0 {
+			if validationErr := validate.StructPartial(invoice, fields...); validationErr != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": validationErr.Error(),
+				})
+				return
+			}
+		}
+
 		var updatedObj primitive.D
 
 		invoiceId := ctx.Param("invoice_id")
@@ -168,6 +202,15 @@ func UpdateInvoice() gin.HandlerFunc {
 		if invoice.PaymentStatus != nil {
 			updatedObj = append(updatedObj, bson.E{Key: "payment_status", Value: invoice.PaymentStatus})
 		}
+		if invoice.Tip != nil {
+			if *invoice.Tip < 0 {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": "tip can not be negative",
+				})
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "tip", Value: toFixed(*invoice.Tip, 2)})
+		}
 
 		invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: invoice.UpdatedAt})
@@ -177,27 +220,82 @@ func UpdateInvoice() gin.HandlerFunc {
 			Upsert: &upsert,
 		}
 
-		status := "PENDING"
+		update := bson.D{{Key: "$set", Value: updatedObj}}
+
+		// only upserted invoices default to PENDING, a tip added after
+		// payment must not reopen the invoice
 		if invoice.PaymentStatus == nil {
-			invoice.PaymentStatus = &status
-			updatedObj = append(updatedObj, bson.E{Key: "payment_status", Value: invoice.PaymentStatus})
+			update = append(update, bson.E{Key: "$setOnInsert", Value: bson.D{{Key: "payment_status", Value: "PENDING"}}})
 		}
 
-		result, err := menuCollection.UpdateOne(
-			curCtx, filter, bson.D{
-				{Key: "$set", Value: updatedObj},
-			},
-			&opt,
-		)
+		var result *mongo.UpdateResult
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			previousStatus := ""
+			var previous models.Invoice
+			if err := invoiceCollection.FindOne(sessCtx, filter).Decode(&previous); err == nil && previous.PaymentStatus != nil {
+				previousStatus = *previous.PaymentStatus
+			}
+			if invoice.PaymentStatus != nil {
+				if err := checkPaymentStatusChange(previousStatus, *invoice.PaymentStatus); err != nil {
+					return err
+				}
+			}
+			tables, err := orderTables(sessCtx, previous.OrderId)
+			if err != nil {
+				return err
+			}
+
+			return updateTableStatus(sessCtx, tables, func() error {
+				var err error
+				result, err = invoiceCollection.UpdateOne(sessCtx, filter, update, &opt)
+				if err != nil {
+					return err
+				}
+
+				var updated models.Invoice
+				if err := invoiceCollection.FindOne(sessCtx, filter).Decode(&updated); err != nil {
+					return err
+				}
+				return applyInvoiceStatus(sessCtx, updated, previousStatus)
+			})
+		})
 
 		if err != nil {
-			msg := "Invoice updation failed"
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"error": msg,
-			})
+			respondError(ctx, err, "Invoice updation failed")
 			return
 		}
 
 		ctx.JSON(http.StatusOK, result)
 	}
 }
+
+// checkPaymentStatusChange keeps a refunded invoice refunded. The money
+// has gone back, so charging the guest again needs a new invoice.
+func checkPaymentStatusChange(previousStatus string, status string) error {
+	if previousStatus == "REFUNDED" && status != "REFUNDED" {
+		return requestError{http.StatusConflict, "A refunded invoice can't change status"}
+	}
+	return nil
+}
+
+// applyInvoiceStatus runs the side effects of an invoice changing status
+func applyInvoiceStatus(curCtx context.Context, invoice models.Invoice, previousStatus string) error {
+	if err := applyLoyalty(curCtx, invoice, previousStatus); err != nil {
+		return err
+	}
+	if invoice.PaymentStatus != nil && *invoice.PaymentStatus == "PAID" && previousStatus != "PAID" {
+		paidAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		_, err := invoiceCollection.UpdateOne(curCtx, bson.M{"invoice_id": invoice.InvoiceId}, bson.M{"$set": bson.M{"paid_at": paidAt}})
+		if err != nil {
+			return err
+		}
+		invoice.PaidAt = &paidAt
+		if err := publish(curCtx, events.InvoicePaid, invoice.InvoiceId, invoice); err != nil {
+			return err
+		}
+	}
+	if invoice.PaymentStatus != nil && *invoice.PaymentStatus == "REFUNDED" && previousStatus != "REFUNDED" {
+		return refundGiftCardPayments(curCtx, invoice)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/models"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type KitchenTicketItem struct {
+	OrderItemID string   `json:"order_item_id"`
+	FoodID      string   `json:"food_id"`
+	FoodName    string   `json:"food_name"`
+	Quantity    string   `json:"quantity"`
+	Allergens   []string `json:"allergens"`
+	Conflicts   []string `json:"conflicts"`
+	Notes       string   `json:"notes,omitempty"`
+	Course      string   `json:"course,omitempty"`
+}
+
+type KitchenTicket struct {
+	OrderID      string              `json:"order_id"`
+	TableNumber  *int                `json:"table_number"`
+	OrderType    string              `json:"order_type,omitempty"`
+	PickupTime   *time.Time          `json:"pickup_time,omitempty"`
+	Notes        string              `json:"notes,omitempty"`
+	Waiting      []string            `json:"waiting_courses,omitempty"`
+	OrderDate    time.Time           `json:"order_date"`
+	AllergyAlert string              `json:"allergy_alert,omitempty"`
+	Allergies    []string            `json:"allergies"`
+	Items        []KitchenTicketItem `json:"items"`
+}
+
+func GetKitchenTicket() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		ticket, err := buildKitchenTicket(curCtx, ctx.Param("order_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		// printers take the plain text layout
+		if ctx.Query("format") == "text" {
+			ctx.String(http.StatusOK, ticket.Text())
+			return
+		}
+		ctx.JSON(http.StatusOK, ticket)
+	}
+}
+
+func buildKitchenTicket(curCtx context.Context, orderId string) (KitchenTicket, error) {
+	var ticket KitchenTicket
+	var order models.Order
+
+	err := orderCollection.FindOne(curCtx, bson.M{"order_id": orderId}).Decode(&order)
+	if err != nil {
+		return ticket, fmt.Errorf("Order was not found")
+	}
+	if order.ScheduledFor != nil && order.ReleasedAt == nil {
+		return ticket, fmt.Errorf("Order is scheduled for %s and not released to the kitchen yet", order.ScheduledFor.In(time.Local).Format("2006-01-02 15:04"))
+	}
+
+	ticket.OrderID = order.OrderID
+	ticket.OrderDate = order.OrderDate
+	if order.Type != "" && order.Type != "DINE_IN" {
+		ticket.OrderType = order.Type
+		ticket.PickupTime = order.PickupTime
+	}
+	if order.Notes != nil {
+		ticket.Notes = *order.Notes
+	}
+	ticket.Allergies = order.Allergies
+	if len(order.Allergies) > 0 {
+		ticket.AllergyAlert = "ALLERGY: " + strings.Join(order.Allergies, ", ")
+	}
+
+	if order.TableID != nil {
+		var table models.Table
+		if err := tableCollection.FindOne(curCtx, bson.M{"table_id": order.TableID}).Decode(&table); err == nil {
+			ticket.TableNumber = table.TableNumber
+		}
+	}
+
+	result, err := orderItemCollection.Find(curCtx, bson.M{"order_id": orderId})
+	if err != nil {
+		return ticket, fmt.Errorf("Error occured while fetching order items")
+	}
+	var orderItems []models.OrderItem
+	if err = result.All(curCtx, &orderItems); err != nil {
+		return ticket, fmt.Errorf("Error occured while fetching order items")
+	}
+
+	foods := map[string]models.Food{}
+	for _, orderItem := range orderItems {
+		if orderItem.FoodID == nil {
+			continue
+		}
+		if _, ok := foods[*orderItem.FoodID]; ok {
+			continue
+		}
+		var food models.Food
+		if err := foodCollection.FindOne(curCtx, bson.M{"food_id": orderItem.FoodID}).Decode(&food); err == nil {
+			foods[*orderItem.FoodID] = food
+		}
+	}
+
+	ticket.Items = []KitchenTicketItem{}
+	for _, orderItem := range orderItems {
+		// guest orders wait for a waiter before the kitchen sees them,
+		// voided items are gone
+		if orderItem.Status == "PENDING_CONFIRMATION" || orderItem.Status == "VOIDED" || orderItem.BumpedAt != nil {
+			continue
+		}
+		// held courses are only listed until the waiter fires them
+		if !itemFired(orderItem) {
+			if !contains(ticket.Waiting, orderItem.Course) {
+				ticket.Waiting = append(ticket.Waiting, orderItem.Course)
+			}
+			continue
+		}
+		item := KitchenTicketItem{OrderItemID: orderItem.OrderItemID, Notes: orderItem.Notes, Course: orderItem.Course}
+		if orderItem.Quantity != nil {
+			item.Quantity = *orderItem.Quantity
+		}
+		if orderItem.FoodID != nil {
+			food := foods[*orderItem.FoodID]
+			item.FoodID = *orderItem.FoodID
+			if food.Name != nil {
+				item.FoodName = *food.Name
+			}
+			item.Allergens = food.Allergens
+			item.Conflicts = allergenConflicts(food, order.Allergies)
+		}
+		ticket.Items = append(ticket.Items, item)
+	}
+	sort.SliceStable(ticket.Items, func(i, j int) bool {
+		return courseRank(ticket.Items[i].Course) < courseRank(ticket.Items[j].Course)
+	})
+	return ticket, nil
+}
+
+func (ticket KitchenTicket) Text() string {
+	var b strings.Builder
+
+	if ticket.AllergyAlert != "" {
+		banner := strings.Repeat("*", len(ticket.AllergyAlert)+8)
+		fmt.Fprintf(&b, "%s\n*** %s ***\n%s\n", banner, ticket.AllergyAlert, banner)
+	}
+	fmt.Fprintf(&b, "ORDER %s\n", ticket.OrderID)
+	if ticket.TableNumber != nil {
+		fmt.Fprintf(&b, "TABLE %d\n", *ticket.TableNumber)
+	}
+	if ticket.OrderType != "" {
+		fmt.Fprintf(&b, "%s\n", ticket.OrderType)
+	}
+	if ticket.PickupTime != nil {
+		fmt.Fprintf(&b, "PICKUP %s\n", ticket.PickupTime.Format("15:04"))
+	}
+	if ticket.Notes != "" {
+		fmt.Fprintf(&b, "NOTE %s\n", ticket.Notes)
+	}
+	fmt.Fprintf(&b, "%s\n\n", ticket.OrderDate.Format("15:04"))
+
+	course := ""
+	for _, item := range ticket.Items {
+		if item.Course != course {
+			course = item.Course
+			fmt.Fprintf(&b, "-- %s --\n", course)
+		}
+		fmt.Fprintf(&b, "%-2s %s\n", item.Quantity, item.FoodName)
+		if len(item.Conflicts) > 0 {
+			fmt.Fprintf(&b, "   !! CONTAINS %s !!\n", strings.Join(item.Conflicts, ", "))
+		}
+		if item.Notes != "" {
+			fmt.Fprintf(&b, "   > %s\n", item.Notes)
+		}
+	}
+	if len(ticket.Waiting) > 0 {
+		fmt.Fprintf(&b, "\nWAITING %s\n", strings.Join(ticket.Waiting, ", "))
+	}
+	return b.String()
+}
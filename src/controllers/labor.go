@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/models"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type LaborDay struct {
+	Date             string   `json:"date"`
+	ScheduledHours   float64  `json:"scheduled_hours"`
+	WorkedHours      float64  `json:"worked_hours"`
+	OvertimeHours    float64  `json:"overtime_hours"`
+	LaborCost        float64  `json:"labor_cost"`
+	Sales            float64  `json:"sales"`
+	LaborCostPercent *float64 `json:"labor_cost_percent"`
+}
+
+type StaffLabor struct {
+	StaffID        string  `json:"staff_id"`
+	Name           string  `json:"name"`
+	Role           string  `json:"role"`
+	ScheduledHours float64 `json:"scheduled_hours"`
+	WorkedHours    float64 `json:"worked_hours"`
+	OvertimeHours  float64 `json:"overtime_hours"`
+	LaborCost      float64 `json:"labor_cost"`
+}
+
+type paidInvoice struct {
+	Invoice models.Invoice
+	Total   float64
+}
+
+// overtimeRules returns the weekly hours after which overtime is paid and
+// the overtime pay multiplier, from OVERTIME_WEEKLY_HOURS and
+// OVERTIME_MULTIPLIER
+func overtimeRules() (float64, float64) {
+	threshold, err := strconv.ParseFloat(os.Getenv("OVERTIME_WEEKLY_HOURS"), 64)
+	if err != nil || threshold <= 0 {
+		threshold = 40
+	}
+	multiplier, err := strconv.ParseFloat(os.Getenv("OVERTIME_MULTIPLIER"), 64)
+	if err != nil || multiplier < 1 {
+		multiplier = 1.5
+	}
+	return threshold, multiplier
+}
+
+func weekStart(t time.Time) time.Time {
+	t = t.In(time.Local)
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+func dayKey(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+func percentOf(part float64, whole float64) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	value := toFixed(part/whole*100, 2)
+	return &value
+}
+
+// paidInvoices returns the invoices paid in the range with the total of
+// their orders, net of discounts
+func paidInvoices(curCtx context.Context, from time.Time, to time.Time) ([]paidInvoice, error) {
+	cursor, err := invoiceCollection.Find(curCtx, bson.M{
+		"payment_status": "PAID",
+		"paid_at":        bson.M{"$gte": from, "$lt": to},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var invoices []models.Invoice
+	if err = cursor.All(curCtx, &invoices); err != nil {
+		return nil, err
+	}
+
+	orderIds := []string{}
+	for _, invoice := range invoices {
+		orderIds = append(orderIds, invoice.OrderId)
+	}
+	totals, err := orderTotals(curCtx, orderIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []paidInvoice{}
+	for _, invoice := range invoices {
+		result = append(result, paidInvoice{Invoice: invoice, Total: totals[invoice.OrderId] - discountTotal(invoice)})
+	}
+	return result, nil
+}
+
+func GetLaborReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+
+		days := map[string]*LaborDay{}
+		dayList := []*LaborDay{}
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			laborDay := &LaborDay{Date: dayKey(day)}
+			days[laborDay.Date] = laborDay
+			dayList = append(dayList, laborDay)
+		}
+
+		cursor, err := staffCollection.Find(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching staff"})
+			return
+		}
+		var allStaff []models.Staff
+		if err = cursor.All(curCtx, &allStaff); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching staff"})
+			return
+		}
+		staffLabor := map[string]*StaffLabor{}
+		staffRates := map[string]float64{}
+		for _, staff := range allStaff {
+			labor := &StaffLabor{StaffID: staff.StaffID}
+			if staff.FirstName != nil && staff.LastName != nil {
+				labor.Name = *staff.FirstName + " " + *staff.LastName
+			}
+			if staff.Role != nil {
+				labor.Role = *staff.Role
+			}
+			if staff.HourlyRate != nil {
+				staffRates[staff.StaffID] = *staff.HourlyRate
+			}
+			staffLabor[staff.StaffID] = labor
+		}
+		laborFor := func(staffId string) *StaffLabor {
+			if labor, ok := staffLabor[staffId]; ok {
+				return labor
+			}
+			labor := &StaffLabor{StaffID: staffId}
+			staffLabor[staffId] = labor
+			return labor
+		}
+
+		cursor, err = shiftCollection.Find(curCtx, bson.M{"start_time": bson.M{"$gte": from, "$lt": to}})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching shifts"})
+			return
+		}
+		var shifts []models.Shift
+		if err = cursor.All(curCtx, &shifts); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching shifts"})
+			return
+		}
+		for _, shift := range shifts {
+			if shift.StaffID == nil || shift.StartTime == nil || shift.EndTime == nil {
+				continue
+			}
+			hours := shift.EndTime.Sub(*shift.StartTime).Hours()
+			if day, ok := days[dayKey(*shift.StartTime)]; ok {
+				day.ScheduledHours += hours
+			}
+			laborFor(*shift.StaffID).ScheduledHours += hours
+		}
+
+		// overtime is weekly, so count the hours from the start of the week
+		// even when the report starts mid week
+		cursor, err = timeEntryCollection.Find(curCtx, bson.M{"clock_in": bson.M{"$gte": weekStart(from), "$lt": to}})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+		var entries []models.TimeEntry
+		if err = cursor.All(curCtx, &entries); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ClockIn.Before(entries[j].ClockIn) })
+
+		threshold, multiplier := overtimeRules()
+		weekHours := map[string]float64{}
+		now := time.Now()
+		for _, entry := range entries {
+			hours := workedTime(entry, now).Hours()
+			key := entry.StaffID + "/" + dayKey(weekStart(entry.ClockIn))
+			regular := threshold - weekHours[key]
+			if regular < 0 {
+				regular = 0
+			}
+			if regular > hours {
+				regular = hours
+			}
+			overtime := hours - regular
+			weekHours[key] += hours
+
+			if entry.ClockIn.Before(from) {
+				continue
+			}
+			cost := regular*entry.HourlyRate + overtime*entry.HourlyRate*multiplier
+			if day, ok := days[dayKey(entry.ClockIn)]; ok {
+				day.WorkedHours += hours
+				day.OvertimeHours += overtime
+				day.LaborCost += cost
+			}
+			labor := laborFor(entry.StaffID)
+			labor.WorkedHours += hours
+			labor.OvertimeHours += overtime
+			labor.LaborCost += cost
+		}
+
+		invoices, err := paidInvoices(curCtx, from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching invoices"})
+			return
+		}
+		for _, invoice := range invoices {
+			if day, ok := days[dayKey(*invoice.Invoice.PaidAt)]; ok {
+				day.Sales += invoice.Total
+			}
+		}
+
+		totals := LaborDay{Date: "total"}
+		for _, day := range dayList {
+			totals.ScheduledHours += day.ScheduledHours
+			totals.WorkedHours += day.WorkedHours
+			totals.OvertimeHours += day.OvertimeHours
+			totals.LaborCost += day.LaborCost
+			totals.Sales += day.Sales
+
+			day.ScheduledHours = toFixed(day.ScheduledHours, 2)
+			day.WorkedHours = toFixed(day.WorkedHours, 2)
+			day.OvertimeHours = toFixed(day.OvertimeHours, 2)
+			day.LaborCost = toFixed(day.LaborCost, 2)
+			day.Sales = toFixed(day.Sales, 2)
+			day.LaborCostPercent = percentOf(day.LaborCost, day.Sales)
+		}
+		totals.ScheduledHours = toFixed(totals.ScheduledHours, 2)
+		totals.WorkedHours = toFixed(totals.WorkedHours, 2)
+		totals.OvertimeHours = toFixed(totals.OvertimeHours, 2)
+		totals.LaborCost = toFixed(totals.LaborCost, 2)
+		totals.Sales = toFixed(totals.Sales, 2)
+		totals.LaborCostPercent = percentOf(totals.LaborCost, totals.Sales)
+
+		staffList := []StaffLabor{}
+		for _, labor := range staffLabor {
+			if labor.ScheduledHours == 0 && labor.WorkedHours == 0 {
+				continue
+			}
+			labor.ScheduledHours = toFixed(labor.ScheduledHours, 2)
+			labor.WorkedHours = toFixed(labor.WorkedHours, 2)
+			labor.OvertimeHours = toFixed(labor.OvertimeHours, 2)
+			labor.LaborCost = toFixed(labor.LaborCost, 2)
+			staffList = append(staffList, *labor)
+		}
+		sort.Slice(staffList, func(i, j int) bool { return staffList[i].LaborCost > staffList[j].LaborCost })
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"from":   dayKey(from),
+			"to":     dayKey(to.AddDate(0, 0, -1)),
+			"days":   dayList,
+			"staff":  staffList,
+			"totals": totals,
+		})
+	}
+}
@@ -0,0 +1,631 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var loyaltyProgramCollection *mongo.Collection = database.OpenCollection(database.Client, "loyaltyProgram")
+var loyaltyLedgerCollection *mongo.Collection = database.OpenCollection(database.Client, "loyaltyLedger")
+
+type LoyaltyAccount struct {
+	CustomerID     string                `json:"customer_id"`
+	Balance        int                   `json:"balance"`
+	BalanceValue   float64               `json:"balance_value"`
+	LifetimePoints int                   `json:"lifetime_points"`
+	Tier           *models.LoyaltyTier   `json:"tier,omitempty"`
+	NextTier       *models.LoyaltyTier   `json:"next_tier,omitempty"`
+	ExpiringSoon   int                   `json:"expiring_soon"`
+	Ledger         []models.LoyaltyEntry `json:"ledger"`
+}
+
+func loyaltyProgram(curCtx context.Context) (models.LoyaltyProgram, error) {
+	var program models.LoyaltyProgram
+	err := loyaltyProgramCollection.FindOne(curCtx, bson.M{"program_id": "default"}).Decode(&program)
+	if err == mongo.ErrNoDocuments {
+		return models.LoyaltyProgram{ProgramID: "default"}, nil
+	}
+	return program, err
+}
+
+var weekdays = map[string]bool{
+	"MONDAY": true, "TUESDAY": true, "WEDNESDAY": true, "THURSDAY": true,
+	"FRIDAY": true, "SATURDAY": true, "SUNDAY": true,
+}
+
+// dayMultiplier doubles points on the configured weekdays ("SATURDAY")
+// and dates ("2024-02-14")
+func dayMultiplier(program models.LoyaltyProgram, at time.Time) float64 {
+	weekday := strings.ToUpper(at.Weekday().String())
+	date := at.Format("2006-01-02")
+	for _, day := range program.DoublePointDays {
+		day = strings.ToUpper(strings.TrimSpace(day))
+		if day == weekday || day == date {
+			return 2
+		}
+	}
+	return 1
+}
+
+// customerTier picks the highest tier the lifetime points reach, and the
+// one after it
+func customerTier(program models.LoyaltyProgram, lifetime int) (*models.LoyaltyTier, *models.LoyaltyTier) {
+	var current, next *models.LoyaltyTier
+	for i := range program.Tiers {
+		tier := &program.Tiers[i]
+		if tier.Threshold <= lifetime {
+			if current == nil || tier.Threshold > current.Threshold {
+				current = tier
+			}
+		} else if next == nil || tier.Threshold < next.Threshold {
+			next = tier
+		}
+	}
+	return current, next
+}
+
+func insertLoyaltyEntry(curCtx context.Context, entry models.LoyaltyEntry) (models.LoyaltyEntry, error) {
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	entry.ID = primitive.NewObjectID()
+	entry.EntryID = entry.ID.Hex()
+	_, err := loyaltyLedgerCollection.InsertOne(curCtx, entry)
+	return entry, err
+}
+
+// creditEntry sets up an entry that adds points, with its expiry
+func creditEntry(program models.LoyaltyProgram, customerId string, kind string, points int) models.LoyaltyEntry {
+	entry := models.LoyaltyEntry{CustomerID: customerId, Type: kind, Points: points, Remaining: points}
+	if program.ExpiryDays > 0 {
+		expiresAt, _ := time.Parse(time.RFC3339, time.Now().AddDate(0, 0, program.ExpiryDays).Format(time.RFC3339))
+		entry.ExpiresAt = &expiresAt
+	}
+	return entry
+}
+
+// expirePoints writes off the unspent points of credits past their
+// expiry
+func expirePoints(curCtx context.Context, customerId string) error {
+	cursor, err := loyaltyLedgerCollection.Find(curCtx, bson.M{
+		"customer_id": customerId,
+		"remaining":   bson.M{"$gt": 0},
+		"expires_at":  bson.M{"$lte": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	var expired []models.LoyaltyEntry
+	if err = cursor.All(curCtx, &expired); err != nil {
+		return err
+	}
+	for _, credit := range expired {
+		entryId := credit.EntryID
+		_, err := insertLoyaltyEntry(curCtx, models.LoyaltyEntry{
+			CustomerID:  customerId,
+			Type:        "EXPIRE",
+			Points:      -credit.Remaining,
+			ReversalOf:  &entryId,
+			Description: "Points expired",
+		})
+		if err != nil {
+			return err
+		}
+		_, err = loyaltyLedgerCollection.UpdateOne(curCtx, bson.M{"entry_id": credit.EntryID}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "remaining", Value: 0}}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spendPoints takes points out of the credits, oldest first, starting
+// with the given credit when there is one
+func spendPoints(curCtx context.Context, customerId string, points int, first *string) error {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := loyaltyLedgerCollection.Find(curCtx, bson.M{
+		"customer_id": customerId,
+		"remaining":   bson.M{"$gt": 0},
+	}, opts)
+	if err != nil {
+		return err
+	}
+	var credits []models.LoyaltyEntry
+	if err = cursor.All(curCtx, &credits); err != nil {
+		return err
+	}
+	if first != nil {
+		for i, credit := range credits {
+			if credit.EntryID == *first {
+				credits = append([]models.LoyaltyEntry{credit}, append(credits[:i], credits[i+1:]...)...)
+				break
+			}
+		}
+	}
+
+	for _, credit := range credits {
+		if points == 0 {
+			break
+		}
+		used := credit.Remaining
+		if used > points {
+			used = points
+		}
+		points -= used
+		_, err := loyaltyLedgerCollection.UpdateOne(curCtx, bson.M{"entry_id": credit.EntryID}, bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "remaining", Value: -used}}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loyaltyBalance returns the spendable points and the lifetime points
+// earned, which decide the tier
+func loyaltyBalance(curCtx context.Context, customerId string) (int, int, error) {
+	if err := expirePoints(curCtx, customerId); err != nil {
+		return 0, 0, err
+	}
+	cursor, err := loyaltyLedgerCollection.Aggregate(curCtx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"customer_id": customerId}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "balance", Value: bson.D{{Key: "$sum", Value: "$points"}}},
+			{Key: "earned", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$type", "EARN"}}}, "$points", 0,
+			}}}}}},
+			{Key: "reversed", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$eq", Value: bson.A{"$type", "REVERSAL"}}},
+					bson.D{{Key: "$lt", Value: bson.A{"$points", 0}}},
+				}}}, "$points", 0,
+			}}}}}},
+		}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	var result []struct {
+		Balance  int `bson:"balance"`
+		Earned   int `bson:"earned"`
+		Reversed int `bson:"reversed"`
+	}
+	if err = cursor.All(curCtx, &result); err != nil {
+		return 0, 0, err
+	}
+	if len(result) == 0 {
+		return 0, 0, nil
+	}
+	return result[0].Balance, result[0].Earned + result[0].Reversed, nil
+}
+
+func discountTotal(invoice models.Invoice) float64 {
+	total := 0.0
+	for _, discount := range invoice.Discounts {
+		total += discount.Amount
+	}
+	return total
+}
+
+// accrueLoyalty credits the customer for a paid invoice, once
+func accrueLoyalty(curCtx context.Context, invoice models.Invoice) error {
+	if invoice.CustomerID == nil {
+		return nil
+	}
+	program, err := loyaltyProgram(curCtx)
+	if err != nil || !program.Enabled {
+		return err
+	}
+	earned, err := loyaltyLedgerCollection.CountDocuments(curCtx, bson.M{"invoice_id": invoice.InvoiceId, "type": "EARN"})
+	if err != nil || earned > 0 {
+		return err
+	}
+
+	lines, subtotal, err := receiptLines(curCtx, invoice.OrderId, nil)
+	if err != nil {
+		return err
+	}
+	_, lifetime, err := loyaltyBalance(curCtx, *invoice.CustomerID)
+	if err != nil {
+		return err
+	}
+	multiplier := dayMultiplier(program, time.Now())
+	if tier, _ := customerTier(program, lifetime); tier != nil && tier.Multiplier > 1 {
+		multiplier *= tier.Multiplier
+	}
+
+	net := math.Max(subtotal-discountTotal(invoice), 0)
+	points := int(math.Floor(net * program.PointsPerUnit * multiplier))
+	for _, line := range lines {
+		for _, bonus := range program.BonusItems {
+			if bonus.FoodID == line.FoodID {
+				points += bonus.Points
+			}
+		}
+	}
+	if points <= 0 {
+		return nil
+	}
+
+	entry := creditEntry(program, *invoice.CustomerID, "EARN", points)
+	invoiceId := invoice.InvoiceId
+	entry.InvoiceID = &invoiceId
+	entry.Description = fmt.Sprintf("Earned on invoice %s", invoiceId)
+	_, err = insertLoyaltyEntry(curCtx, entry)
+	return err
+}
+
+// reverseLoyalty undoes the points earned and spent on a refunded
+// invoice. Taking back earned points can leave a negative balance when
+// they were already spent.
+func reverseLoyalty(curCtx context.Context, invoice models.Invoice) error {
+	cursor, err := loyaltyLedgerCollection.Find(curCtx, bson.M{
+		"invoice_id": invoice.InvoiceId,
+		"type":       bson.M{"$in": bson.A{"EARN", "REDEEM"}},
+	})
+	if err != nil {
+		return err
+	}
+	var entries []models.LoyaltyEntry
+	if err = cursor.All(curCtx, &entries); err != nil {
+		return err
+	}
+	program, err := loyaltyProgram(curCtx)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		reversed, err := loyaltyLedgerCollection.CountDocuments(curCtx, bson.M{"reversal_of": entry.EntryID, "type": "REVERSAL"})
+		if err != nil {
+			return err
+		}
+		if reversed > 0 {
+			continue
+		}
+
+		entryId, invoiceId := entry.EntryID, invoice.InvoiceId
+		var reversal models.LoyaltyEntry
+		if entry.Type == "EARN" {
+			reversal = models.LoyaltyEntry{CustomerID: entry.CustomerID, Type: "REVERSAL", Points: -entry.Points}
+			if err := spendPoints(curCtx, entry.CustomerID, entry.Points, &entryId); err != nil {
+				return err
+			}
+		} else {
+			// spent points come back as a fresh credit
+			reversal = creditEntry(program, entry.CustomerID, "REVERSAL", -entry.Points)
+		}
+		reversal.InvoiceID = &invoiceId
+		reversal.ReversalOf = &entryId
+		reversal.Description = fmt.Sprintf("Refund of invoice %s", invoiceId)
+		if _, err := insertLoyaltyEntry(curCtx, reversal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyLoyalty runs the ledger side of an invoice status change
+func applyLoyalty(curCtx context.Context, invoice models.Invoice, previousStatus string) error {
+	if invoice.PaymentStatus == nil || *invoice.PaymentStatus == previousStatus {
+		return nil
+	}
+	switch *invoice.PaymentStatus {
+	case "PAID":
+		return accrueLoyalty(curCtx, invoice)
+	case "REFUNDED":
+		return reverseLoyalty(curCtx, invoice)
+	}
+	return nil
+}
+
+func GetLoyaltyProgram() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		program, err := loyaltyProgram(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the loyalty program"})
+			return
+		}
+		ctx.JSON(http.StatusOK, program)
+	}
+}
+
+func UpdateLoyaltyProgram() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var program models.LoyaltyProgram
+		if err := ctx.BindJSON(&program); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(program); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		for _, day := range program.DoublePointDays {
+			day = strings.ToUpper(strings.TrimSpace(day))
+			if _, err := time.Parse("2006-01-02", day); err == nil {
+				continue
+			}
+			if !weekdays[day] {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "double_point_days must be weekdays or dates like 2006-01-02"})
+				return
+			}
+		}
+
+		program.ProgramID = "default"
+		program.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		upsert := true
+		opt := options.ReplaceOptions{Upsert: &upsert}
+		_, err := loyaltyProgramCollection.ReplaceOne(curCtx, bson.M{"program_id": "default"}, program, &opt)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Loyalty program updation failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, program)
+	}
+}
+
+func GetLoyaltyAccount() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		customer, err := findCustomer(curCtx, ctx.Param("customer_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching customer")
+			return
+		}
+		program, err := loyaltyProgram(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the loyalty program"})
+			return
+		}
+
+		account := LoyaltyAccount{CustomerID: customer.CustomerID}
+		account.Balance, account.LifetimePoints, err = loyaltyBalance(curCtx, customer.CustomerID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the loyalty ledger"})
+			return
+		}
+		account.BalanceValue = toFixed(float64(account.Balance)*program.PointValue, 2)
+		account.Tier, account.NextTier = customerTier(program, account.LifetimePoints)
+
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(100)
+		cursor, err := loyaltyLedgerCollection.Find(curCtx, bson.M{"customer_id": customer.CustomerID}, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the loyalty ledger"})
+			return
+		}
+		account.Ledger = []models.LoyaltyEntry{}
+		if err = cursor.All(curCtx, &account.Ledger); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the loyalty ledger"})
+			return
+		}
+
+		soon := time.Now().AddDate(0, 0, 30)
+		cursor, err = loyaltyLedgerCollection.Find(curCtx, bson.M{
+			"customer_id": customer.CustomerID,
+			"remaining":   bson.M{"$gt": 0},
+			"expires_at":  bson.M{"$lte": soon},
+		})
+		if err == nil {
+			var credits []models.LoyaltyEntry
+			if cursor.All(curCtx, &credits) == nil {
+				for _, credit := range credits {
+					account.ExpiringSoon += credit.Remaining
+				}
+			}
+		}
+		ctx.JSON(http.StatusOK, account)
+	}
+}
+
+// AdjustLoyaltyPoints lets a manager add or remove points by hand
+func AdjustLoyaltyPoints() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Points      int    `json:"points" validate:"required"`
+			Description string `json:"description" validate:"required"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var entry models.LoyaltyEntry
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			customer, err := findCustomer(sessCtx, ctx.Param("customer_id"))
+			if err != nil {
+				return err
+			}
+			program, err := loyaltyProgram(sessCtx)
+			if err != nil {
+				return err
+			}
+			if body.Points > 0 {
+				entry = creditEntry(program, customer.CustomerID, "ADJUST", body.Points)
+			} else {
+				balance, _, err := loyaltyBalance(sessCtx, customer.CustomerID)
+				if err != nil {
+					return err
+				}
+				if balance < -body.Points {
+					return requestError{http.StatusConflict, "Not enough points"}
+				}
+				if err := spendPoints(sessCtx, customer.CustomerID, -body.Points, nil); err != nil {
+					return err
+				}
+				entry = models.LoyaltyEntry{CustomerID: customer.CustomerID, Type: "ADJUST", Points: body.Points}
+			}
+			entry.Description = body.Description
+			entry, err = insertLoyaltyEntry(sessCtx, entry)
+			return err
+		})
+		if err != nil {
+			respondError(ctx, err, "Loyalty adjustment failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, entry)
+	}
+}
+
+// RedeemLoyaltyPoints takes points off an unpaid invoice, either as an
+// amount off ({"points": 500}) or as a free reward item ({"food_id": ...})
+func RedeemLoyaltyPoints() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Points int     `json:"points" validate:"min=0"`
+			FoodID *string `json:"food_id"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if (body.Points > 0) == (body.FoodID != nil) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Send either points or food_id"})
+			return
+		}
+
+		var discount models.InvoiceDiscount
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			var invoice models.Invoice
+			err := invoiceCollection.FindOne(sessCtx, bson.M{"invoice_id": ctx.Param("invoice_id")}).Decode(&invoice)
+			if err == mongo.ErrNoDocuments {
+				return requestError{http.StatusNotFound, "Invoice was not found"}
+			}
+			if err != nil {
+				return err
+			}
+			if invoice.PaymentStatus == nil || *invoice.PaymentStatus != "PENDING" {
+				return requestError{http.StatusConflict, "Points can only be redeemed on unpaid invoices"}
+			}
+			if invoice.CustomerID == nil {
+				return requestError{http.StatusBadRequest, "Invoice has no customer"}
+			}
+			program, err := loyaltyProgram(sessCtx)
+			if err != nil {
+				return err
+			}
+			if !program.Enabled {
+				return requestError{http.StatusConflict, "Loyalty program is not enabled"}
+			}
+
+			lines, subtotal, err := receiptLines(sessCtx, invoice.OrderId, nil)
+			if err != nil {
+				return err
+			}
+			due := subtotal - discountTotal(invoice)
+
+			if body.FoodID != nil {
+				discount.Type = "LOYALTY_REWARD"
+				discount.FoodID = body.FoodID
+				for _, reward := range program.RewardItems {
+					if reward.FoodID == *body.FoodID {
+						discount.Points = reward.Points
+					}
+				}
+				if discount.Points == 0 {
+					return requestError{http.StatusBadRequest, "Food is not a loyalty reward"}
+				}
+				// the reward must be on the bill and not already free
+				rewarded := 0
+				for _, existing := range invoice.Discounts {
+					if existing.FoodID != nil && *existing.FoodID == *body.FoodID {
+						rewarded++
+					}
+				}
+				for _, line := range lines {
+					if line.FoodID != *body.FoodID {
+						continue
+					}
+					if rewarded > 0 {
+						rewarded--
+						continue
+					}
+					discount.Amount = line.Amount
+					break
+				}
+				if discount.Amount == 0 {
+					return requestError{http.StatusBadRequest, "Reward item is not on this order"}
+				}
+			} else {
+				discount.Type = "LOYALTY_POINTS"
+				discount.Points = body.Points
+				discount.Amount = toFixed(float64(body.Points)*program.PointValue, 2)
+			}
+			if discount.Amount > due+0.005 {
+				return requestError{http.StatusBadRequest, "Discount is more than the amount due"}
+			}
+
+			balance, _, err := loyaltyBalance(sessCtx, *invoice.CustomerID)
+			if err != nil {
+				return err
+			}
+			if balance < discount.Points {
+				return requestError{http.StatusConflict, "Not enough points"}
+			}
+			if err := spendPoints(sessCtx, *invoice.CustomerID, discount.Points, nil); err != nil {
+				return err
+			}
+
+			invoiceId := invoice.InvoiceId
+			entry, err := insertLoyaltyEntry(sessCtx, models.LoyaltyEntry{
+				CustomerID:  *invoice.CustomerID,
+				InvoiceID:   &invoiceId,
+				Type:        "REDEEM",
+				Points:      -discount.Points,
+				Description: fmt.Sprintf("Redeemed on invoice %s", invoiceId),
+			})
+			if err != nil {
+				return err
+			}
+			discount.EntryID = entry.EntryID
+
+			updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			_, err = invoiceCollection.UpdateOne(sessCtx, bson.M{"invoice_id": invoiceId}, bson.D{
+				{Key: "$push", Value: bson.D{{Key: "discounts", Value: discount}}},
+				{Key: "$set", Value: bson.D{{Key: "updated_at", Value: updatedAt}}},
+			})
+			return err
+		})
+		if err != nil {
+			respondError(ctx, err, "Redemption failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, discount)
+	}
+}
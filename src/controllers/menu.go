@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"infinity/rms/database"
+	"infinity/rms/helpers"
 	"infinity/rms/models"
 	"log"
 	"net/http"
@@ -34,6 +35,10 @@ func GetMenus() gin.HandlerFunc {
 		if err = result.All(c, &allMenus); err != nil {
 			log.Fatal(err)
 		}
+		locales := requestLocales(ctx)
+		for _, menu := range allMenus {
+			localizeDoc(menu, locales, "name", "category")
+		}
 		ctx.JSON(http.StatusOK, allMenus)
 
 	}
@@ -53,6 +58,10 @@ func GetMenu() gin.HandlerFunc {
 			})
 		}
 
+		locales := requestLocales(ctx)
+		menu.Name = helpers.Localize(menu.NameTranslations, menu.Name, locales)
+		menu.Category = helpers.Localize(menu.CategoryTranslations, menu.Category, locales)
+
 		ctx.JSON(http.StatusOK, menu)
 	}
 }
@@ -137,6 +146,9 @@ func UpdateMenu() gin.HandlerFunc {
 		if menu.Category != "" {
 			updateObj = append(updateObj, bson.E{Key: "category", Value: menu.Category})
 		}
+		if menu.SKU != "" {
+			updateObj = append(updateObj, bson.E{Key: "sku", Value: menu.SKU})
+		}
 
 		menu.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
@@ -0,0 +1,683 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"io"
+	"maps"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MenuImportRow is one food together with the menu it belongs to. CSV
+// files use the json names as headers and "|" between list values, with a
+// column per locale for translations, e.g. "name_translations.fr".
+type MenuImportRow struct {
+	MenuSKU                  string            `json:"menu_sku"`
+	MenuName                 string            `json:"menu_name"`
+	MenuNameTranslations     map[string]string `json:"menu_name_translations,omitempty"`
+	MenuCategory             string            `json:"menu_category"`
+	MenuCategoryTranslations map[string]string `json:"menu_category_translations,omitempty"`
+	MenuStartDate            *time.Time        `json:"menu_start_date,omitempty"`
+	MenuEndDate              *time.Time        `json:"menu_end_date,omitempty"`
+	SKU                      string            `json:"sku"`
+	Name                     string            `json:"name"`
+	NameTranslations         map[string]string `json:"name_translations,omitempty"`
+	Price                    float64           `json:"price"`
+	FoodImage                string            `json:"food_image,omitempty"`
+	Allergens                []string          `json:"allergens,omitempty"`
+	DietaryTags              []string          `json:"dietary_tags,omitempty"`
+
+	line int
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+type MenuImportResult struct {
+	DryRun       bool             `json:"dry_run"`
+	Rows         int              `json:"rows"`
+	MenusCreated int              `json:"menus_created"`
+	MenusUpdated int              `json:"menus_updated"`
+	FoodsCreated int              `json:"foods_created"`
+	FoodsUpdated int              `json:"foods_updated"`
+	Errors       []ImportRowError `json:"errors"`
+}
+
+var menuImportHeader = []string{
+	"menu_sku", "menu_name", "menu_category", "menu_start_date", "menu_end_date",
+	"sku", "name", "price", "food_image", "allergens", "dietary_tags",
+}
+
+// menuImportTranslated are the fields with a "<field>.<locale>" CSV column
+// for each locale
+var menuImportTranslated = []string{"menu_name_translations", "menu_category_translations", "name_translations"}
+
+func (row *MenuImportRow) translations(field string) *map[string]string {
+	switch field {
+	case "menu_name_translations":
+		return &row.MenuNameTranslations
+	case "menu_category_translations":
+		return &row.MenuCategoryTranslations
+	case "name_translations":
+		return &row.NameTranslations
+	}
+	return nil
+}
+
+func ImportMenus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		dryRun, _ := strconv.ParseBool(ctx.Query("dry_run"))
+
+		data, format, err := readImportBody(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var rows []MenuImportRow
+		var parseErrors []ImportRowError
+		if format == "csv" {
+			rows, parseErrors = parseMenuCSV(data)
+		} else {
+			if err := json.Unmarshal(data, &rows); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid JSON: %s", err)})
+				return
+			}
+			for i := range rows {
+				rows[i].line = i + 1
+			}
+		}
+
+		result := MenuImportResult{DryRun: dryRun, Rows: len(rows) + len(parseErrors)}
+		result.Errors = append(parseErrors, validateMenuRows(curCtx, rows)...)
+		sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Row < result.Errors[j].Row })
+
+		// nothing is written unless every row is valid
+		if len(result.Errors) > 0 {
+			ctx.JSON(http.StatusUnprocessableEntity, result)
+			return
+		}
+
+		if err := applyMenuImport(curCtx, rows, &result); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Menu import failed: %s", err)})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func ExportMenus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		menuFilter := bson.M{}
+		if menuId := ctx.Query("menu_id"); menuId != "" {
+			menuFilter["menu_id"] = menuId
+		}
+
+		rows, err := exportMenuRows(curCtx, menuFilter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while exporting menus"})
+			return
+		}
+
+		if ctx.Query("format") == "csv" {
+			columns := translationColumns(rows)
+			var buf bytes.Buffer
+			writer := csv.NewWriter(&buf)
+			writer.Write(append(append([]string{}, menuImportHeader...), columns...))
+			for _, row := range rows {
+				writer.Write(row.csvRecord(columns))
+			}
+			writer.Flush()
+			ctx.Header("Content-Disposition", `attachment; filename="menus.csv"`)
+			ctx.Data(http.StatusOK, "text/csv", buf.Bytes())
+			return
+		}
+		ctx.Header("Content-Disposition", `attachment; filename="menus.json"`)
+		ctx.JSON(http.StatusOK, rows)
+	}
+}
+
+// ImportMenuRows upserts menus and foods by SKU the way ImportMenus does,
+// for callers outside HTTP. Nothing is written unless every row is valid,
+// result.Errors says what isn't.
+func ImportMenuRows(curCtx context.Context, rows []MenuImportRow, dryRun bool) (MenuImportResult, error) {
+	for i := range rows {
+		rows[i].line = i + 1
+	}
+	result := MenuImportResult{DryRun: dryRun, Rows: len(rows)}
+	result.Errors = validateMenuRows(curCtx, rows)
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	err := applyMenuImport(curCtx, rows, &result)
+	return result, err
+}
+
+// readImportBody accepts either a multipart "file" field or a raw body;
+// the format comes from ?format=, then the file name, then the content type
+func readImportBody(ctx *gin.Context) ([]byte, string, error) {
+	format := ctx.Query("format")
+
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("The file field is required")
+		}
+		if format == "" && strings.HasSuffix(strings.ToLower(fileHeader.Filename), ".csv") {
+			format = "csv"
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if format == "" {
+			format = "json"
+		}
+		return data, format, err
+	}
+
+	if format == "" {
+		format = "json"
+		if strings.Contains(ctx.ContentType(), "csv") {
+			format = "csv"
+		}
+	}
+	data, err := io.ReadAll(ctx.Request.Body)
+	return data, format, err
+}
+
+func parseMenuCSV(data []byte) ([]MenuImportRow, []ImportRowError) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []ImportRowError{{Row: 1, Error: "Missing header row"}}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	rows := []MenuImportRow{}
+	errs := []ImportRowError{}
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			errs = append(errs, ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := MenuImportRow{
+			line:         line,
+			MenuSKU:      get("menu_sku"),
+			MenuName:     get("menu_name"),
+			MenuCategory: get("menu_category"),
+			SKU:          get("sku"),
+			Name:         get("name"),
+			FoodImage:    get("food_image"),
+			Allergens:    splitList(get("allergens")),
+			DietaryTags:  splitList(get("dietary_tags")),
+		}
+		for name := range columns {
+			field, locale, ok := strings.Cut(name, ".")
+			translations := row.translations(field)
+			if !ok || translations == nil {
+				continue
+			}
+			if text := get(name); text != "" {
+				if *translations == nil {
+					*translations = map[string]string{}
+				}
+				(*translations)[strings.ToLower(locale)] = text
+			}
+		}
+
+		rowErrs := []ImportRowError{}
+		if price := get("price"); price != "" {
+			row.Price, err = strconv.ParseFloat(price, 64)
+			if err != nil {
+				rowErrs = append(rowErrs, ImportRowError{Row: line, Field: "price", Error: "Price is not a number"})
+			}
+		}
+		for _, field := range []string{"menu_start_date", "menu_end_date"} {
+			value := get(field)
+			if value == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				rowErrs = append(rowErrs, ImportRowError{Row: line, Field: field, Error: "Date must be RFC3339"})
+				continue
+			}
+			if field == "menu_start_date" {
+				row.MenuStartDate = &parsed
+			} else {
+				row.MenuEndDate = &parsed
+			}
+		}
+
+		if len(rowErrs) > 0 {
+			errs = append(errs, rowErrs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "|")
+}
+
+func validateMenuRows(curCtx context.Context, rows []MenuImportRow) []ImportRowError {
+	errs := []ImportRowError{}
+
+	known, err := knownAllergens(curCtx)
+	if err != nil {
+		return []ImportRowError{{Error: "Error occured while checking allergens"}}
+	}
+
+	foodSKUs := map[string]int{}
+	menus := map[string]*MenuImportRow{}
+	for i := range rows {
+		row := &rows[i]
+		// JSON rows are numbered from 1, CSV rows by file line
+		rowNumber := row.line
+
+		row.Allergens = normalizeTags(row.Allergens)
+		row.DietaryTags = normalizeTags(row.DietaryTags)
+
+		if row.MenuSKU == "" {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "menu_sku", Error: "Menu SKU is required"})
+		}
+		if row.MenuName == "" {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "menu_name", Error: "Menu name is required"})
+		}
+		if row.MenuCategory == "" {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "menu_category", Error: "Menu category is required"})
+		}
+		// a menu is written once, from its first row, so the others can't
+		// quietly disagree with it
+		if first, ok := menus[row.MenuSKU]; ok {
+			errs = append(errs, menuConflicts(*first, *row)...)
+		} else if row.MenuSKU != "" {
+			menus[row.MenuSKU] = row
+		}
+		if row.SKU == "" {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "sku", Error: "SKU is required"})
+		} else if first, ok := foodSKUs[row.SKU]; ok {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "sku", Error: fmt.Sprintf("SKU already used on row %d", first)})
+		} else {
+			foodSKUs[row.SKU] = rowNumber
+		}
+		if len(row.Name) < 2 || len(row.Name) > 100 {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "name", Error: "Name must be 2 to 100 characters"})
+		}
+		if row.Price <= 0 {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "price", Error: "Price must be positive"})
+		}
+		for _, code := range row.Allergens {
+			if !known[code] {
+				errs = append(errs, ImportRowError{Row: rowNumber, Field: "allergens", Error: "Unknown allergen " + code})
+			}
+		}
+		if err := checkDietaryTags(row.DietaryTags); err != nil {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "dietary_tags", Error: err.Error()})
+		}
+		if row.MenuStartDate != nil && row.MenuEndDate != nil && !row.MenuEndDate.After(*row.MenuStartDate) {
+			errs = append(errs, ImportRowError{Row: rowNumber, Field: "menu_end_date", Error: "End date must be after start date"})
+		}
+		for _, field := range menuImportTranslated {
+			for locale := range *row.translations(field) {
+				if _, ok := supportedLocale(locale); !ok {
+					errs = append(errs, ImportRowError{Row: rowNumber, Field: field, Error: "Unsupported locale " + locale})
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// menuConflicts compares a row with the first row of the same menu
+func menuConflicts(first, row MenuImportRow) []ImportRowError {
+	sameDate := func(a, b *time.Time) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.Equal(*b)
+	}
+
+	errs := []ImportRowError{}
+	conflict := func(field string) {
+		errs = append(errs, ImportRowError{Row: row.line, Field: field, Error: fmt.Sprintf("Menu differs from row %d", first.line)})
+	}
+	if row.MenuName != first.MenuName {
+		conflict("menu_name")
+	}
+	if row.MenuCategory != first.MenuCategory {
+		conflict("menu_category")
+	}
+	if !sameDate(row.MenuStartDate, first.MenuStartDate) {
+		conflict("menu_start_date")
+	}
+	if !sameDate(row.MenuEndDate, first.MenuEndDate) {
+		conflict("menu_end_date")
+	}
+	if !maps.Equal(row.MenuNameTranslations, first.MenuNameTranslations) {
+		conflict("menu_name_translations")
+	}
+	if !maps.Equal(row.MenuCategoryTranslations, first.MenuCategoryTranslations) {
+		conflict("menu_category_translations")
+	}
+	return errs
+}
+
+// applyMenuImport upserts menus and foods keyed by their SKU
+func applyMenuImport(curCtx context.Context, rows []MenuImportRow, result *MenuImportResult) error {
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	menuSKUs := []string{}
+	foodSKUs := []string{}
+	for _, row := range rows {
+		menuSKUs = append(menuSKUs, row.MenuSKU)
+		foodSKUs = append(foodSKUs, row.SKU)
+	}
+
+	menuIds, err := idsBySKU(curCtx, menuCollection, menuSKUs, "menu_id")
+	if err != nil {
+		return err
+	}
+	foodIds, err := idsBySKU(curCtx, foodCollection, foodSKUs, "food_id")
+	if err != nil {
+		return err
+	}
+
+	menuModels := []mongo.WriteModel{}
+	seenMenus := map[string]bool{}
+	for _, row := range rows {
+		if seenMenus[row.MenuSKU] {
+			continue
+		}
+		seenMenus[row.MenuSKU] = true
+
+		set := bson.D{
+			{Key: "name", Value: row.MenuName},
+			{Key: "category", Value: row.MenuCategory},
+			{Key: "start_date", Value: row.MenuStartDate},
+			{Key: "end_date", Value: row.MenuEndDate},
+			{Key: "updated_at", Value: updatedAt},
+		}
+		// files without translations leave the ones already there alone
+		if len(row.MenuNameTranslations) > 0 {
+			set = append(set, bson.E{Key: "name_translations", Value: row.MenuNameTranslations})
+		}
+		if len(row.MenuCategoryTranslations) > 0 {
+			set = append(set, bson.E{Key: "category_translations", Value: row.MenuCategoryTranslations})
+		}
+		if menuId, ok := menuIds[row.MenuSKU]; ok {
+			result.MenusUpdated++
+			set = append(set, bson.E{Key: "sku", Value: row.MenuSKU})
+			menuModels = append(menuModels, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"menu_id": menuId}).
+				SetUpdate(bson.D{{Key: "$set", Value: set}}))
+			continue
+		}
+
+		id := primitive.NewObjectID()
+		menuIds[row.MenuSKU] = id.Hex()
+		result.MenusCreated++
+		menuModels = append(menuModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"sku": row.MenuSKU}).
+			SetUpdate(bson.D{
+				{Key: "$set", Value: append(set, bson.E{Key: "sku", Value: row.MenuSKU})},
+				{Key: "$setOnInsert", Value: bson.D{
+					{Key: "_id", Value: id},
+					{Key: "menu_id", Value: id.Hex()},
+					{Key: "created_at", Value: updatedAt},
+				}},
+			}).
+			SetUpsert(true))
+	}
+
+	foodModels := []mongo.WriteModel{}
+	for _, row := range rows {
+		set := bson.D{
+			{Key: "name", Value: row.Name},
+			{Key: "price", Value: toFixed(row.Price, 2)},
+			{Key: "menu_id", Value: menuIds[row.MenuSKU]},
+			{Key: "food_image", Value: row.FoodImage},
+			{Key: "allergens", Value: row.Allergens},
+			{Key: "dietary_tags", Value: row.DietaryTags},
+			{Key: "updated_at", Value: updatedAt},
+		}
+		if len(row.NameTranslations) > 0 {
+			set = append(set, bson.E{Key: "name_translations", Value: row.NameTranslations})
+		}
+		if foodId, ok := foodIds[row.SKU]; ok {
+			result.FoodsUpdated++
+			set = append(set, bson.E{Key: "sku", Value: row.SKU})
+			foodModels = append(foodModels, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"food_id": foodId}).
+				SetUpdate(bson.D{{Key: "$set", Value: set}}))
+			continue
+		}
+
+		id := primitive.NewObjectID()
+		result.FoodsCreated++
+		foodModels = append(foodModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"sku": row.SKU}).
+			SetUpdate(bson.D{
+				{Key: "$set", Value: append(set, bson.E{Key: "sku", Value: row.SKU})},
+				{Key: "$setOnInsert", Value: bson.D{
+					{Key: "_id", Value: id},
+					{Key: "food_id", Value: id.Hex()},
+					{Key: "created_at", Value: updatedAt},
+				}},
+			}).
+			SetUpsert(true))
+	}
+
+	if result.DryRun {
+		return nil
+	}
+
+	// menus and foods land together or not at all
+	return database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+		if len(menuModels) > 0 {
+			if _, err := menuCollection.BulkWrite(sessCtx, menuModels); err != nil {
+				return err
+			}
+		}
+		if len(foodModels) > 0 {
+			if _, err := foodCollection.BulkWrite(sessCtx, foodModels); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// idsBySKU maps each SKU to an existing id. Exports use the id as SKU for
+// records that never had one, so those match on the id field as well.
+func idsBySKU(curCtx context.Context, collection *mongo.Collection, skus []string, idField string) (map[string]string, error) {
+	ids := map[string]string{}
+
+	cursor, err := collection.Find(curCtx, bson.M{"$or": bson.A{
+		bson.M{"sku": bson.M{"$in": skus}},
+		bson.M{idField: bson.M{"$in": skus}},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.M
+	if err = cursor.All(curCtx, &docs); err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		id, _ := doc[idField].(string)
+		if sku, _ := doc["sku"].(string); sku != "" {
+			ids[sku] = id
+		}
+		if _, ok := ids[id]; !ok {
+			ids[id] = id
+		}
+	}
+	return ids, nil
+}
+
+func exportMenuRows(curCtx context.Context, menuFilter bson.M) ([]MenuImportRow, error) {
+	cursor, err := menuCollection.Find(curCtx, menuFilter)
+	if err != nil {
+		return nil, err
+	}
+	var menus []models.Menu
+	if err = cursor.All(curCtx, &menus); err != nil {
+		return nil, err
+	}
+
+	menusById := map[string]models.Menu{}
+	menuIds := []string{}
+	for _, menu := range menus {
+		menusById[menu.MenuId] = menu
+		menuIds = append(menuIds, menu.MenuId)
+	}
+
+	cursor, err = foodCollection.Find(curCtx, bson.M{"menu_id": bson.M{"$in": menuIds}})
+	if err != nil {
+		return nil, err
+	}
+	var foods []models.Food
+	if err = cursor.All(curCtx, &foods); err != nil {
+		return nil, err
+	}
+
+	rows := []MenuImportRow{}
+	for _, food := range foods {
+		menu := menusById[*food.MenuId]
+
+		// records created through the API have no SKU yet, fall back to the id
+		row := MenuImportRow{
+			MenuSKU:                  menu.SKU,
+			MenuName:                 menu.Name,
+			MenuNameTranslations:     menu.NameTranslations,
+			MenuCategory:             menu.Category,
+			MenuCategoryTranslations: menu.CategoryTranslations,
+			MenuStartDate:            menu.StartDate,
+			MenuEndDate:              menu.EndDate,
+			SKU:                      food.FoodId,
+			NameTranslations:         food.NameTranslations,
+			Allergens:                food.Allergens,
+			DietaryTags:              food.DietaryTags,
+		}
+		if row.MenuSKU == "" {
+			row.MenuSKU = menu.MenuId
+		}
+		if food.SKU != nil && *food.SKU != "" {
+			row.SKU = *food.SKU
+		}
+		if food.Name != nil {
+			row.Name = *food.Name
+		}
+		if food.Price != nil {
+			row.Price = *food.Price
+		}
+		if food.FoodImage != nil {
+			row.FoodImage = *food.FoodImage
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].MenuSKU != rows[j].MenuSKU {
+			return rows[i].MenuSKU < rows[j].MenuSKU
+		}
+		return rows[i].SKU < rows[j].SKU
+	})
+	return rows, nil
+}
+
+// translationColumns lists a column for each translation the rows have
+func translationColumns(rows []MenuImportRow) []string {
+	seen := map[string]bool{}
+	columns := []string{}
+	for i := range rows {
+		for _, field := range menuImportTranslated {
+			for locale := range *rows[i].translations(field) {
+				if column := field + "." + locale; !seen[column] {
+					seen[column] = true
+					columns = append(columns, column)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func (row MenuImportRow) csvRecord(translationColumns []string) []string {
+	formatDate := func(date *time.Time) string {
+		if date == nil {
+			return ""
+		}
+		return date.Format(time.RFC3339)
+	}
+	record := []string{
+		row.MenuSKU,
+		row.MenuName,
+		row.MenuCategory,
+		formatDate(row.MenuStartDate),
+		formatDate(row.MenuEndDate),
+		row.SKU,
+		row.Name,
+		strconv.FormatFloat(row.Price, 'f', 2, 64),
+		row.FoodImage,
+		strings.Join(row.Allergens, "|"),
+		strings.Join(row.DietaryTags, "|"),
+	}
+	for _, column := range translationColumns {
+		field, locale, _ := strings.Cut(column, ".")
+		record = append(record, (*row.translations(field))[locale])
+	}
+	return record
+}
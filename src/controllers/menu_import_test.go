@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestMenuCSVKeepsTranslations(t *testing.T) {
+	rows := []MenuImportRow{
+		{
+			MenuSKU: "M-1", MenuName: "Lunch", MenuCategory: "Mains",
+			MenuNameTranslations: map[string]string{"fr": "Déjeuner"},
+			SKU:                  "F-1", Name: "Soup", Price: 4.5,
+			NameTranslations: map[string]string{"fr": "Soupe", "es": "Sopa"},
+		},
+		{
+			MenuSKU: "M-1", MenuName: "Lunch", MenuCategory: "Mains",
+			MenuNameTranslations: map[string]string{"fr": "Déjeuner"},
+			SKU:                  "F-2", Name: "Bread", Price: 2,
+		},
+	}
+
+	columns := translationColumns(rows)
+	if want := []string{"menu_name_translations.fr", "name_translations.es", "name_translations.fr"}; !reflect.DeepEqual(columns, want) {
+		t.Fatalf("columns %v, want %v", columns, want)
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(append(append([]string{}, menuImportHeader...), columns...))
+	for _, row := range rows {
+		writer.Write(row.csvRecord(columns))
+	}
+	writer.Flush()
+
+	parsed, errs := parseMenuCSV(buf.Bytes())
+	if len(errs) > 0 {
+		t.Fatalf("parse errors %v", errs)
+	}
+	for i := range parsed {
+		parsed[i].line = 0
+	}
+	if !reflect.DeepEqual(parsed, rows) {
+		t.Fatalf("rows came back as %+v, want %+v", parsed, rows)
+	}
+}
+
+func TestMenuConflicts(t *testing.T) {
+	first := MenuImportRow{MenuSKU: "M-1", MenuName: "Lunch", MenuCategory: "Mains", line: 2}
+	if errs := menuConflicts(first, first); len(errs) != 0 {
+		t.Fatalf("the same menu conflicts: %v", errs)
+	}
+
+	row := first
+	row.line = 5
+	row.MenuName = "Dinner"
+	row.MenuCategory = "Starters"
+	errs := menuConflicts(first, row)
+	if len(errs) != 2 || errs[0].Field != "menu_name" || errs[1].Field != "menu_category" || errs[0].Row != 5 {
+		t.Fatalf("got %v, want name and category conflicts on row 5", errs)
+	}
+}
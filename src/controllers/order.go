@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"infinity/rms/database"
+	"infinity/rms/events"
 	"infinity/rms/models"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,7 +23,17 @@ var orderCollection *mongo.Collection = database.OpenCollection(database.Client,
 func GetOrders() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		c, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
+		filter := bson.M{}
+		if ctx.Query("mine") == "true" {
+			filter["server_id"] = ctx.GetString("uid")
+		}
+		if orderType := strings.ToUpper(ctx.Query("type")); orderType == "DINE_IN" {
+			// orders from before order types are all dine-in
+			filter["type"] = bson.M{"$in": []interface{}{"DINE_IN", nil}}
+		} else if orderType != "" {
+			filter["type"] = orderType
+		}
+		result, err := orderCollection.Find(context.TODO(), filter)
 		defer cancel()
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -79,26 +91,61 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
-		err := tableCollection.FindOne(curCtx, bson.M{"table_id": order.TableID}).Decode(&table)
-		if err != nil {
-			msg := fmt.Sprintf("Table wasn't found")
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"error": msg,
-			})
+		if offPremises(order) {
+			if err := prepareOffPremisesOrder(curCtx, &order); err != nil {
+				respondError(ctx, err, "Failed to create an order")
+				return
+			}
+		} else {
+			order.Type = "DINE_IN"
+			err := tableCollection.FindOne(curCtx, bson.M{"table_id": order.TableID}).Decode(&table)
+			if err != nil {
+				msg := fmt.Sprintf("Table wasn't found")
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"error": msg,
+				})
+				return
+			}
 		}
 
+		order.Allergies = normalizeTags(order.Allergies)
+		if order.CustomerID != nil {
+			allergies, err := customerAllergies(curCtx, *order.CustomerID, order.Allergies)
+			if err != nil {
+				respondError(ctx, err, "Error occured while fetching customer")
+				return
+			}
+			order.Allergies = allergies
+		}
+		order.ServerID = currentUser(ctx)
+		if order.Covers == nil && order.TableID != nil {
+			order.Covers = table.NumberOfGuests
+		}
 		order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.ID = primitive.NewObjectID()
 		order.OrderID = order.ID.Hex()
 		order.OrderDate, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := scheduleOrder(curCtx, &order); err != nil {
+			respondError(ctx, err, "Failed to create an order")
+			return
+		}
 
-		result, insertErr := orderCollection.InsertOne(curCtx, order)
-		if insertErr != nil {
-			msg := fmt.Sprintf("Failed to create an order")
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"error": msg,
+		var result *mongo.InsertOneResult
+		insertErr := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, tableIdsOf(order.TableID), func() error {
+				if err := reserveSlot(sessCtx, order); err != nil {
+					return err
+				}
+				var err error
+				if result, err = orderCollection.InsertOne(sessCtx, order); err != nil {
+					return err
+				}
+				return publish(sessCtx, events.OrderCreated, order.OrderID, order)
 			})
+		})
+		if insertErr != nil {
+			respondError(ctx, insertErr, "Failed to create an order")
 			return
 		}
 		ctx.JSON(http.StatusOK, result)
@@ -134,6 +181,39 @@ func UpdateOrder() gin.HandlerFunc {
 			}
 			updatedObj = append(updatedObj, bson.E{Key: "table_id", Value: order.TableID})
 		}
+		if order.Allergies != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "allergies", Value: normalizeTags(order.Allergies)})
+		}
+		if order.ServerID != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "server_id", Value: order.ServerID})
+		}
+		if order.CustomerID != nil {
+			if _, err := findCustomer(curCtx, *order.CustomerID); err != nil {
+				respondError(ctx, err, "Error occured while fetching customer")
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "customer_id", Value: order.CustomerID})
+		}
+		if order.ContactName != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "contact_name", Value: order.ContactName})
+		}
+		if order.ContactPhone != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "contact_phone", Value: normalizePhone(*order.ContactPhone)})
+		}
+		if order.PickupTime != nil {
+			if err := checkPickupTime(order.PickupTime); err != nil {
+				respondError(ctx, err, "Order updation failed")
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "pickup_time", Value: order.PickupTime})
+		}
+		if order.Covers != nil {
+			if *order.Covers < 1 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "covers must be at least 1"})
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "covers", Value: order.Covers})
+		}
 
 		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
@@ -162,15 +242,22 @@ func UpdateOrder() gin.HandlerFunc {
 	}
 }
 
-func OrderItemOrderCreator(order models.Order) string {
-	curCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-
+// OrderItemOrderCreator inserts an order started by adding items to it.
+// Call it inside the unit of work that inserts the items.
+func OrderItemOrderCreator(curCtx context.Context, order models.Order) (string, error) {
 	order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.ID = primitive.NewObjectID()
 	order.OrderID = order.ID.Hex()
 
-	orderCollection.InsertOne(curCtx, order)
-	defer cancel()
-	return order.OrderID
+	if err := reserveSlot(curCtx, order); err != nil {
+		return "", err
+	}
+	if _, err := orderCollection.InsertOne(curCtx, order); err != nil {
+		return "", err
+	}
+	if err := publish(curCtx, events.OrderCreated, order.OrderID, order); err != nil {
+		return "", err
+	}
+	return order.OrderID, nil
 }
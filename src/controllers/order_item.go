@@ -2,8 +2,8 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	"infinity/rms/database"
+	"infinity/rms/events"
 	"infinity/rms/models"
 	"log"
 	"net/http"
@@ -17,8 +17,12 @@ import (
 )
 
 type OrderItemPack struct {
-	TableID    *string
-	OrderItems []models.OrderItem
+	OrderID      *string
+	TableID      *string
+	CustomerID   *string
+	ScheduledFor *time.Time
+	Allergies    []string
+	OrderItems   []models.OrderItem
 }
 
 // DB
@@ -86,7 +90,7 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 	defer cancel()
 
 	matchStage := bson.D{
-		{Key: "$match", Value: bson.D{{Key: "order_id", Value: id}}},
+		{Key: "$match", Value: bson.D{{Key: "order_id", Value: id}, {Key: "status", Value: bson.D{{Key: "$ne", Value: "VOIDED"}}}}},
 	}
 
 	foodLookupStage := bson.D{
@@ -205,14 +209,90 @@ func CreateOrderItem() gin.HandlerFunc {
 			return
 		}
 
+		// items for a takeaway or delivery order go onto that order
+		if orderItemPack.OrderID != nil {
+			existing, err := findOrder(curCtx, *orderItemPack.OrderID)
+			if err == nil && existing.Status != nil && contains(finishedOrderStatuses, *existing.Status) {
+				err = requestError{http.StatusConflict, "Order is closed, items can't be added"}
+			}
+			if err == nil {
+				_, err = ensureUnpaid(curCtx, existing.OrderID)
+			}
+			if err != nil {
+				respondError(ctx, err, "Error occured while fetching order")
+				return
+			}
+			order = existing
+		}
+
 		order.OrderDate, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.Allergies = normalizeTags(append(order.Allergies, orderItemPack.Allergies...))
+		if orderItemPack.CustomerID != nil {
+			allergies, err := customerAllergies(curCtx, *orderItemPack.CustomerID, order.Allergies)
+			if err != nil {
+				respondError(ctx, err, "Error occured while fetching customer")
+				return
+			}
+			order.Allergies = allergies
+			order.CustomerID = orderItemPack.CustomerID
+		}
+
+		warnings, err := allergenWarnings(curCtx, orderItemPack.OrderItems, order.Allergies)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while checking allergens",
+			})
+			return
+		}
+		if len(warnings) > 0 && allergenPolicyBlocks() {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":             "Order items conflict with declared allergies",
+				"allergen_warnings": warnings,
+			})
+			return
+		}
 
-		orderItemsToBeInserted := []interface{}{}
-		order.TableID = orderItemPack.TableID
-		order_id := OrderItemOrderCreator(order)
+		if len(orderItemPack.OrderItems) == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "order_items can't be empty",
+			})
+			return
+		}
+		if order.OrderID == "" {
+			order.TableID = orderItemPack.TableID
+			order.ServerID = currentUser(ctx)
+			order.ScheduledFor = orderItemPack.ScheduledFor
+			if err := scheduleOrder(curCtx, &order); err != nil {
+				respondError(ctx, err, "Failed to create an order")
+				return
+			}
+			if order.TableID != nil {
+				var table models.Table
+				if err := tableCollection.FindOne(curCtx, bson.M{"table_id": order.TableID}).Decode(&table); err == nil {
+					order.Covers = table.NumberOfGuests
+				}
+			}
+		}
+
+		// the kitchen and voids own these, whatever the client sent
+		for i := range orderItemPack.OrderItems {
+			item := &orderItemPack.OrderItems[i]
+			item.Status = ""
+			item.FiredAt = nil
+			item.BumpedAt = nil
+			item.VoidedAt = nil
+			item.VoidReason = ""
+			item.VoidedBy = ""
+		}
 
-		for _, orderItem := range orderItemPack.OrderItems {
-			orderItem.OrderID = order_id
+		if err := prepareKitchenItems(curCtx, order, orderItemPack.OrderItems); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching order items",
+			})
+			return
+		}
+		// every item is checked before anything is written
+		for i, orderItem := range orderItemPack.OrderItems {
 			validationErr := validate.Struct(orderItem)
 			if validationErr != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{
@@ -220,6 +300,12 @@ func CreateOrderItem() gin.HandlerFunc {
 				})
 				return
 			}
+			if orderItem.UnitPrice == nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": "unit_price is required",
+				})
+				return
+			}
 			orderItem.ID = primitive.NewObjectID()
 			orderItem.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -227,18 +313,53 @@ func CreateOrderItem() gin.HandlerFunc {
 
 			var num = toFixed(*orderItem.UnitPrice, 2)
 			orderItem.UnitPrice = &num
-			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+			orderItemPack.OrderItems[i] = orderItem
 		}
 
-		result, err := orderItemCollection.InsertMany(curCtx, orderItemsToBeInserted)
-		if err != nil {
-			msg := fmt.Sprintf("Failed to create order items")
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"error": msg,
+		// a new order and its items are created together or not at all
+		var result *mongo.InsertManyResult
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, tableIdsOf(order.TableID), func() error {
+				order_id := order.OrderID
+				if order_id == "" {
+					var err error
+					if order_id, err = OrderItemOrderCreator(sessCtx, order); err != nil {
+						return err
+					}
+				} else if len(order.Allergies) > 0 || orderItemPack.CustomerID != nil {
+					// an existing order keeps the allergies and customer it was given
+					update := bson.D{}
+					if len(order.Allergies) > 0 {
+						update = append(update, bson.E{Key: "$addToSet", Value: bson.D{{Key: "allergies", Value: bson.D{{Key: "$each", Value: order.Allergies}}}}})
+					}
+					if orderItemPack.CustomerID != nil {
+						update = append(update, bson.E{Key: "$set", Value: bson.D{{Key: "customer_id", Value: order.CustomerID}}})
+					}
+					if _, err := orderCollection.UpdateOne(sessCtx, bson.M{"order_id": order_id}, update); err != nil {
+						return err
+					}
+				}
+				orderItemsToBeInserted := []interface{}{}
+				for _, orderItem := range orderItemPack.OrderItems {
+					orderItem.OrderID = order_id
+					orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+				}
+
+				var err error
+				if result, err = orderItemCollection.InsertMany(sessCtx, orderItemsToBeInserted); err != nil {
+					return err
+				}
+				return publishItemsAdded(sessCtx, orderItemsToBeInserted)
 			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Failed to create order items")
 			return
 		}
-		ctx.JSON(http.StatusOK, result)
+		ctx.JSON(http.StatusOK, gin.H{
+			"InsertedIDs":       result.InsertedIDs,
+			"allergen_warnings": warnings,
+		})
 	}
 }
 
@@ -296,3 +417,71 @@ func UpdateOrderItem() gin.HandlerFunc {
 		ctx.JSON(http.StatusOK, result)
 	}
 }
+
+// VoidOrderItem takes an item off an unpaid order, its bill and the
+// kitchen's screens. The item stays on record with who voided it and why.
+func VoidOrderItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Reason string `json:"reason" validate:"required,max=200"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var orderItem models.OrderItem
+		err := orderItemCollection.FindOne(curCtx, bson.M{"order_item_id": ctx.Param("orderItem_id")}).Decode(&orderItem)
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Order item was not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order item"})
+			return
+		}
+		if _, err := ensureUnpaid(curCtx, orderItem.OrderID); err != nil {
+			respondError(ctx, err, "Error occured while fetching invoice")
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		orderItem.Status = "VOIDED"
+		orderItem.VoidedAt = &now
+		orderItem.VoidReason = body.Reason
+		orderItem.VoidedBy = ctx.GetString("uid")
+		orderItem.UpdatedAt = now
+
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			result, err := orderItemCollection.UpdateOne(sessCtx,
+				bson.M{"order_item_id": orderItem.OrderItemID, "status": bson.M{"$ne": "VOIDED"}},
+				bson.M{"$set": bson.M{
+					"status":      orderItem.Status,
+					"voided_at":   now,
+					"void_reason": orderItem.VoidReason,
+					"voided_by":   orderItem.VoidedBy,
+					"updated_at":  now,
+				}},
+			)
+			if err != nil {
+				return err
+			}
+			if result.MatchedCount == 0 {
+				return requestError{http.StatusConflict, "Order item is already voided"}
+			}
+			return publish(sessCtx, events.ItemVoided, orderItem.OrderItemID, orderItem)
+		})
+		if err != nil {
+			respondError(ctx, err, "Order Item updation failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, orderItem)
+	}
+}
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"infinity/rms/platform"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var platformItemCollection *mongo.Collection = database.OpenCollection(database.Client, "platformItem")
+
+var platforms = platform.FromEnv()
+
+// platformOrder builds our order and its items from an external order.
+// Every unit becomes its own order item, as on the floor.
+func platformOrder(curCtx context.Context, external platform.ExternalOrder) (models.Order, []interface{}, error) {
+	ids := []string{}
+	for _, item := range external.Items {
+		ids = append(ids, item.ExternalID)
+	}
+	cursor, err := platformItemCollection.Find(curCtx, bson.M{"platform": external.Platform, "external_item_id": bson.M{"$in": ids}})
+	if err != nil {
+		return models.Order{}, nil, err
+	}
+	var mappings []models.PlatformItem
+	if err = cursor.All(curCtx, &mappings); err != nil {
+		return models.Order{}, nil, err
+	}
+	mapped := map[string]models.PlatformItem{}
+	for _, mapping := range mappings {
+		mapped[*mapping.ExternalItemID] = mapping
+	}
+
+	unmapped := []string{}
+	for _, item := range external.Items {
+		if _, ok := mapped[item.ExternalID]; !ok {
+			unmapped = append(unmapped, item.ExternalID+" ("+item.Name+")")
+		}
+	}
+	if len(unmapped) > 0 {
+		return models.Order{}, nil, requestError{http.StatusUnprocessableEntity, fmt.Sprintf("Unmapped items: %s", strings.Join(unmapped, ", "))}
+	}
+	if len(external.Items) == 0 {
+		return models.Order{}, nil, requestError{http.StatusBadRequest, "Order has no items"}
+	}
+
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	status := "RECEIVED"
+	phone := normalizePhone(external.Customer.Phone)
+	order := models.Order{
+		ID:              primitive.NewObjectID(),
+		OrderDate:       now,
+		Type:            external.Type,
+		ContactName:     &external.Customer.Name,
+		ContactPhone:    &phone,
+		PickupTime:      external.PickupTime,
+		Status:          &status,
+		StatusHistory:   []models.OrderStatusChange{{Status: status, At: time.Now()}},
+		Platform:        &external.Platform,
+		ExternalOrderID: &external.ExternalID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	order.OrderID = order.ID.Hex()
+	if external.Notes != "" {
+		order.Notes = &external.Notes
+	}
+	if external.Address != nil && external.Type == "DELIVERY" {
+		order.DeliveryAddress = &models.DeliveryAddress{
+			Line1:      external.Address.Line1,
+			Line2:      external.Address.Line2,
+			City:       external.Address.City,
+			PostalCode: external.Address.PostalCode,
+			Notes:      external.Address.Notes,
+		}
+	}
+	if external.DeliveryFee > 0 {
+		fee := toFixed(external.DeliveryFee, 2)
+		order.DeliveryFee = &fee
+	}
+	if phone != "" {
+		customer := models.Customer{Phone: &phone}
+		if existing, err := findDuplicate(curCtx, customer, ""); err == nil && existing != nil {
+			order.CustomerID = &existing.CustomerID
+			order.Allergies, _ = customerAllergies(curCtx, existing.CustomerID, nil)
+		}
+	}
+
+	items := []models.OrderItem{}
+	for _, item := range external.Items {
+		mapping := mapped[item.ExternalID]
+		size := "M"
+		if mapping.Quantity != nil {
+			size = *mapping.Quantity
+		}
+		price := toFixed(item.UnitPrice, 2)
+		for i := 0; i < item.Quantity || i == 0; i++ {
+			orderItem := models.OrderItem{
+				ID:        primitive.NewObjectID(),
+				Quantity:  &size,
+				UnitPrice: &price,
+				FoodID:    mapping.FoodID,
+				OrderID:   order.OrderID,
+				Source:    external.Platform,
+				Notes:     item.Notes,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			orderItem.OrderItemID = orderItem.ID.Hex()
+			items = append(items, orderItem)
+		}
+	}
+	if err := prepareKitchenItems(curCtx, order, items); err != nil {
+		return models.Order{}, nil, err
+	}
+	documents := []interface{}{}
+	for _, item := range items {
+		documents = append(documents, item)
+	}
+	return order, documents, nil
+}
+
+// ReceivePlatformOrder is the webhook delivery apps post new orders to.
+// Redelivered orders are acknowledged without creating them twice.
+func ReceivePlatformOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		integration, ok := platforms[ctx.Param("platform")]
+		if !ok {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Platform is not configured"})
+			return
+		}
+		body, err := ctx.GetRawData()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := integration.Adapter.Verify(ctx.Request.Header, body, integration.Secret); err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		external, err := integration.Adapter.Parse(body)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var order models.Order
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			err := orderCollection.FindOne(sessCtx, bson.M{"platform": external.Platform, "external_order_id": external.ExternalID}).Decode(&order)
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				return err
+			}
+
+			var items []interface{}
+			if order, items, err = platformOrder(sessCtx, external); err != nil {
+				return err
+			}
+			if _, err := orderCollection.InsertOne(sessCtx, order); err != nil {
+				return err
+			}
+			if _, err = orderItemCollection.InsertMany(sessCtx, items); err != nil {
+				return err
+			}
+			if err := publish(sessCtx, events.OrderCreated, order.OrderID, order); err != nil {
+				return err
+			}
+			return publishItemsAdded(sessCtx, items)
+		})
+		if err != nil {
+			log.Printf("platform %s order %s: %v", external.Platform, external.ExternalID, err)
+			respondError(ctx, err, "Failed to create an order")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": order.OrderID, "external_order_id": external.ExternalID})
+	}
+}
+
+// pushPlatformStatus is the bus subscriber reporting a status change back
+// to the app the order came from. A platform that is down gets the change
+// again when the event is retried.
+func pushPlatformStatus(curCtx context.Context, event events.Event) error {
+	var change orderStatusChange
+	if err := event.Decode(&change); err != nil {
+		return err
+	}
+	if change.Platform == nil || change.ExternalOrderID == nil {
+		return nil
+	}
+	integration, ok := platforms[*change.Platform]
+	if !ok || integration.Client == nil {
+		return nil
+	}
+	return integration.Client.UpdateStatus(curCtx, *change.ExternalOrderID, change.Status)
+}
+
+func GetPlatformItems() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := platformItemCollection.Find(curCtx, bson.M{"platform": ctx.Param("platform")})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching platform items",
+			})
+			return
+		}
+
+		var allItems []bson.M
+		if err = result.All(curCtx, &allItems); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allItems)
+	}
+}
+
+// SavePlatformItem maps an external item id to a food, replacing any
+// earlier mapping of that id
+func SavePlatformItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var item models.PlatformItem
+
+		if err := ctx.BindJSON(&item); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(item)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		count, err := foodCollection.CountDocuments(curCtx, bson.M{"food_id": item.FoodID})
+		if err != nil || count == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Food was not found",
+			})
+			return
+		}
+
+		item.Platform = ctx.Param("platform")
+		item.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		filter := bson.M{"platform": item.Platform, "external_item_id": item.ExternalItemID}
+		result, err := platformItemCollection.UpdateOne(curCtx, filter, bson.M{
+			"$set": bson.M{
+				"food_id":    item.FoodID,
+				"quantity":   item.Quantity,
+				"updated_at": item.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{
+				"_id":        primitive.NewObjectID(),
+				"created_at": item.UpdatedAt,
+			},
+		}, options.Update().SetUpsert(true))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Platform item was not saved",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func DeletePlatformItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := platformItemCollection.DeleteOne(curCtx, bson.M{
+			"platform":         ctx.Param("platform"),
+			"external_item_id": ctx.Param("external_item_id"),
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Platform item deletion failed",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
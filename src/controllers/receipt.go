@@ -0,0 +1,275 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ReceiptLine struct {
+	FoodID   string  `json:"food_id"`
+	FoodName string  `json:"food_name"`
+	Quantity string  `json:"quantity"`
+	Amount   float64 `json:"amount"`
+}
+
+type Receipt struct {
+	Locale        string                   `json:"locale"`
+	InvoiceID     string                   `json:"invoice_id"`
+	OrderID       string                   `json:"order_id"`
+	TableNumber   *int                     `json:"table_number,omitempty"`
+	Date          time.Time                `json:"date"`
+	PaymentMethod string                   `json:"payment_method"`
+	PaymentStatus string                   `json:"payment_status"`
+	Lines         []ReceiptLine            `json:"lines"`
+	Subtotal      float64                  `json:"subtotal"`
+	Discounts     []models.InvoiceDiscount `json:"discounts,omitempty"`
+	Total         float64                  `json:"total"`
+	Labels        gin.H                    `json:"labels"`
+}
+
+func GetReceipt() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		locales := requestLocales(ctx)
+
+		var invoice models.Invoice
+		err := invoiceCollection.FindOne(curCtx, bson.M{"invoice_id": ctx.Param("invoice_id")}).Decode(&invoice)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice was not found"})
+			return
+		}
+
+		receipt := Receipt{
+			Locale:    locales[0],
+			InvoiceID: invoice.InvoiceId,
+			OrderID:   invoice.OrderId,
+			Date:      invoice.CreatedAt,
+		}
+		if invoice.PaymentMethod != nil {
+			receipt.PaymentMethod = helpers.Translate(*invoice.PaymentMethod, locales)
+		}
+		if invoice.PaymentStatus != nil {
+			receipt.PaymentStatus = helpers.Translate(*invoice.PaymentStatus, locales)
+		}
+
+		var order models.Order
+		if err := orderCollection.FindOne(curCtx, bson.M{"order_id": invoice.OrderId}).Decode(&order); err == nil && order.TableID != nil {
+			var table models.Table
+			if err := tableCollection.FindOne(curCtx, bson.M{"table_id": order.TableID}).Decode(&table); err == nil {
+				receipt.TableNumber = table.TableNumber
+			}
+		}
+
+		receipt.Lines, receipt.Subtotal, err = receiptLines(curCtx, invoice.OrderId, locales)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching invoice"})
+			return
+		}
+		receipt.Discounts = invoice.Discounts
+		receipt.Total = toFixed(receipt.Subtotal-discountTotal(invoice), 2)
+
+		receipt.Labels = gin.H{}
+		for _, label := range []string{"Receipt", "Table", "Date", "Subtotal", "Discount", "Total", "Payment", "Status", "Thank you for your visit"} {
+			receipt.Labels[label] = helpers.Translate(label, locales)
+		}
+
+		if ctx.Query("format") == "text" {
+			ctx.String(http.StatusOK, receipt.Text())
+			return
+		}
+		ctx.JSON(http.StatusOK, receipt)
+	}
+}
+
+func receiptLines(curCtx context.Context, orderId string, locales []string) ([]ReceiptLine, float64, error) {
+	cursor, err := orderItemCollection.Find(curCtx, bson.M{"order_id": orderId, "status": bson.M{"$ne": "VOIDED"}})
+	if err != nil {
+		return nil, 0, err
+	}
+	var orderItems []models.OrderItem
+	if err = cursor.All(curCtx, &orderItems); err != nil {
+		return nil, 0, err
+	}
+
+	lines := []ReceiptLine{}
+	total := 0.0
+	packaging := 0.0
+	for _, orderItem := range orderItems {
+		line := ReceiptLine{}
+		if orderItem.Quantity != nil {
+			line.Quantity = *orderItem.Quantity
+		}
+		if orderItem.FoodID != nil {
+			line.FoodID = *orderItem.FoodID
+			var food models.Food
+			if err := foodCollection.FindOne(curCtx, bson.M{"food_id": orderItem.FoodID}).Decode(&food); err == nil {
+				if food.Name != nil {
+					line.FoodName = helpers.Localize(food.NameTranslations, *food.Name, locales)
+				}
+				if food.Price != nil {
+					line.Amount = *food.Price
+				}
+				if food.PackagingFee != nil {
+					packaging += *food.PackagingFee
+				}
+			}
+		}
+		if orderItem.UnitPrice != nil {
+			line.Amount = *orderItem.UnitPrice
+		}
+		total += line.Amount
+		lines = append(lines, line)
+	}
+
+	var order models.Order
+	if err := orderCollection.FindOne(curCtx, bson.M{"order_id": orderId}).Decode(&order); err != nil || !offPremises(order) {
+		return lines, toFixed(total, 2), nil
+	}
+	charges := []ReceiptLine{}
+	if packaging > 0 {
+		charges = append(charges, ReceiptLine{FoodName: helpers.Translate("Packaging", locales), Amount: toFixed(packaging, 2)})
+	}
+	if fee := deliveryFee(order, total); fee > 0 {
+		charges = append(charges, ReceiptLine{FoodName: helpers.Translate("Delivery fee", locales), Amount: fee})
+	}
+	for _, charge := range charges {
+		total += charge.Amount
+		lines = append(lines, charge)
+	}
+	return lines, toFixed(total, 2), nil
+}
+
+// orderTotal adds up an order's items the way receiptLines does: the
+// price an item was ordered at or else its food's price, plus packaging
+// and the delivery fee on orders that leave the restaurant
+func orderTotal(order models.Order, orderItems []models.OrderItem, foods map[string]models.Food) float64 {
+	total := 0.0
+	packaging := 0.0
+	for _, orderItem := range orderItems {
+		amount := 0.0
+		if orderItem.FoodID != nil {
+			if food, ok := foods[*orderItem.FoodID]; ok {
+				if food.Price != nil {
+					amount = *food.Price
+				}
+				if food.PackagingFee != nil {
+					packaging += *food.PackagingFee
+				}
+			}
+		}
+		if orderItem.UnitPrice != nil {
+			amount = *orderItem.UnitPrice
+		}
+		total += amount
+	}
+	if !offPremises(order) {
+		return toFixed(total, 2)
+	}
+	return toFixed(total+toFixed(packaging, 2)+deliveryFee(order, total), 2)
+}
+
+// orderTotals is orderTotal for many orders, with their orders, items and
+// foods loaded in one query each
+func orderTotals(curCtx context.Context, orderIds []string) (map[string]float64, error) {
+	totals := map[string]float64{}
+	if len(orderIds) == 0 {
+		return totals, nil
+	}
+	cursor, err := orderCollection.Find(curCtx, bson.M{"order_id": bson.M{"$in": orderIds}})
+	if err != nil {
+		return nil, err
+	}
+	var orders []models.Order
+	if err = cursor.All(curCtx, &orders); err != nil {
+		return nil, err
+	}
+	byId := map[string]models.Order{}
+	for _, order := range orders {
+		byId[order.OrderID] = order
+	}
+
+	cursor, err = orderItemCollection.Find(curCtx, bson.M{"order_id": bson.M{"$in": orderIds}, "status": bson.M{"$ne": "VOIDED"}})
+	if err != nil {
+		return nil, err
+	}
+	var orderItems []models.OrderItem
+	if err = cursor.All(curCtx, &orderItems); err != nil {
+		return nil, err
+	}
+	itemsOf := map[string][]models.OrderItem{}
+	foodIds := []string{}
+	for _, orderItem := range orderItems {
+		itemsOf[orderItem.OrderID] = append(itemsOf[orderItem.OrderID], orderItem)
+		if orderItem.FoodID != nil && !contains(foodIds, *orderItem.FoodID) {
+			foodIds = append(foodIds, *orderItem.FoodID)
+		}
+	}
+
+	cursor, err = foodCollection.Find(curCtx, bson.M{"food_id": bson.M{"$in": foodIds}})
+	if err != nil {
+		return nil, err
+	}
+	var foods []models.Food
+	if err = cursor.All(curCtx, &foods); err != nil {
+		return nil, err
+	}
+	foodsById := map[string]models.Food{}
+	for _, food := range foods {
+		foodsById[food.FoodId] = food
+	}
+
+	for _, orderId := range orderIds {
+		totals[orderId] = orderTotal(byId[orderId], itemsOf[orderId], foodsById)
+	}
+	return totals, nil
+}
+
+// formatAmount uses a decimal comma for the locales that expect one
+func formatAmount(amount float64, locale string) string {
+	text := fmt.Sprintf("%.2f", amount)
+	switch locale {
+	case "fr", "es", "de", "it", "pt", "nl":
+		text = strings.Replace(text, ".", ",", 1)
+	}
+	return text
+}
+
+func (receipt Receipt) Text() string {
+	var b strings.Builder
+	label := func(key string) string {
+		value, _ := receipt.Labels[key].(string)
+		return value
+	}
+
+	fmt.Fprintf(&b, "%s %s\n", strings.ToUpper(label("Receipt")), receipt.InvoiceID)
+	if receipt.TableNumber != nil {
+		fmt.Fprintf(&b, "%s: %d\n", label("Table"), *receipt.TableNumber)
+	}
+	fmt.Fprintf(&b, "%s: %s\n\n", label("Date"), receipt.Date.Format("2006-01-02 15:04"))
+
+	for _, line := range receipt.Lines {
+		fmt.Fprintf(&b, "%-2s %-28s %10s\n", line.Quantity, line.FoodName, formatAmount(line.Amount, receipt.Locale))
+	}
+	if len(receipt.Discounts) > 0 {
+		fmt.Fprintf(&b, "\n%-31s %10s\n", label("Subtotal"), formatAmount(receipt.Subtotal, receipt.Locale))
+		for _, discount := range receipt.Discounts {
+			fmt.Fprintf(&b, "%-31s %10s\n", label("Discount"), formatAmount(-discount.Amount, receipt.Locale))
+		}
+	}
+	fmt.Fprintf(&b, "\n%-31s %10s\n", strings.ToUpper(label("Total")), formatAmount(receipt.Total, receipt.Locale))
+	if receipt.PaymentMethod != "" {
+		fmt.Fprintf(&b, "%s: %s\n", label("Payment"), receipt.PaymentMethod)
+	}
+	fmt.Fprintf(&b, "%s: %s\n\n%s\n", label("Status"), receipt.PaymentStatus, label("Thank you for your visit"))
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"testing"
+
+	"infinity/rms/models"
+)
+
+func float(value float64) *float64 {
+	return &value
+}
+
+func TestOrderTotal(t *testing.T) {
+	soup, bread := "soup", "bread"
+	foods := map[string]models.Food{
+		soup:  {FoodId: soup, Price: float(4.5), PackagingFee: float(0.25)},
+		bread: {FoodId: bread, Price: float(2), PackagingFee: float(0.1)},
+	}
+	items := []models.OrderItem{
+		{FoodID: &soup, UnitPrice: float(4)},
+		{FoodID: &bread},
+	}
+
+	cases := []struct {
+		name  string
+		order models.Order
+		want  float64
+	}{
+		{"dine in", models.Order{Type: "DINE_IN"}, 6},
+		{"takeaway adds packaging", models.Order{Type: "TAKEAWAY"}, 6.35},
+		{"delivery adds the fee", models.Order{Type: "DELIVERY", DeliveryFee: float(3)}, 9.35},
+		{"free delivery", models.Order{Type: "DELIVERY", DeliveryFee: float(3), FreeDeliveryAbove: float(5)}, 6.35},
+	}
+	for _, c := range cases {
+		if got := orderTotal(c.order, items, foods); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
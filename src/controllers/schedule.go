@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// heldOrders are scheduled orders the kitchen hasn't been given yet
+var heldOrders = bson.M{"scheduled_for": bson.M{"$ne": nil}, "released_at": nil}
+
+// releasedOrders is the opposite of heldOrders, including every order that
+// was never scheduled
+var releasedOrders = bson.A{bson.M{"scheduled_for": nil}, bson.M{"released_at": bson.M{"$ne": nil}}}
+
+// schedulingRules reads SCHEDULE_LEAD_MINUTES (how long before its time an
+// order goes to the kitchen), SCHEDULE_SLOT_MINUTES and
+// SCHEDULE_SLOT_CAPACITY (orders per slot, 0 for no limit)
+func schedulingRules() (time.Duration, time.Duration, int) {
+	lead, err := strconv.Atoi(os.Getenv("SCHEDULE_LEAD_MINUTES"))
+	if err != nil || lead < 0 {
+		lead = 30
+	}
+	slot, err := strconv.Atoi(os.Getenv("SCHEDULE_SLOT_MINUTES"))
+	if err != nil || slot < 1 {
+		slot = 15
+	}
+	capacity, err := strconv.Atoi(os.Getenv("SCHEDULE_SLOT_CAPACITY"))
+	if err != nil || capacity < 0 {
+		capacity = 10
+	}
+	return time.Duration(lead) * time.Minute, time.Duration(slot) * time.Minute, capacity
+}
+
+// slotStart counts slots from local midnight so they line up with the clock
+func slotStart(t time.Time, slot time.Duration) time.Time {
+	t = t.In(time.Local)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	return midnight.Add(t.Sub(midnight) / slot * slot)
+}
+
+// scheduleSlotCollection has a document per slot that orders for the slot
+// write to, so two of them can't both take its last place
+var scheduleSlotCollection *mongo.Collection = database.OpenCollection(database.Client, "scheduleSlot")
+
+func slotOrders(curCtx context.Context, start time.Time, slot time.Duration) (int64, error) {
+	return orderCollection.CountDocuments(curCtx, bson.M{
+		"scheduled_for": bson.M{"$gte": start, "$lt": start.Add(slot)},
+		"status":        bson.M{"$ne": "CANCELLED"},
+	})
+}
+
+// scheduleOrder checks a future order against its slot's capacity and
+// holds it until the lead time before it is due. The order still has to
+// reserveSlot in the unit that inserts it.
+func scheduleOrder(curCtx context.Context, order *models.Order) error {
+	if order.ScheduledFor == nil {
+		return nil
+	}
+	now := time.Now()
+	if order.ScheduledFor.Before(now) {
+		return requestError{http.StatusBadRequest, "scheduled_for must be in the future"}
+	}
+	if err := slotHasRoom(curCtx, *order.ScheduledFor); err != nil {
+		return err
+	}
+
+	lead, _, _ := schedulingRules()
+	order.OrderDate = *order.ScheduledFor
+	if offPremises(*order) && order.PickupTime == nil {
+		order.PickupTime = order.ScheduledFor
+	}
+	if !order.ScheduledFor.After(now.Add(lead)) {
+		released, _ := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+		order.ReleasedAt = &released
+	}
+	return nil
+}
+
+func slotHasRoom(curCtx context.Context, scheduledFor time.Time) error {
+	_, slot, capacity := schedulingRules()
+	if capacity == 0 {
+		return nil
+	}
+	start := slotStart(scheduledFor, slot)
+	count, err := slotOrders(curCtx, start, slot)
+	if err != nil {
+		return err
+	}
+	if count >= int64(capacity) {
+		return requestError{http.StatusConflict, fmt.Sprintf("The %s slot is full", start.Format("15:04"))}
+	}
+	return nil
+}
+
+// reserveSlot counts the slot again inside the unit inserting the order.
+// Writing the slot's document first makes concurrent units for the same
+// slot conflict, so the one retried sees the other's order.
+func reserveSlot(sessCtx context.Context, order models.Order) error {
+	_, slot, capacity := schedulingRules()
+	if order.ScheduledFor == nil || capacity == 0 {
+		return nil
+	}
+	start := slotStart(*order.ScheduledFor, slot)
+	_, err := scheduleSlotCollection.UpdateOne(sessCtx, bson.M{"_id": start.Unix()},
+		bson.M{"$inc": bson.M{"reservations": 1}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	return slotHasRoom(sessCtx, *order.ScheduledFor)
+}
+
+// ReleaseScheduledOrders hands held orders to the kitchen once they are
+// within the lead time. It runs in the background from main.
+func ReleaseScheduledOrders(curCtx context.Context) error {
+	lead, _, _ := schedulingRules()
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	cursor, err := orderCollection.Find(curCtx, bson.M{"scheduled_for": bson.M{"$ne": nil, "$lte": now.Add(lead)}, "released_at": nil})
+	if err != nil {
+		return err
+	}
+	var orders []models.Order
+	if err = cursor.All(curCtx, &orders); err != nil {
+		return err
+	}
+	// one order that can't be released doesn't hold back the others
+	released := 0
+	for _, order := range orders {
+		if err := releaseOrder(curCtx, order, now); err != nil {
+			log.Printf("releasing scheduled order %s: %v", order.OrderID, err)
+			continue
+		}
+		released++
+	}
+	if released > 0 {
+		log.Printf("released %d scheduled orders to the kitchen", released)
+	}
+	return nil
+}
+
+// releaseOrder marks the order released and fires what is due together,
+// so an order is never released without its items reaching the kitchen
+func releaseOrder(curCtx context.Context, order models.Order, now time.Time) error {
+	return database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+		result, err := orderCollection.UpdateOne(sessCtx, bson.M{"order_id": order.OrderID, "released_at": nil}, bson.M{
+			"$set": bson.M{"released_at": now, "updated_at": now},
+		})
+		if err != nil || result.ModifiedCount == 0 {
+			return err
+		}
+		order.ReleasedAt = &now
+		return fireOpenCourse(sessCtx, order)
+	})
+}
+
+func GetScheduledOrders() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		opts := options.Find().SetSort(bson.D{{Key: "scheduled_for", Value: 1}})
+		result, err := orderCollection.Find(curCtx, heldOrders, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching orders",
+			})
+			return
+		}
+
+		var allOrders []bson.M
+		if err = result.All(curCtx, &allOrders); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allOrders)
+	}
+}
+
+// GetOrderSlots shows how full each slot of a day is, from now on when the
+// day is today
+func GetOrderSlots() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		day := time.Now().In(time.Local)
+		if date := ctx.Query("date"); date != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "date must look like 2006-01-02"})
+				return
+			}
+			day = parsed
+		}
+		_, slot, capacity := schedulingRules()
+		from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+		to := from.AddDate(0, 0, 1)
+		if now := slotStart(time.Now(), slot); now.After(from) {
+			from = now
+		}
+
+		cursor, err := orderCollection.Find(curCtx, bson.M{
+			"scheduled_for": bson.M{"$gte": from, "$lt": to},
+			"status":        bson.M{"$ne": "CANCELLED"},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+			return
+		}
+		var orders []models.Order
+		if err = cursor.All(curCtx, &orders); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+			return
+		}
+		counts := map[int64]int{}
+		for _, order := range orders {
+			counts[slotStart(*order.ScheduledFor, slot).Unix()]++
+		}
+
+		slots := []gin.H{}
+		for start := from; start.Before(to); start = start.Add(slot) {
+			count := counts[start.Unix()]
+			entry := gin.H{"start": start, "end": start.Add(slot), "orders": count}
+			if capacity > 0 {
+				entry["capacity"] = capacity
+				entry["available"] = count < capacity
+			} else {
+				entry["available"] = true
+			}
+			slots = append(slots, entry)
+		}
+		ctx.JSON(http.StatusOK, slots)
+	}
+}
+
+// ReleaseOrder sends a held order to the kitchen ahead of its lead time
+func ReleaseOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		order, err := findOrder(curCtx, ctx.Param("order_id"))
+		if err != nil {
+			respondError(ctx, err, "Error occured while fetching order")
+			return
+		}
+		if order.ScheduledFor == nil || order.ReleasedAt != nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Order is not waiting to be released"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := releaseOrder(curCtx, order, now); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Order updation failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": order.OrderID, "released_at": now})
+	}
+}
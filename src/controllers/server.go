@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var sectionAssignmentCollection *mongo.Collection = database.OpenCollection(database.Client, "sectionAssignment")
+
+type ServerSales struct {
+	ServerID      string   `json:"server_id"`
+	Name          string   `json:"name"`
+	Orders        int      `json:"orders"`
+	Covers        int      `json:"covers"`
+	Sales         float64  `json:"sales"`
+	Tips          float64  `json:"tips"`
+	AverageCheck  float64  `json:"average_check"`
+	SalesPerCover float64  `json:"sales_per_cover"`
+	TipPercent    *float64 `json:"tip_percent"`
+}
+
+// currentUser is the uid set by middleware.Auth
+func currentUser(ctx *gin.Context) *string {
+	uid := ctx.GetString("uid")
+	if uid == "" {
+		return nil
+	}
+	return &uid
+}
+
+// activeSections lists the sections the server is assigned to right now
+func activeSections(curCtx context.Context, serverId string, at time.Time) ([]string, error) {
+	cursor, err := sectionAssignmentCollection.Find(curCtx, bson.M{
+		"server_id":  serverId,
+		"start_time": bson.M{"$lte": at},
+		"end_time":   bson.M{"$gt": at},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var assignments []models.SectionAssignment
+	if err = cursor.All(curCtx, &assignments); err != nil {
+		return nil, err
+	}
+	sections := []string{}
+	for _, assignment := range assignments {
+		sections = append(sections, *assignment.Section)
+	}
+	return sections, nil
+}
+
+// serverForTable is whoever the table was handed to, or else whoever has
+// its section on the current shift
+func serverForTable(curCtx context.Context, table models.Table, at time.Time) *string {
+	if table.ServerID != nil {
+		return table.ServerID
+	}
+	if table.Section == nil {
+		return nil
+	}
+	var assignment models.SectionAssignment
+	err := sectionAssignmentCollection.FindOne(curCtx, bson.M{
+		"section":    table.Section,
+		"start_time": bson.M{"$lte": at},
+		"end_time":   bson.M{"$gt": at},
+	}).Decode(&assignment)
+	if err != nil {
+		return nil
+	}
+	return assignment.ServerID
+}
+
+// myTablesFilter matches the tables handed to the user and the tables in
+// the sections they are working
+func myTablesFilter(curCtx context.Context, uid string) (bson.M, error) {
+	sections, err := activeSections(curCtx, uid, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"server_id": uid},
+		bson.M{"server_id": nil, "section": bson.M{"$in": sections}},
+	}}, nil
+}
+
+func GetSectionAssignments() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+		filter := bson.M{"start_time": bson.M{"$lt": to}, "end_time": bson.M{"$gt": from}}
+		if serverId := ctx.Query("server_id"); serverId != "" {
+			filter["server_id"] = serverId
+		}
+		if section := ctx.Query("section"); section != "" {
+			filter["section"] = section
+		}
+
+		result, err := sectionAssignmentCollection.Find(curCtx, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching section assignments",
+			})
+			return
+		}
+
+		var allAssignments []bson.M
+		if err = result.All(curCtx, &allAssignments); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allAssignments)
+	}
+}
+
+// CreateSectionAssignment gives a server a section for a shift. When a
+// shift_id is given the times default to the shift's.
+func CreateSectionAssignment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var assignment models.SectionAssignment
+
+		if err := ctx.BindJSON(&assignment); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(assignment)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		count, err := userCollection.CountDocuments(curCtx, bson.M{"user_id": assignment.ServerID})
+		if err != nil || count == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "User was not found",
+			})
+			return
+		}
+
+		if assignment.ShiftID != nil {
+			var shift models.Shift
+			err := shiftCollection.FindOne(curCtx, bson.M{"shift_id": assignment.ShiftID}).Decode(&shift)
+			if err != nil {
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"error": "Shift was not found",
+				})
+				return
+			}
+			// the shift has to be the server's own
+			var staff models.Staff
+			err = staffCollection.FindOne(curCtx, bson.M{"staff_id": shift.StaffID}).Decode(&staff)
+			if err != nil || staff.UserID == nil || *staff.UserID != *assignment.ServerID {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": "Shift belongs to someone else",
+				})
+				return
+			}
+			if assignment.StartTime == nil {
+				assignment.StartTime = shift.StartTime
+			}
+			if assignment.EndTime == nil {
+				assignment.EndTime = shift.EndTime
+			}
+		}
+		if assignment.StartTime == nil || assignment.EndTime == nil || !assignment.EndTime.After(*assignment.StartTime) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "start_time and end_time are required and end_time must be after start_time",
+			})
+			return
+		}
+
+		// one server per section at a time
+		overlapping, err := sectionAssignmentCollection.CountDocuments(curCtx, bson.M{
+			"section":    assignment.Section,
+			"start_time": bson.M{"$lt": assignment.EndTime},
+			"end_time":   bson.M{"$gt": assignment.StartTime},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching section assignments",
+			})
+			return
+		}
+		if overlapping > 0 {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": "Section is already assigned for part of this time",
+			})
+			return
+		}
+
+		assignment.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		assignment.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		assignment.ID = primitive.NewObjectID()
+		assignment.AssignmentID = assignment.ID.Hex()
+
+		result, insertErr := sectionAssignmentCollection.InsertOne(curCtx, assignment)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Section assignment was not created",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func DeleteSectionAssignment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := sectionAssignmentCollection.DeleteOne(curCtx, bson.M{"assignment_id": ctx.Param("assignment_id")})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Section assignment deletion failed",
+			})
+			return
+		}
+		if result.DeletedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Section assignment was not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// GetServerReport sums the paid invoices in the range by the server of
+// their order
+func GetServerReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+
+		invoices, err := paidInvoices(curCtx, from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching invoices"})
+			return
+		}
+
+		orderIds := []string{}
+		for _, invoice := range invoices {
+			orderIds = append(orderIds, invoice.Invoice.OrderId)
+		}
+		orders := map[string]models.Order{}
+		if len(orderIds) > 0 {
+			cursor, err := orderCollection.Find(curCtx, bson.M{"order_id": bson.M{"$in": orderIds}})
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+				return
+			}
+			var allOrders []models.Order
+			if err = cursor.All(curCtx, &allOrders); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching orders"})
+				return
+			}
+			for _, order := range allOrders {
+				orders[order.OrderID] = order
+			}
+		}
+
+		servers := map[string]*ServerSales{}
+		for _, invoice := range invoices {
+			order := orders[invoice.Invoice.OrderId]
+			serverId := ""
+			if order.ServerID != nil {
+				serverId = *order.ServerID
+			}
+			sales, ok := servers[serverId]
+			if !ok {
+				sales = &ServerSales{ServerID: serverId}
+				servers[serverId] = sales
+			}
+			sales.Orders++
+			if order.Covers != nil {
+				sales.Covers += *order.Covers
+			}
+			sales.Sales += invoice.Total
+			if invoice.Invoice.Tip != nil {
+				sales.Tips += *invoice.Invoice.Tip
+			}
+		}
+
+		serverIds := []string{}
+		for serverId := range servers {
+			if serverId != "" {
+				serverIds = append(serverIds, serverId)
+			}
+		}
+		names, err := userNames(curCtx, serverIds)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching users"})
+			return
+		}
+
+		report := []ServerSales{}
+		for serverId, sales := range servers {
+			sales.Name = names[serverId]
+			if serverId == "" {
+				sales.Name = "Unassigned"
+			}
+			sales.AverageCheck = toFixed(sales.Sales/float64(sales.Orders), 2)
+			if sales.Covers > 0 {
+				sales.SalesPerCover = toFixed(sales.Sales/float64(sales.Covers), 2)
+			}
+			sales.TipPercent = percentOf(sales.Tips, sales.Sales)
+			sales.Sales = toFixed(sales.Sales, 2)
+			sales.Tips = toFixed(sales.Tips, 2)
+			report = append(report, *sales)
+		}
+		sort.Slice(report, func(i, j int) bool { return report[i].Sales > report[j].Sales })
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"from":    dayKey(from),
+			"to":      dayKey(to.AddDate(0, 0, -1)),
+			"servers": report,
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var staffCollection *mongo.Collection = database.OpenCollection(database.Client, "staff")
+var shiftCollection *mongo.Collection = database.OpenCollection(database.Client, "shift")
+
+func GetStaff() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if role := ctx.Query("role"); role != "" {
+			filter["role"] = role
+		}
+		if ctx.Query("active") == "true" {
+			filter["active"] = bson.M{"$ne": false}
+		}
+
+		result, err := staffCollection.Find(curCtx, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching staff",
+			})
+			return
+		}
+
+		var allStaff []bson.M
+		if err = result.All(curCtx, &allStaff); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allStaff)
+	}
+}
+
+func GetStaffMember() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var staff models.Staff
+		err := staffCollection.FindOne(curCtx, bson.M{"staff_id": ctx.Param("staff_id")}).Decode(&staff)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Staff member was not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, staff)
+	}
+}
+
+func CreateStaff() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var staff models.Staff
+
+		if err := ctx.BindJSON(&staff); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(staff)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		if staff.Active == nil {
+			active := true
+			staff.Active = &active
+		}
+		staff.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		staff.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		staff.ID = primitive.NewObjectID()
+		staff.StaffID = staff.ID.Hex()
+
+		result, insertErr := staffCollection.InsertOne(curCtx, staff)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Staff member was not created",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateStaff() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var staff models.Staff
+
+		if err := ctx.BindJSON(&staff); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// only validate the fields that are being changed
+		fields := []string{}
+		if staff.FirstName != nil {
+			fields = append(fields, "FirstName")
+		}
+		if staff.LastName != nil {
+			fields = append(fields, "LastName")
+		}
+		if staff.Role != nil {
+			fields = append(fields, "Role")
+		}
+		if staff.HourlyRate != nil {
+			fields = append(fields, "HourlyRate")
+		}
+		if staff.Email != nil {
+			fields = append(fields, "Email")
+		}
+		if len(fields) > 0 {
+			if validationErr := validate.StructPartial(staff, fields...); validationErr != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": validationErr.Error(),
+				})
+				return
+			}
+		}
+		var updatedObj primitive.D
+
+		if staff.FirstName != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "first_name", Value: staff.FirstName})
+		}
+		if staff.LastName != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "last_name", Value: staff.LastName})
+		}
+		if staff.Role != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "role", Value: staff.Role})
+		}
+		if staff.HourlyRate != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "hourly_rate", Value: staff.HourlyRate})
+		}
+		if staff.Phone != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "phone", Value: staff.Phone})
+		}
+		if staff.Email != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "email", Value: staff.Email})
+		}
+		if staff.UserID != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "user_id", Value: staff.UserID})
+		}
+		if staff.Active != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "active", Value: staff.Active})
+		}
+
+		staff.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: staff.UpdatedAt})
+
+		filter := bson.M{"staff_id": ctx.Param("staff_id")}
+		result, err := staffCollection.UpdateOne(curCtx, filter, bson.D{
+			{Key: "$set", Value: updatedObj},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Staff updation failed",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// dateRange reads the from and to query dates (2006-01-02, both
+// inclusive) and returns them as a half open range. It defaults to the
+// last seven days.
+func dateRange(ctx *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -7)
+	if value := ctx.Query("from"); value != "" {
+		day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return from, to, err
+		}
+		from = day
+	}
+	if value := ctx.Query("to"); value != "" {
+		day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return from, to, err
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
+
+func GetShifts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+		filter := bson.M{"start_time": bson.M{"$gte": from, "$lt": to}}
+		if staffId := ctx.Query("staff_id"); staffId != "" {
+			filter["staff_id"] = staffId
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}})
+		result, err := shiftCollection.Find(curCtx, filter, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error occured while fetching shifts",
+			})
+			return
+		}
+
+		var allShifts []bson.M
+		if err = result.All(curCtx, &allShifts); err != nil {
+			log.Fatal(err)
+		}
+		ctx.JSON(http.StatusOK, allShifts)
+	}
+}
+
+func CreateShift() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var shift models.Shift
+
+		if err := ctx.BindJSON(&shift); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		validationErr := validate.Struct(shift)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		var staff models.Staff
+		if err := staffCollection.FindOne(curCtx, bson.M{"staff_id": shift.StaffID}).Decode(&staff); err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Staff member was not found",
+			})
+			return
+		}
+		if shift.Role == nil {
+			shift.Role = staff.Role
+		}
+
+		shift.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		shift.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		shift.ID = primitive.NewObjectID()
+		shift.ShiftID = shift.ID.Hex()
+
+		result, insertErr := shiftCollection.InsertOne(curCtx, shift)
+		if insertErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Shift was not created",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateShift() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var shift models.Shift
+
+		if err := ctx.BindJSON(&shift); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var current models.Shift
+		filter := bson.M{"shift_id": ctx.Param("shift_id")}
+		if err := shiftCollection.FindOne(curCtx, filter).Decode(&current); err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Shift was not found",
+			})
+			return
+		}
+
+		var updatedObj primitive.D
+
+		if shift.StaffID != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "staff_id", Value: shift.StaffID})
+		}
+		if shift.Role != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "role", Value: shift.Role})
+		}
+		if shift.StartTime != nil {
+			current.StartTime = shift.StartTime
+			updatedObj = append(updatedObj, bson.E{Key: "start_time", Value: shift.StartTime})
+		}
+		if shift.EndTime != nil {
+			current.EndTime = shift.EndTime
+			updatedObj = append(updatedObj, bson.E{Key: "end_time", Value: shift.EndTime})
+		}
+		if !current.EndTime.After(*current.StartTime) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "end_time must be after start_time",
+			})
+			return
+		}
+		if shift.Notes != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "notes", Value: shift.Notes})
+		}
+
+		shift.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: shift.UpdatedAt})
+
+		result, err := shiftCollection.UpdateOne(curCtx, filter, bson.D{
+			{Key: "$set", Value: updatedObj},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Shift updation failed",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func DeleteShift() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := shiftCollection.DeleteOne(curCtx, bson.M{"shift_id": ctx.Param("shift_id")})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Shift deletion failed",
+			})
+			return
+		}
+		if result.DeletedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "Shift was not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
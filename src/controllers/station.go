@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/models"
+	"infinity/rms/notify"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// foods without a station are cooked on the main line
+const defaultStation = "KITCHEN"
+
+type StationTicketItem struct {
+	OrderItemID string    `json:"order_item_id"`
+	FoodID      string    `json:"food_id"`
+	FoodName    string    `json:"food_name"`
+	Quantity    string    `json:"quantity"`
+	Course      string    `json:"course,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+	FiredAt     time.Time `json:"fired_at"`
+	PrepMinutes int       `json:"prep_minutes"`
+}
+
+type StationTicket struct {
+	OrderID        string              `json:"order_id"`
+	Station        string              `json:"station"`
+	TableNumber    *int                `json:"table_number,omitempty"`
+	OrderType      string              `json:"order_type,omitempty"`
+	FiredAt        time.Time           `json:"fired_at"`
+	ElapsedMinutes float64             `json:"elapsed_minutes"`
+	TargetMinutes  int                 `json:"target_minutes"`
+	Overdue        bool                `json:"overdue"`
+	Items          []StationTicketItem `json:"items"`
+}
+
+type PrepTimes struct {
+	Key            string   `json:"key"`
+	Name           string   `json:"name,omitempty"`
+	Count          int      `json:"count"`
+	AverageMinutes float64  `json:"average_minutes"`
+	P90Minutes     float64  `json:"p90_minutes"`
+	OverSLA        int      `json:"over_sla"`
+	OverSLAPercent *float64 `json:"over_sla_percent"`
+	durations      []float64
+}
+
+// defaultPrepMinutes is the target for foods without their own,
+// KITCHEN_PREP_MINUTES
+func defaultPrepMinutes() int {
+	minutes, err := strconv.Atoi(os.Getenv("KITCHEN_PREP_MINUTES"))
+	if err != nil || minutes < 1 {
+		minutes = 15
+	}
+	return minutes
+}
+
+func normalizeStation(station string) string {
+	station = strings.ToUpper(strings.TrimSpace(station))
+	if station == "" {
+		return defaultStation
+	}
+	return station
+}
+
+func routeItem(item *models.OrderItem, food models.Food) {
+	item.Station = defaultStation
+	if food.Station != nil {
+		item.Station = normalizeStation(*food.Station)
+	}
+	item.PrepMinutes = defaultPrepMinutes()
+	if food.PrepMinutes != nil {
+		item.PrepMinutes = *food.PrepMinutes
+	}
+}
+
+func stationOf(item models.OrderItem) string {
+	return normalizeStation(item.Station)
+}
+
+func stationFilter(station string) interface{} {
+	if station == defaultStation {
+		return bson.M{"$in": bson.A{defaultStation, nil}}
+	}
+	return station
+}
+
+func targetMinutes(item models.OrderItem) int {
+	if item.PrepMinutes > 0 {
+		return item.PrepMinutes
+	}
+	return defaultPrepMinutes()
+}
+
+// cookingItems are fired and not yet bumped, oldest first
+func cookingItems(curCtx context.Context, filter bson.M) ([]models.OrderItem, error) {
+	filter["bumped_at"] = nil
+	filter["status"] = bson.M{"$nin": bson.A{"PENDING_CONFIRMATION", "VOIDED"}}
+	filter["$or"] = bson.A{bson.M{"fired_at": bson.M{"$ne": nil}}, bson.M{"course": nil}}
+	opts := options.Find().SetSort(bson.D{{Key: "fired_at", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := orderItemCollection.Find(curCtx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var items []models.OrderItem
+	err = cursor.All(curCtx, &items)
+	return items, err
+}
+
+func firedAt(item models.OrderItem) time.Time {
+	if item.FiredAt != nil {
+		return *item.FiredAt
+	}
+	return item.CreatedAt
+}
+
+// stationTickets splits cooking items into one ticket per order and
+// station. A ticket is late once its slowest item's target has passed.
+func stationTickets(curCtx context.Context, items []models.OrderItem) ([]StationTicket, error) {
+	tickets := []StationTicket{}
+	index := map[string]int{}
+	now := time.Now()
+
+	names := map[string]string{}
+	for _, item := range items {
+		if item.FoodID == nil {
+			continue
+		}
+		if _, ok := names[*item.FoodID]; ok {
+			continue
+		}
+		var food models.Food
+		if err := foodCollection.FindOne(curCtx, bson.M{"food_id": item.FoodID}).Decode(&food); err == nil && food.Name != nil {
+			names[*item.FoodID] = *food.Name
+		}
+	}
+
+	for _, item := range items {
+		key := item.OrderID + "/" + stationOf(item)
+		i, ok := index[key]
+		if !ok {
+			i = len(tickets)
+			index[key] = i
+			tickets = append(tickets, StationTicket{OrderID: item.OrderID, Station: stationOf(item), FiredAt: firedAt(item)})
+		}
+		ticket := &tickets[i]
+		line := StationTicketItem{
+			OrderItemID: item.OrderItemID,
+			Course:      item.Course,
+			Notes:       item.Notes,
+			FiredAt:     firedAt(item),
+			PrepMinutes: targetMinutes(item),
+		}
+		if item.FoodID != nil {
+			line.FoodID = *item.FoodID
+			line.FoodName = names[*item.FoodID]
+		}
+		if item.Quantity != nil {
+			line.Quantity = *item.Quantity
+		}
+		if line.FiredAt.Before(ticket.FiredAt) {
+			ticket.FiredAt = line.FiredAt
+		}
+		if line.PrepMinutes > ticket.TargetMinutes {
+			ticket.TargetMinutes = line.PrepMinutes
+		}
+		ticket.Items = append(ticket.Items, line)
+	}
+
+	released := []StationTicket{}
+	for i := range tickets {
+		var order models.Order
+		if err := orderCollection.FindOne(curCtx, bson.M{"order_id": tickets[i].OrderID}).Decode(&order); err == nil {
+			if order.ScheduledFor != nil && order.ReleasedAt == nil {
+				continue
+			}
+			if offPremises(order) {
+				tickets[i].OrderType = order.Type
+			}
+			if order.TableID != nil {
+				var table models.Table
+				if err := tableCollection.FindOne(curCtx, bson.M{"table_id": order.TableID}).Decode(&table); err == nil {
+					tickets[i].TableNumber = table.TableNumber
+				}
+			}
+		}
+		tickets[i].ElapsedMinutes = toFixed(now.Sub(tickets[i].FiredAt).Minutes(), 1)
+		tickets[i].Overdue = tickets[i].ElapsedMinutes > float64(tickets[i].TargetMinutes)
+		released = append(released, tickets[i])
+	}
+	return released, nil
+}
+
+// GetStationTickets is the screen for one prep station, oldest ticket first.
+// ?overdue=true leaves only the late ones.
+func GetStationTickets() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		station := normalizeStation(ctx.Param("station"))
+		items, err := cookingItems(curCtx, bson.M{"station": stationFilter(station)})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+		tickets, err := stationTickets(curCtx, items)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+		if ctx.Query("overdue") == "true" {
+			late := []StationTicket{}
+			for _, ticket := range tickets {
+				if ticket.Overdue {
+					late = append(late, ticket)
+				}
+			}
+			tickets = late
+		}
+		ctx.JSON(http.StatusOK, tickets)
+	}
+}
+
+// GetKitchenAlerts lists the late tickets across every station
+func GetKitchenAlerts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		items, err := cookingItems(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+		tickets, err := stationTickets(curCtx, items)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+		late := []StationTicket{}
+		for _, ticket := range tickets {
+			if ticket.Overdue {
+				late = append(late, ticket)
+			}
+		}
+		ctx.JSON(http.StatusOK, late)
+	}
+}
+
+// AlertLateTickets tells the kitchen, once per ticket, when a ticket runs
+// past its target. KITCHEN_ALERT_TO names who gets the message. It runs in
+// the background from main.
+func AlertLateTickets(curCtx context.Context) error {
+	items, err := cookingItems(curCtx, bson.M{"sla_alerted_at": nil})
+	if err != nil {
+		return err
+	}
+	tickets, err := stationTickets(curCtx, items)
+	if err != nil {
+		return err
+	}
+
+	to := os.Getenv("KITCHEN_ALERT_TO")
+	if to == "" {
+		to = "kitchen"
+	}
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	for _, ticket := range tickets {
+		if !ticket.Overdue {
+			continue
+		}
+		where := "order " + ticket.OrderID
+		if ticket.TableNumber != nil {
+			where = fmt.Sprintf("table %d", *ticket.TableNumber)
+		}
+		err := notifier.Notify(curCtx, notify.Message{
+			To:      to,
+			Subject: ticket.Station + " ticket is late",
+			Body:    fmt.Sprintf("The %s ticket for %s has been cooking for %.0f minutes, the target is %d.", ticket.Station, where, ticket.ElapsedMinutes, ticket.TargetMinutes),
+		})
+		if err != nil {
+			return err
+		}
+		ids := []string{}
+		for _, item := range ticket.Items {
+			ids = append(ids, item.OrderItemID)
+		}
+		if _, err := orderItemCollection.UpdateMany(curCtx, bson.M{"order_item_id": bson.M{"$in": ids}}, bson.M{
+			"$set": bson.M{"sla_alerted_at": now},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func summarizePrepTimes(groups map[string]*PrepTimes) []PrepTimes {
+	summary := []PrepTimes{}
+	for _, group := range groups {
+		sort.Float64s(group.durations)
+		total := 0.0
+		for _, minutes := range group.durations {
+			total += minutes
+		}
+		group.AverageMinutes = toFixed(total/float64(group.Count), 1)
+		group.P90Minutes = toFixed(percentile(group.durations, 0.9), 1)
+		group.OverSLAPercent = percentOf(float64(group.OverSLA), float64(group.Count))
+		summary = append(summary, *group)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].P90Minutes > summary[j].P90Minutes })
+	return summary
+}
+
+// GetKitchenReport gives average and p90 times from fire to bump per
+// station and per food, for items bumped between from and to
+func GetKitchenReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+
+		cursor, err := orderItemCollection.Find(curCtx, bson.M{
+			"bumped_at": bson.M{"$gte": from, "$lt": to},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+		var items []models.OrderItem
+		if err = cursor.All(curCtx, &items); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching order items"})
+			return
+		}
+
+		stations := map[string]*PrepTimes{}
+		foods := map[string]*PrepTimes{}
+		add := func(groups map[string]*PrepTimes, key string, minutes float64, late bool) {
+			group, ok := groups[key]
+			if !ok {
+				group = &PrepTimes{Key: key}
+				groups[key] = group
+			}
+			group.Count++
+			group.durations = append(group.durations, minutes)
+			if late {
+				group.OverSLA++
+			}
+		}
+		for _, item := range items {
+			minutes := item.BumpedAt.Sub(firedAt(item)).Minutes()
+			if minutes < 0 {
+				continue
+			}
+			late := minutes > float64(targetMinutes(item))
+			add(stations, stationOf(item), minutes, late)
+			if item.FoodID != nil {
+				add(foods, *item.FoodID, minutes, late)
+			}
+		}
+
+		byFood := summarizePrepTimes(foods)
+		for i := range byFood {
+			var food models.Food
+			if err := foodCollection.FindOne(curCtx, bson.M{"food_id": byFood[i].Key}).Decode(&food); err == nil && food.Name != nil {
+				byFood[i].Name = *food.Name
+			}
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"from":     from,
+			"to":       to,
+			"stations": summarizePrepTimes(stations),
+			"foods":    byFood,
+		})
+	}
+}
@@ -21,8 +21,19 @@ var tableCollection *mongo.Collection = database.OpenCollection(database.Client,
 func GetTables() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		c, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := tableCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
+		filter := bson.M{}
+		if ctx.Query("mine") == "true" {
+			mine, err := myTablesFilter(c, ctx.GetString("uid"))
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Error occured while fetching section assignments",
+				})
+				return
+			}
+			filter = mine
+		}
+		result, err := tableCollection.Find(context.TODO(), filter)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Error occured while fetching all tables",
@@ -85,7 +96,7 @@ func CreateTable() gin.HandlerFunc {
 		table.ID = primitive.NewObjectID()
 		table.TableID = table.ID.Hex()
 
-		result, insertErr := orderCollection.InsertOne(curCtx, table)
+		result, insertErr := tableCollection.InsertOne(curCtx, table)
 		if insertErr != nil {
 			msg := fmt.Sprintf("Failed to create a table item")
 			ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -122,6 +133,36 @@ func UpdateTable() gin.HandlerFunc {
 			updatedObj = append(updatedObj, bson.E{Key: "table_number", Value: table.TableNumber})
 		}
 
+		validationErr := validate.Struct(table)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": validationErr.Error(),
+			})
+			return
+		}
+
+		if table.AreaID != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "area_id", Value: table.AreaID})
+		}
+		if table.Section != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "section", Value: table.Section})
+		}
+		if table.X != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "x", Value: table.X})
+		}
+		if table.Y != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "y", Value: table.Y})
+		}
+		if table.Shape != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "shape", Value: table.Shape})
+		}
+		if table.Seats != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "seats", Value: table.Seats})
+		}
+		if table.ServerID != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "server_id", Value: table.ServerID})
+		}
+
 		table.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: table.UpdatedAt})
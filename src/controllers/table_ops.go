@@ -0,0 +1,463 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// requestError carries the status code out of a transaction callback
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e requestError) Error() string {
+	return e.message
+}
+
+func respondError(ctx *gin.Context, err error, fallback string) {
+	var reqErr requestError
+	if errors.As(err, &reqErr) {
+		ctx.JSON(reqErr.status, gin.H{"error": reqErr.message})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+func findOrder(curCtx context.Context, orderId string) (models.Order, error) {
+	var order models.Order
+	err := orderCollection.FindOne(curCtx, bson.M{"order_id": orderId}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return order, requestError{http.StatusNotFound, "Order was not found"}
+	}
+	return order, err
+}
+
+func findTable(curCtx context.Context, tableId string) (models.Table, error) {
+	var table models.Table
+	err := tableCollection.FindOne(curCtx, bson.M{"table_id": tableId}).Decode(&table)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return table, requestError{http.StatusNotFound, "Table was not found"}
+	}
+	return table, err
+}
+
+func findOrderInvoice(curCtx context.Context, orderId string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := invoiceCollection.FindOne(curCtx, bson.M{"order_id": orderId}).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// ensureUnpaid refuses to touch orders whose check is already settled
+func ensureUnpaid(curCtx context.Context, orderId string) (*models.Invoice, error) {
+	invoice, err := findOrderInvoice(curCtx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if invoice != nil && invoice.PaymentStatus != nil && *invoice.PaymentStatus == "PAID" {
+		return nil, requestError{http.StatusConflict, "Order is already paid"}
+	}
+	return invoice, nil
+}
+
+func MoveOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			TableID string `json:"table_id" validate:"required"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		orderId := ctx.Param("order_id")
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			order, err := findOrder(sessCtx, orderId)
+			if err != nil {
+				return err
+			}
+			if order.TableID != nil && *order.TableID == body.TableID {
+				return requestError{http.StatusBadRequest, "Order is already on that table"}
+			}
+			return updateTableStatus(sessCtx, tableIdsOf(order.TableID, &body.TableID), func() error {
+				return moveOrderToTable(sessCtx, order, body.TableID)
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Order move failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": orderId, "table_id": body.TableID})
+	}
+}
+
+func moveOrderToTable(sessCtx context.Context, order models.Order, tableId string) error {
+	if _, err := ensureUnpaid(sessCtx, order.OrderID); err != nil {
+		return err
+	}
+	if _, err := findTable(sessCtx, tableId); err != nil {
+		return err
+	}
+	open, err := openOrderForTable(sessCtx, tableId)
+	if err != nil {
+		return err
+	}
+	if open != nil {
+		return requestError{http.StatusConflict, "Table already has an open order, merge the tables instead"}
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	_, err = orderCollection.UpdateOne(sessCtx, bson.M{"order_id": order.OrderID}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "table_id", Value: tableId},
+			{Key: "updated_at", Value: updatedAt},
+		}},
+	})
+	return err
+}
+
+// MergeTables folds the source table's open order into the target table's
+// open order, so both parties share one check
+func MergeTables() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			SourceTableID string `json:"source_table_id" validate:"required"`
+			TargetTableID string `json:"target_table_id" validate:"required,nefield=SourceTableID"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var orderId string
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, []string{body.SourceTableID, body.TargetTableID}, func() error {
+				source, err := openOrderForTable(sessCtx, body.SourceTableID)
+				if err != nil {
+					return err
+				}
+				if source == nil {
+					return requestError{http.StatusNotFound, "Source table has no open order"}
+				}
+				target, err := openOrderForTable(sessCtx, body.TargetTableID)
+				if err != nil {
+					return err
+				}
+				if target == nil {
+					orderId = source.OrderID
+					return moveOrderToTable(sessCtx, *source, body.TargetTableID)
+				}
+				orderId = target.OrderID
+				return mergeOrders(sessCtx, *source, *target)
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Table merge failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": orderId, "table_id": body.TargetTableID})
+	}
+}
+
+func mergeOrders(sessCtx context.Context, source models.Order, target models.Order) error {
+	sourceInvoice, err := ensureUnpaid(sessCtx, source.OrderID)
+	if err != nil {
+		return err
+	}
+	targetInvoice, err := ensureUnpaid(sessCtx, target.OrderID)
+	if err != nil {
+		return err
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	// items keep their kitchen state, only the order they belong to changes
+	_, err = orderItemCollection.UpdateMany(sessCtx, bson.M{"order_id": source.OrderID}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "order_id", Value: target.OrderID},
+			{Key: "updated_at", Value: updatedAt},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	targetUpdate := bson.D{{Key: "$set", Value: bson.D{{Key: "updated_at", Value: updatedAt}}}}
+	if len(source.Allergies) > 0 {
+		targetUpdate = append(targetUpdate, bson.E{Key: "$addToSet", Value: bson.D{
+			{Key: "allergies", Value: bson.D{{Key: "$each", Value: source.Allergies}}},
+		}})
+	}
+	if _, err = orderCollection.UpdateOne(sessCtx, bson.M{"order_id": target.OrderID}, targetUpdate); err != nil {
+		return err
+	}
+
+	_, err = orderCollection.UpdateOne(sessCtx, bson.M{"order_id": source.OrderID}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "merged_into", Value: target.OrderID},
+			{Key: "updated_at", Value: updatedAt},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// one check per order: keep the target's invoice, or carry the
+	// source's over when the target has none yet. Both ways its tender and
+	// discounts stay on the check.
+	if sourceInvoice != nil {
+		if targetInvoice == nil {
+			_, err = invoiceCollection.UpdateOne(sessCtx, bson.M{"invoice_id": sourceInvoice.InvoiceId}, bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "order_id", Value: target.OrderID},
+					{Key: "updated_at", Value: updatedAt},
+				}},
+			})
+		} else {
+			err = mergeInvoices(sessCtx, *sourceInvoice, *targetInvoice, updatedAt)
+		}
+	}
+	return err
+}
+
+// mergeInvoices folds the source invoice into the target one. Gift card
+// payments and loyalty discounts on the source were already taken from
+// the card or the points balance, so they move over with their ledger
+// entries rather than being lost.
+func mergeInvoices(sessCtx context.Context, source models.Invoice, target models.Invoice, updatedAt time.Time) error {
+	set := bson.D{{Key: "updated_at", Value: updatedAt}}
+	if len(source.Discounts) > 0 && source.CustomerID != nil {
+		if target.CustomerID != nil && *target.CustomerID != *source.CustomerID {
+			return requestError{http.StatusConflict, "Both checks have loyalty discounts for different customers"}
+		}
+		set = append(set, bson.E{Key: "customer_id", Value: source.CustomerID})
+	}
+	update := bson.D{{Key: "$set", Value: set}}
+	push := bson.D{}
+	if len(source.Payments) > 0 {
+		push = append(push, bson.E{Key: "payments", Value: bson.D{{Key: "$each", Value: source.Payments}}})
+	}
+	if len(source.Discounts) > 0 {
+		push = append(push, bson.E{Key: "discounts", Value: bson.D{{Key: "$each", Value: source.Discounts}}})
+	}
+	if len(push) > 0 {
+		update = append(update, bson.E{Key: "$push", Value: push})
+	}
+	if _, err := invoiceCollection.UpdateOne(sessCtx, bson.M{"invoice_id": target.InvoiceId}, update); err != nil {
+		return err
+	}
+
+	moved := bson.M{"$set": bson.M{"invoice_id": target.InvoiceId}}
+	if _, err := giftCardTransactionCollection.UpdateMany(sessCtx, bson.M{"invoice_id": source.InvoiceId}, moved); err != nil {
+		return err
+	}
+	if _, err := loyaltyLedgerCollection.UpdateMany(sessCtx, bson.M{"invoice_id": source.InvoiceId}, moved); err != nil {
+		return err
+	}
+	_, err := invoiceCollection.DeleteOne(sessCtx, bson.M{"invoice_id": source.InvoiceId})
+	return err
+}
+
+// SplitOrder moves some items of an order onto a new order, on the same
+// table or another one; when the other table already has an open order
+// the items join it
+func SplitOrder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			OrderItemIDs []string `json:"order_item_ids" validate:"required,min=1"`
+			TableID      *string  `json:"table_id"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		orderId := ctx.Param("order_id")
+		var newOrderId string
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			order, err := findOrder(sessCtx, orderId)
+			if err != nil {
+				return err
+			}
+			return updateTableStatus(sessCtx, tableIdsOf(order.TableID, body.TableID), func() error {
+				if _, err := ensureUnpaid(sessCtx, orderId); err != nil {
+					return err
+				}
+
+				selected, err := orderItemCollection.CountDocuments(sessCtx, bson.M{
+					"order_id":      orderId,
+					"order_item_id": bson.M{"$in": body.OrderItemIDs},
+				})
+				if err != nil {
+					return err
+				}
+				if selected != int64(len(body.OrderItemIDs)) {
+					return requestError{http.StatusBadRequest, "Some order items do not belong to this order"}
+				}
+				total, err := orderItemCollection.CountDocuments(sessCtx, bson.M{"order_id": orderId})
+				if err != nil {
+					return err
+				}
+				if selected == total {
+					return requestError{http.StatusBadRequest, "Every item was selected, move the order instead"}
+				}
+
+				tableId := order.TableID
+				if body.TableID != nil && (order.TableID == nil || *body.TableID != *order.TableID) {
+					if _, err := findTable(sessCtx, *body.TableID); err != nil {
+						return err
+					}
+					open, err := openOrderForTable(sessCtx, *body.TableID)
+					if err != nil {
+						return err
+					}
+					if open != nil {
+						if _, err := ensureUnpaid(sessCtx, open.OrderID); err != nil {
+							return err
+						}
+						newOrderId = open.OrderID
+					}
+					tableId = body.TableID
+				}
+
+				updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				if newOrderId == "" {
+					var newOrder models.Order
+					newOrder.ID = primitive.NewObjectID()
+					newOrder.OrderID = newOrder.ID.Hex()
+					newOrder.TableID = tableId
+					newOrder.Allergies = order.Allergies
+					newOrder.ServerID = order.ServerID
+					newOrder.OrderDate = order.OrderDate
+					newOrder.CreatedAt = updatedAt
+					newOrder.UpdatedAt = updatedAt
+					if _, err := orderCollection.InsertOne(sessCtx, newOrder); err != nil {
+						return err
+					}
+					if err := publish(sessCtx, events.OrderCreated, newOrder.OrderID, newOrder); err != nil {
+						return err
+					}
+					newOrderId = newOrder.OrderID
+				}
+
+				_, err = orderItemCollection.UpdateMany(sessCtx, bson.M{
+					"order_id":      orderId,
+					"order_item_id": bson.M{"$in": body.OrderItemIDs},
+				}, bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "order_id", Value: newOrderId},
+						{Key: "updated_at", Value: updatedAt},
+					}},
+				})
+				return err
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Order split failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"order_id": orderId, "new_order_id": newOrderId})
+	}
+}
+
+// TransferTable hands a table over to another waiter
+func TransferTable() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			ServerID string `json:"server_id" validate:"required"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		tableId := ctx.Param("table_id")
+		err := database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, []string{tableId}, func() error {
+				if _, err := findTable(sessCtx, tableId); err != nil {
+					return err
+				}
+				count, err := userCollection.CountDocuments(sessCtx, bson.M{"user_id": body.ServerID})
+				if err != nil {
+					return err
+				}
+				if count == 0 {
+					return requestError{http.StatusNotFound, "User was not found"}
+				}
+
+				updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				_, err = tableCollection.UpdateOne(sessCtx, bson.M{"table_id": tableId}, bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "server_id", Value: body.ServerID},
+						{Key: "updated_at", Value: updatedAt},
+					}},
+				})
+				if err != nil {
+					return err
+				}
+
+				// the open check goes with the table
+				open, err := openOrderForTable(sessCtx, tableId)
+				if err != nil || open == nil {
+					return err
+				}
+				_, err = orderCollection.UpdateOne(sessCtx, bson.M{"order_id": open.OrderID}, bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "server_id", Value: body.ServerID},
+						{Key: "updated_at", Value: updatedAt},
+					}},
+				})
+				return err
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Table transfer failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"table_id": tableId, "server_id": body.ServerID})
+	}
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var timeEntryCollection *mongo.Collection = database.OpenCollection(database.Client, "timeEntry")
+
+func openTimeEntry(curCtx context.Context, staffId string) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := timeEntryCollection.FindOne(curCtx, bson.M{"staff_id": staffId, "clock_out": nil}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// workedTime is the time on the clock minus unpaid breaks. Open entries
+// and breaks count up to now.
+func workedTime(entry models.TimeEntry, now time.Time) time.Duration {
+	end := now
+	if entry.ClockOut != nil {
+		end = *entry.ClockOut
+	}
+	worked := end.Sub(entry.ClockIn)
+	for _, b := range entry.Breaks {
+		if b.Paid {
+			continue
+		}
+		breakEnd := end
+		if b.End != nil {
+			breakEnd = *b.End
+		}
+		worked -= breakEnd.Sub(b.Start)
+	}
+	if worked < 0 {
+		return 0
+	}
+	return worked
+}
+
+func ClockIn() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var staff models.Staff
+		err := staffCollection.FindOne(curCtx, bson.M{"staff_id": ctx.Param("staff_id")}).Decode(&staff)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Staff member was not found"})
+			return
+		}
+		if staff.Active != nil && !*staff.Active {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is not active"})
+			return
+		}
+
+		open, err := openTimeEntry(curCtx, staff.StaffID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+		if open != nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is already clocked in"})
+			return
+		}
+
+		var entry models.TimeEntry
+		entry.ClockIn, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.StaffID = staff.StaffID
+		entry.Breaks = []models.Break{}
+		if staff.HourlyRate != nil {
+			entry.HourlyRate = *staff.HourlyRate
+		}
+
+		// link to the scheduled shift this clock in belongs to, allowing
+		// for clocking in up to an hour early
+		var shift models.Shift
+		err = shiftCollection.FindOne(curCtx, bson.M{
+			"staff_id":   staff.StaffID,
+			"start_time": bson.M{"$lte": entry.ClockIn.Add(time.Hour)},
+			"end_time":   bson.M{"$gt": entry.ClockIn},
+		}).Decode(&shift)
+		if err == nil {
+			entry.ShiftID = &shift.ShiftID
+		}
+
+		entry.CreatedAt = entry.ClockIn
+		entry.UpdatedAt = entry.ClockIn
+		entry.ID = primitive.NewObjectID()
+		entry.TimeEntryID = entry.ID.Hex()
+
+		if _, err := timeEntryCollection.InsertOne(curCtx, entry); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Clock in failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, entry)
+	}
+}
+
+func ClockOut() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entry, err := openTimeEntry(curCtx, ctx.Param("staff_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+		if entry == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is not clocked in"})
+			return
+		}
+
+		clockOut, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		for i := range entry.Breaks {
+			if entry.Breaks[i].End == nil {
+				entry.Breaks[i].End = &clockOut
+			}
+		}
+		entry.ClockOut = &clockOut
+		entry.UpdatedAt = clockOut
+
+		_, err = timeEntryCollection.UpdateOne(curCtx, bson.M{"time_entry_id": entry.TimeEntryID}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "clock_out", Value: entry.ClockOut},
+				{Key: "breaks", Value: entry.Breaks},
+				{Key: "updated_at", Value: entry.UpdatedAt},
+			}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Clock out failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"entry":        entry,
+			"worked_hours": toFixed(workedTime(*entry, clockOut).Hours(), 2),
+		})
+	}
+}
+
+func StartBreak() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Paid bool `json:"paid"`
+		}
+		if ctx.Request.ContentLength > 0 {
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		entry, err := openTimeEntry(curCtx, ctx.Param("staff_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+		if entry == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is not clocked in"})
+			return
+		}
+		for _, b := range entry.Breaks {
+			if b.End == nil {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is already on a break"})
+				return
+			}
+		}
+
+		start, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.Breaks = append(entry.Breaks, models.Break{Start: start, Paid: body.Paid})
+		_, err = timeEntryCollection.UpdateOne(curCtx, bson.M{"time_entry_id": entry.TimeEntryID}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "breaks", Value: entry.Breaks},
+				{Key: "updated_at", Value: start},
+			}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Break could not be started"})
+			return
+		}
+		ctx.JSON(http.StatusOK, entry)
+	}
+}
+
+func EndBreak() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entry, err := openTimeEntry(curCtx, ctx.Param("staff_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+		if entry == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is not clocked in"})
+			return
+		}
+
+		end, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ended := false
+		for i := range entry.Breaks {
+			if entry.Breaks[i].End == nil {
+				entry.Breaks[i].End = &end
+				ended = true
+			}
+		}
+		if !ended {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Staff member is not on a break"})
+			return
+		}
+
+		_, err = timeEntryCollection.UpdateOne(curCtx, bson.M{"time_entry_id": entry.TimeEntryID}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "breaks", Value: entry.Breaks},
+				{Key: "updated_at", Value: end},
+			}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Break could not be ended"})
+			return
+		}
+		ctx.JSON(http.StatusOK, entry)
+	}
+}
+
+func GetTimeEntries() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := dateRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates like 2006-01-02"})
+			return
+		}
+		filter := bson.M{"clock_in": bson.M{"$gte": from, "$lt": to}}
+		if staffId := ctx.Query("staff_id"); staffId != "" {
+			filter["staff_id"] = staffId
+		}
+
+		opts := options.Find().SetSort(bson.D{{Key: "clock_in", Value: 1}})
+		result, err := timeEntryCollection.Find(curCtx, filter, opts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching time entries"})
+			return
+		}
+
+		var entries []models.TimeEntry
+		if err = result.All(curCtx, &entries); err != nil {
+			log.Fatal(err)
+		}
+
+		now := time.Now()
+		allEntries := []gin.H{}
+		for _, entry := range entries {
+			allEntries = append(allEntries, gin.H{
+				"entry":        entry,
+				"worked_hours": toFixed(workedTime(entry, now).Hours(), 2),
+			})
+		}
+		ctx.JSON(http.StatusOK, allEntries)
+	}
+}
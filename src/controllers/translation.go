@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/helpers"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type translatableEntity struct {
+	collection *mongo.Collection
+	idField    string
+	fields     []string
+}
+
+func translatableEntities() map[string]translatableEntity {
+	return map[string]translatableEntity{
+		"menus":     {menuCollection, "menu_id", []string{"name", "category"}},
+		"foods":     {foodCollection, "food_id", []string{"name"}},
+		"allergens": {allergenCollection, "allergen_id", []string{"name"}},
+	}
+}
+
+func requestLocales(ctx *gin.Context) []string {
+	if value, ok := ctx.Get("locales"); ok {
+		if locales, ok := value.([]string); ok {
+			return locales
+		}
+	}
+	return []string{helpers.DefaultLocale}
+}
+
+// localizeDoc swaps each field for its translation in place
+func localizeDoc(doc bson.M, locales []string, fields ...string) {
+	for _, field := range fields {
+		translations := map[string]string{}
+		switch value := doc[field+"_translations"].(type) {
+		case bson.M:
+			for locale, text := range value {
+				translations[locale], _ = text.(string)
+			}
+		case bson.D:
+			for _, e := range value {
+				translations[e.Key], _ = e.Value.(string)
+			}
+		}
+		fallback, _ := doc[field].(string)
+		doc[field] = helpers.Localize(translations, fallback, locales)
+	}
+}
+
+func GetTranslations() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entity, ok := translatableEntities()[ctx.Param("entity")]
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported entity"})
+			return
+		}
+
+		var doc bson.M
+		err := entity.collection.FindOne(curCtx, bson.M{entity.idField: ctx.Param("entity_id")}).Decode(&doc)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Error occured while fetching"})
+			return
+		}
+
+		translations := gin.H{}
+		for _, field := range entity.fields {
+			translations[field] = doc[field+"_translations"]
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"entity":       ctx.Param("entity"),
+			"id":           ctx.Param("entity_id"),
+			"translations": translations,
+		})
+	}
+}
+
+func SetTranslation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entity, ok := translatableEntities()[ctx.Param("entity")]
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported entity"})
+			return
+		}
+		locale, ok := supportedLocale(ctx.Param("locale"))
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported locale %s", ctx.Param("locale"))})
+			return
+		}
+
+		var body map[string]string
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj bson.D
+		for _, field := range entity.fields {
+			if text, ok := body[field]; ok && text != "" {
+				updateObj = append(updateObj, bson.E{Key: field + "_translations." + locale, Value: text})
+			}
+		}
+		if len(updateObj) == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "No translatable fields given"})
+			return
+		}
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updatedAt})
+
+		result, err := entity.collection.UpdateOne(curCtx,
+			bson.M{entity.idField: ctx.Param("entity_id")},
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Translation update failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Error occured while fetching"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func DeleteTranslation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entity, ok := translatableEntities()[ctx.Param("entity")]
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported entity"})
+			return
+		}
+		locale, ok := supportedLocale(ctx.Param("locale"))
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported locale %s", ctx.Param("locale"))})
+			return
+		}
+
+		var unset bson.D
+		for _, field := range entity.fields {
+			unset = append(unset, bson.E{Key: field + "_translations." + locale, Value: ""})
+		}
+
+		result, err := entity.collection.UpdateOne(curCtx,
+			bson.M{entity.idField: ctx.Param("entity_id")},
+			bson.D{{Key: "$unset", Value: unset}},
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Translation update failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Translation was not found"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+func supportedLocale(locale string) (string, bool) {
+	for _, supported := range helpers.SupportedLocales() {
+		if supported == locale {
+			return locale, true
+		}
+	}
+	return "", false
+}
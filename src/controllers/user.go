@@ -197,8 +197,14 @@ func LogIn() gin.HandlerFunc {
 			return
 		}
 
+		// restored users come back without a password until it is reset
+		if user.Password == nil || foundUser.Password == nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "login or password is incorrect"})
+			return
+		}
+
 		// verify password
-		passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
+		passwordIsValid, msg := VerifyPassword(*foundUser.Password, *user.Password)
 		defer cancel()
 		if passwordIsValid != true {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -225,7 +231,10 @@ func HashPassword(password string) string {
 	return string(bytes)
 }
 
-func VerifyPassword(userPassword string, providedPassword string) (bool, string) {
-	err := bcrypt.CompareHashAndPassword([]byte(userPassword), []byte(providedPassword))
-	return err == nil, err.Error()
+func VerifyPassword(hashedPassword string, providedPassword string) (bool, string) {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(providedPassword))
+	if err != nil {
+		return false, "login or password is incorrect"
+	}
+	return true, ""
 }
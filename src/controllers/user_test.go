@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, msg := VerifyPassword(string(hashed), "s3cret"); !ok || msg != "" {
+		t.Fatalf("right password: got %v %q", ok, msg)
+	}
+	if ok, msg := VerifyPassword(string(hashed), "guess"); ok || msg == "" {
+		t.Fatalf("wrong password: got %v %q", ok, msg)
+	}
+}
@@ -0,0 +1,637 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"infinity/rms/notify"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var waitlistCollection *mongo.Collection = database.OpenCollection(database.Client, "waitlist")
+
+var notifier notify.Notifier = notify.NewFromEnv()
+
+var activeWaitlist = bson.M{"status": bson.M{"$in": bson.A{"WAITING", "NOTIFIED"}}}
+
+// defaultTurnTime is used until there is enough history, DEFAULT_TURN_MINUTES
+func defaultTurnTime() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("DEFAULT_TURN_MINUTES"))
+	if err != nil || minutes < 1 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// averageTurnTime is how long tables stayed occupied over the last 30
+// days, from the order being opened to its invoice being paid
+func averageTurnTime(curCtx context.Context) (time.Duration, error) {
+	since := time.Now().AddDate(0, 0, -30)
+	cursor, err := invoiceCollection.Aggregate(curCtx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "payment_status", Value: "PAID"},
+			{Key: "updated_at", Value: bson.D{{Key: "$gte", Value: since}}},
+		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "order"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "order"},
+		}}},
+		{{Key: "$unwind", Value: "$order"}},
+		{{Key: "$match", Value: bson.D{{Key: "order.table_id", Value: bson.D{{Key: "$ne", Value: nil}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "turn_ms", Value: bson.D{{Key: "$avg", Value: bson.D{
+				{Key: "$subtract", Value: bson.A{"$updated_at", "$order.created_at"}},
+			}}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var result []struct {
+		TurnMs float64 `bson:"turn_ms"`
+		Count  int     `bson:"count"`
+	}
+	if err = cursor.All(curCtx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 || result[0].Count < 5 || result[0].TurnMs <= 0 {
+		return defaultTurnTime(), nil
+	}
+	return time.Duration(result[0].TurnMs) * time.Millisecond, nil
+}
+
+func tableFits(state TableState, partySize int) bool {
+	return state.Seats == nil || *state.Seats >= partySize
+}
+
+// estimateWait quotes a party of partySize that has `ahead` parties in
+// front of it. Occupied tables are expected to free up one turn time
+// after they were seated; each party ahead takes the next table to free
+// up.
+func estimateWait(states []TableState, turnTime time.Duration, partySize int, ahead int) int {
+	now := time.Now()
+	free := 0
+	remaining := []time.Duration{}
+	for _, state := range states {
+		if !tableFits(state, partySize) {
+			continue
+		}
+		switch state.Status {
+		case "AVAILABLE":
+			free++
+		case "NEEDS_CLEANING":
+			remaining = append(remaining, 5*time.Minute)
+		case "SEATED", "ORDERED", "AWAITING_PAYMENT":
+			left := turnTime
+			if state.SeatedAt != nil {
+				left = state.SeatedAt.Add(turnTime).Sub(now)
+			}
+			if left < 5*time.Minute {
+				left = 5 * time.Minute
+			}
+			remaining = append(remaining, left)
+		}
+	}
+
+	if ahead < free {
+		return 0
+	}
+	ahead -= free
+	if len(remaining) == 0 {
+		// nothing big enough is in use, fall back to whole turns
+		return int(turnTime.Minutes()) * (ahead + 1)
+	}
+	sort.Slice(remaining, func(i, j int) bool { return remaining[i] < remaining[j] })
+	wait := remaining[ahead%len(remaining)] + time.Duration(ahead/len(remaining))*turnTime
+	return int(wait.Minutes() + 0.5)
+}
+
+func floorSnapshot(curCtx context.Context) ([]TableState, time.Duration, error) {
+	cursor, err := tableCollection.Find(curCtx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+	var tables []models.Table
+	if err = cursor.All(curCtx, &tables); err != nil {
+		return nil, 0, err
+	}
+	states, err := tableStates(curCtx, tables)
+	if err != nil {
+		return nil, 0, err
+	}
+	turnTime, err := averageTurnTime(curCtx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return states, turnTime, nil
+}
+
+func activeEntries(curCtx context.Context) ([]models.WaitlistEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}})
+	cursor, err := waitlistCollection.Find(curCtx, activeWaitlist, opts)
+	if err != nil {
+		return nil, err
+	}
+	entries := []models.WaitlistEntry{}
+	if err = cursor.All(curCtx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// renumberWaitlist closes the gaps left by seated or cancelled parties
+func renumberWaitlist(curCtx context.Context) error {
+	entries, err := activeEntries(curCtx)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if entry.Position == i+1 {
+			continue
+		}
+		_, err := waitlistCollection.UpdateOne(curCtx, bson.M{"waitlist_id": entry.WaitlistID}, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "position", Value: i + 1}}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetWaitlist() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entries, err := activeEntries(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the waitlist"})
+			return
+		}
+		states, turnTime, err := floorSnapshot(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching table status"})
+			return
+		}
+
+		result := []gin.H{}
+		for i, entry := range entries {
+			result = append(result, gin.H{
+				"entry":                  entry,
+				"waited_minutes":         int(time.Since(entry.CreatedAt).Minutes()),
+				"estimated_wait_minutes": estimateWait(states, turnTime, *entry.PartySize, i),
+			})
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"turn_time_minutes": int(turnTime.Minutes()),
+			"parties":           result,
+		})
+	}
+}
+
+func GetWaitEstimate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		partySize, err := strconv.Atoi(ctx.Query("party_size"))
+		if err != nil || partySize < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "party_size is required"})
+			return
+		}
+		ahead, err := waitlistCollection.CountDocuments(curCtx, activeWaitlist)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the waitlist"})
+			return
+		}
+		states, turnTime, err := floorSnapshot(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching table status"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"party_size":             partySize,
+			"parties_ahead":          ahead,
+			"estimated_wait_minutes": estimateWait(states, turnTime, partySize, int(ahead)),
+		})
+	}
+}
+
+func CreateWaitlistEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		if err := ctx.BindJSON(&entry); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entry.Status = "WAITING"
+		if validationErr := validate.Struct(entry); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		ahead, err := waitlistCollection.CountDocuments(curCtx, activeWaitlist)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the waitlist"})
+			return
+		}
+		states, turnTime, err := floorSnapshot(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching table status"})
+			return
+		}
+
+		// link regulars by phone or email when the host didn't pick them
+		if entry.CustomerID == nil {
+			customer := models.Customer{Phone: entry.Phone, Email: entry.Email}
+			normalizeCustomer(&customer)
+			if existing, err := findDuplicate(curCtx, customer, ""); err == nil && existing != nil {
+				entry.CustomerID = &existing.CustomerID
+			}
+		} else if _, err := findCustomer(curCtx, *entry.CustomerID); err != nil {
+			respondError(ctx, err, "Error occured while fetching customer")
+			return
+		}
+
+		entry.Position = int(ahead) + 1
+		entry.QuotedWaitMinutes = estimateWait(states, turnTime, *entry.PartySize, int(ahead))
+		entry.TableID = nil
+		entry.OrderID = nil
+		entry.NotifiedAt = nil
+		entry.SeatedAt = nil
+		entry.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.ID = primitive.NewObjectID()
+		entry.WaitlistID = entry.ID.Hex()
+
+		if _, err := waitlistCollection.InsertOne(curCtx, entry); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Party was not added to the waitlist"})
+			return
+		}
+		ctx.JSON(http.StatusOK, entry)
+	}
+}
+
+func UpdateWaitlistEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		if err := ctx.BindJSON(&entry); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updatedObj primitive.D
+		if entry.PartyName != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "party_name", Value: entry.PartyName})
+		}
+		if entry.PartySize != nil {
+			if *entry.PartySize < 1 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be at least 1"})
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "party_size", Value: entry.PartySize})
+		}
+		if entry.Phone != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "phone", Value: entry.Phone})
+		}
+		if entry.Email != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "email", Value: entry.Email})
+		}
+		if entry.Notes != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "notes", Value: entry.Notes})
+		}
+		// seating goes through /seat so that the order gets created
+		switch entry.Status {
+		case "":
+		case "CANCELLED", "NO_SHOW", "WAITING":
+			updatedObj = append(updatedObj, bson.E{Key: "status", Value: entry.Status})
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Status can only be set to WAITING, CANCELLED or NO_SHOW"})
+			return
+		}
+
+		entry.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: entry.UpdatedAt})
+
+		result, err := waitlistCollection.UpdateOne(curCtx,
+			bson.M{"waitlist_id": ctx.Param("waitlist_id")},
+			bson.D{{Key: "$set", Value: updatedObj}},
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Waitlist updation failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Party was not found"})
+			return
+		}
+		if entry.Status != "" {
+			if err := renumberWaitlist(curCtx); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Waitlist updation failed"})
+				return
+			}
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// MoveWaitlistEntry puts a party at the given position, shifting the
+// parties in between
+func MoveWaitlistEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Position int `json:"position" validate:"required,min=1"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(body); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		waitlistId := ctx.Param("waitlist_id")
+		entries, err := activeEntries(curCtx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching the waitlist"})
+			return
+		}
+
+		index := -1
+		for i, entry := range entries {
+			if entry.WaitlistID == waitlistId {
+				index = i
+			}
+		}
+		if index < 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Party was not found"})
+			return
+		}
+
+		moved := entries[index]
+		entries = append(entries[:index], entries[index+1:]...)
+		target := body.Position - 1
+		if target > len(entries) {
+			target = len(entries)
+		}
+		entries = append(entries[:target], append([]models.WaitlistEntry{moved}, entries[target:]...)...)
+
+		for i, entry := range entries {
+			if entry.Position == i+1 {
+				continue
+			}
+			_, err := waitlistCollection.UpdateOne(curCtx, bson.M{"waitlist_id": entry.WaitlistID}, bson.D{
+				{Key: "$set", Value: bson.D{{Key: "position", Value: i + 1}}},
+			})
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Waitlist updation failed"})
+				return
+			}
+		}
+		ctx.JSON(http.StatusOK, gin.H{"waitlist_id": waitlistId, "position": target + 1})
+	}
+}
+
+func NotifyWaitlistEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		err := waitlistCollection.FindOne(curCtx, bson.M{"waitlist_id": ctx.Param("waitlist_id")}).Decode(&entry)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Party was not found"})
+			return
+		}
+		if entry.Status != "WAITING" && entry.Status != "NOTIFIED" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Party is no longer waiting"})
+			return
+		}
+
+		to := ""
+		if entry.Phone != nil {
+			to = *entry.Phone
+		} else if entry.Email != nil {
+			to = *entry.Email
+		}
+		if to == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Party has no phone or email"})
+			return
+		}
+
+		err = notifier.Notify(curCtx, notify.Message{
+			To:      to,
+			Subject: "Your table is ready",
+			Body:    fmt.Sprintf("Hi %s, your table for %d is ready. Please come to the host stand.", *entry.PartyName, *entry.PartySize),
+		})
+		if err != nil {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Notification failed: %s", err)})
+			return
+		}
+
+		notifiedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		// the party may have been seated or left while the message went out
+		result, err := waitlistCollection.UpdateOne(curCtx, bson.M{
+			"waitlist_id": entry.WaitlistID,
+			"status":      activeWaitlist["status"],
+		}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "NOTIFIED"},
+				{Key: "notified_at", Value: notifiedAt},
+				{Key: "updated_at", Value: notifiedAt},
+			}},
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Waitlist updation failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Party is no longer waiting"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"waitlist_id": entry.WaitlistID, "status": "NOTIFIED"})
+	}
+}
+
+// pickTable chooses the smallest available table the party fits at
+func pickTable(states []TableState, partySize int) *TableState {
+	var best *TableState
+	for i := range states {
+		state := &states[i]
+		if state.Status != "AVAILABLE" || !tableFits(*state, partySize) {
+			continue
+		}
+		if best == nil || (state.Seats != nil && (best.Seats == nil || *state.Seats < *best.Seats)) {
+			best = state
+		}
+	}
+	return best
+}
+
+// checkSeating refuses a table that pickTable wouldn't have chosen
+func checkSeating(state TableState, partySize int) error {
+	if state.Status != "AVAILABLE" {
+		return requestError{http.StatusConflict, "Table is not free"}
+	}
+	if !tableFits(state, partySize) {
+		return requestError{http.StatusConflict, "Table is too small for this party"}
+	}
+	return nil
+}
+
+// SeatWaitlistEntry seats the party at the given table, or the best free
+// one, and opens the order for that table
+func SeatWaitlistEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			TableID *string `json:"table_id"`
+		}
+		if ctx.Request.ContentLength > 0 {
+			if err := ctx.BindJSON(&body); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var entry models.WaitlistEntry
+		err := waitlistCollection.FindOne(curCtx, bson.M{
+			"waitlist_id": ctx.Param("waitlist_id"),
+			"status":      activeWaitlist["status"],
+		}).Decode(&entry)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Party was not found"})
+			return
+		}
+
+		tableId := ""
+		if body.TableID != nil {
+			tableId = *body.TableID
+		} else {
+			states, _, err := floorSnapshot(curCtx)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching table status"})
+				return
+			}
+			table := pickTable(states, *entry.PartySize)
+			if table == nil {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "No free table fits this party"})
+				return
+			}
+			tableId = table.TableID
+		}
+
+		var orderId string
+		err = database.UnitOfWork.Run(curCtx, func(sessCtx context.Context) error {
+			return updateTableStatus(sessCtx, []string{tableId}, func() error {
+				table, err := findTable(sessCtx, tableId)
+				if err != nil {
+					return err
+				}
+				if body.TableID != nil {
+					states, err := tableStatesByID(sessCtx, []string{tableId})
+					if err != nil {
+						return err
+					}
+					if err := checkSeating(states[tableId], *entry.PartySize); err != nil {
+						return err
+					}
+				}
+				open, err := openOrderForTable(sessCtx, tableId)
+				if err != nil {
+					return err
+				}
+				if open != nil {
+					return requestError{http.StatusConflict, "Table already has an open order"}
+				}
+
+				seatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				var order models.Order
+				order.ID = primitive.NewObjectID()
+				order.OrderID = order.ID.Hex()
+				order.TableID = &tableId
+				order.ServerID = serverForTable(sessCtx, table, seatedAt)
+				order.Covers = entry.PartySize
+				order.CustomerID = entry.CustomerID
+				if entry.CustomerID != nil {
+					if order.Allergies, err = customerAllergies(sessCtx, *entry.CustomerID, nil); err != nil {
+						return err
+					}
+				}
+				order.OrderDate = seatedAt
+				order.CreatedAt = seatedAt
+				order.UpdatedAt = seatedAt
+				if _, err := orderCollection.InsertOne(sessCtx, order); err != nil {
+					return err
+				}
+				if err := publish(sessCtx, events.OrderCreated, order.OrderID, order); err != nil {
+					return err
+				}
+				orderId = order.OrderID
+
+				_, err = tableCollection.UpdateOne(sessCtx, bson.M{"table_id": tableId}, bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "number_of_guests", Value: entry.PartySize},
+						{Key: "updated_at", Value: seatedAt},
+					}},
+				})
+				if err != nil {
+					return err
+				}
+
+				_, err = waitlistCollection.UpdateOne(sessCtx, bson.M{"waitlist_id": entry.WaitlistID}, bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "status", Value: "SEATED"},
+						{Key: "table_id", Value: tableId},
+						{Key: "order_id", Value: orderId},
+						{Key: "seated_at", Value: seatedAt},
+						{Key: "updated_at", Value: seatedAt},
+					}},
+				})
+				return err
+			})
+		})
+		if err != nil {
+			respondError(ctx, err, "Party could not be seated")
+			return
+		}
+		if err := renumberWaitlist(curCtx); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Waitlist updation failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"waitlist_id": entry.WaitlistID,
+			"table_id":    tableId,
+			"order_id":    orderId,
+		})
+	}
+}
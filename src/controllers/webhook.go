@@ -0,0 +1,548 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinity/rms/database"
+	"infinity/rms/events"
+	"infinity/rms/models"
+	"infinity/rms/webhook"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var webhookCollection *mongo.Collection = database.OpenCollection(database.Client, "webhook")
+var webhookDeliveryCollection *mongo.Collection = database.OpenCollection(database.Client, "webhookDelivery")
+
+var webhookSender = &webhook.Sender{}
+
+// webhookEventNames are the names webhooks know domain events by
+var webhookEventNames = map[string]string{
+	events.OrderCreated:       "order.created",
+	events.OrderStatusChanged: "order.status_changed",
+	events.OrderItemAdded:     "order_item.added",
+	events.ItemVoided:         "order_item.voided",
+	events.InvoicePaid:        "invoice.paid",
+	events.TableStatusChanged: "table.status_changed",
+}
+
+// webhookEvents are the events a webhook can subscribe to, besides "*"
+var webhookEvents = []string{"order.created", "order.status_changed", "order_item.added", "order_item.voided", "invoice.paid", "table.status_changed"}
+
+// a claimed delivery is left alone by other instances for this long
+const webhookLease = time.Minute
+
+// webhookRetryRules reads WEBHOOK_MAX_ATTEMPTS and WEBHOOK_RETRY_SECONDS,
+// the wait after the first failure. The wait doubles up to an hour.
+func webhookRetryRules() (int, time.Duration) {
+	attempts, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		attempts = 8
+	}
+	seconds, err := strconv.Atoi(os.Getenv("WEBHOOK_RETRY_SECONDS"))
+	if err != nil || seconds < 1 {
+		seconds = 30
+	}
+	return attempts, time.Duration(seconds) * time.Second
+}
+
+func checkWebhookEvents(names []string) error {
+	for _, event := range names {
+		if event != "*" && !contains(webhookEvents, event) {
+			return fmt.Errorf("unknown event %q, use one of %s or *", event, strings.Join(webhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// queueWebhooks is the bus subscriber turning domain events into
+// deliveries for every active webhook subscribed to them
+func queueWebhooks(curCtx context.Context, event events.Event) error {
+	name, ok := webhookEventNames[event.Name]
+	if !ok {
+		return nil
+	}
+	cursor, err := webhookCollection.Find(curCtx, bson.M{
+		"active": bson.M{"$ne": false},
+		"events": bson.M{"$in": bson.A{name, "*"}},
+	})
+	if err != nil {
+		return err
+	}
+	var webhooks []models.Webhook
+	if err = cursor.All(curCtx, &webhooks); err != nil {
+		return err
+	}
+	return queueDeliveries(curCtx, webhooks, event.ID, name, event.OccurredAt, event.Data)
+}
+
+// queueDeliveries writes one delivery per webhook, all with the same
+// event id so subscribers can tell repeats apart. Queueing an event twice
+// doesn't deliver it twice.
+func queueDeliveries(curCtx context.Context, webhooks []models.Webhook, eventId string, event string, at time.Time, data interface{}) error {
+	payload, err := json.Marshal(gin.H{"id": eventId, "event": event, "created_at": at, "data": data})
+	if err != nil {
+		return err
+	}
+
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	upsert := true
+	for _, hook := range webhooks {
+		delivery := models.WebhookDelivery{
+			ID:            primitive.NewObjectID(),
+			EventID:       eventId,
+			Event:         event,
+			WebhookID:     hook.WebhookID,
+			Payload:       string(payload),
+			Status:        "PENDING",
+			NextAttemptAt: &now,
+			Log:           []models.WebhookAttempt{},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		delivery.DeliveryID = delivery.ID.Hex()
+		_, err := webhookDeliveryCollection.UpdateOne(curCtx,
+			bson.M{"event_id": eventId, "webhook_id": hook.WebhookID},
+			bson.M{"$setOnInsert": delivery},
+			&options.UpdateOptions{Upsert: &upsert},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverWebhooks sends the deliveries that are due. It runs in the
+// background from main.
+func DeliverWebhooks(curCtx context.Context) error {
+	maxAttempts, base := webhookRetryRules()
+	for curCtx.Err() == nil {
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// claiming the delivery keeps other instances from sending it too
+		var delivery models.WebhookDelivery
+		err := webhookDeliveryCollection.FindOneAndUpdate(curCtx,
+			bson.M{"status": "PENDING", "next_attempt_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"next_attempt_at": now.Add(webhookLease)}},
+			options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}),
+		).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := attemptDelivery(curCtx, delivery, maxAttempts, base); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func attemptDelivery(curCtx context.Context, delivery models.WebhookDelivery, maxAttempts int, base time.Duration) error {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	attempt := models.WebhookAttempt{At: now}
+	giveUp := false
+
+	var hook models.Webhook
+	err := webhookCollection.FindOne(curCtx, bson.M{"webhook_id": delivery.WebhookID}).Decode(&hook)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		attempt.Error, giveUp = "webhook was deleted", true
+	case err != nil:
+		return err
+	case hook.Active != nil && !*hook.Active:
+		attempt.Error, giveUp = "webhook is disabled", true
+	default:
+		result, err := webhookSender.Send(curCtx, webhook.Request{
+			URL:        *hook.URL,
+			Secret:     hook.Secret,
+			Event:      delivery.Event,
+			DeliveryID: delivery.DeliveryID,
+			Body:       []byte(delivery.Payload),
+		})
+		attempt.StatusCode = result.StatusCode
+		attempt.Response = result.Response
+		attempt.DurationMs = result.Duration.Milliseconds()
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+	}
+
+	attempts := delivery.Attempts + 1
+	set := bson.M{"attempts": attempts, "last_error": attempt.Error, "updated_at": now}
+	switch {
+	case attempt.Error == "":
+		set["status"] = "DELIVERED"
+		set["delivered_at"] = now
+		set["next_attempt_at"] = nil
+	case giveUp || attempts >= maxAttempts:
+		set["status"] = "DEAD"
+		set["dead_at"] = now
+		set["next_attempt_at"] = nil
+		log.Printf("webhooks: delivery %s of %s to %s is dead: %s", delivery.DeliveryID, delivery.Event, delivery.WebhookID, attempt.Error)
+	default:
+		set["next_attempt_at"] = now.Add(events.Backoff(attempts, base, time.Hour))
+	}
+	// the log keeps the latest tries
+	_, err = webhookDeliveryCollection.UpdateOne(curCtx, bson.M{"delivery_id": delivery.DeliveryID}, bson.M{
+		"$set":  set,
+		"$push": bson.M{"log": bson.M{"$each": bson.A{attempt}, "$slice": -20}},
+	})
+	return err
+}
+
+func GetWebhookEvents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, webhookEvents)
+	}
+}
+
+func GetWebhooks() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := webhookCollection.Find(curCtx, bson.M{})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhooks"})
+			return
+		}
+		webhooks := []models.Webhook{}
+		if err = result.All(curCtx, &webhooks); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhooks"})
+			return
+		}
+		// the secret is only shown when it is made
+		for i := range webhooks {
+			webhooks[i].Secret = ""
+		}
+		ctx.JSON(http.StatusOK, webhooks)
+	}
+}
+
+func GetWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var hook models.Webhook
+		err := webhookCollection.FindOne(curCtx, bson.M{"webhook_id": ctx.Param("webhook_id")}).Decode(&hook)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook was not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhook"})
+			return
+		}
+		hook.Secret = ""
+		ctx.JSON(http.StatusOK, hook)
+	}
+}
+
+// CreateWebhook answers with the signing secret, the only time it is shown
+func CreateWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var hook models.Webhook
+		if err := ctx.BindJSON(&hook); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(hook); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if err := checkWebhookEvents(hook.Events); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook was not created"})
+			return
+		}
+		hook.Secret = secret
+		if hook.Active == nil {
+			active := true
+			hook.Active = &active
+		}
+		hook.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		hook.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		hook.ID = primitive.NewObjectID()
+		hook.WebhookID = hook.ID.Hex()
+
+		if _, err := webhookCollection.InsertOne(curCtx, hook); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook was not created"})
+			return
+		}
+		ctx.JSON(http.StatusOK, hook)
+	}
+}
+
+// UpdateWebhook changes a subscription. "rotate_secret": true replaces
+// the signing secret and answers with the new one.
+func UpdateWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			models.Webhook
+			RotateSecret bool `json:"rotate_secret"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hook := body.Webhook
+
+		var updatedObj primitive.D
+		var fields []string
+
+		if hook.URL != nil {
+			fields = append(fields, "URL")
+			updatedObj = append(updatedObj, bson.E{Key: "url", Value: hook.URL})
+		}
+		if hook.Events != nil {
+			if err := checkWebhookEvents(hook.Events); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			fields = append(fields, "Events")
+			updatedObj = append(updatedObj, bson.E{Key: "events", Value: hook.Events})
+		}
+		if hook.Description != nil {
+			fields = append(fields, "Description")
+			updatedObj = append(updatedObj, bson.E{Key: "description", Value: hook.Description})
+		}
+		if hook.Active != nil {
+			updatedObj = append(updatedObj, bson.E{Key: "active", Value: hook.Active})
+		}
+		if len(fields) > 0 {
+			if err := validate.StructPartial(hook, fields...); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		secret := ""
+		if body.RotateSecret {
+			var err error
+			if secret, err = webhook.NewSecret(); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook updation failed"})
+				return
+			}
+			updatedObj = append(updatedObj, bson.E{Key: "secret", Value: secret})
+		}
+
+		hook.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updatedObj = append(updatedObj, bson.E{Key: "updated_at", Value: hook.UpdatedAt})
+
+		var updated models.Webhook
+		err := webhookCollection.FindOneAndUpdate(curCtx,
+			bson.M{"webhook_id": ctx.Param("webhook_id")},
+			bson.D{{Key: "$set", Value: updatedObj}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook was not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook updation failed"})
+			return
+		}
+		updated.Secret = secret
+		ctx.JSON(http.StatusOK, updated)
+	}
+}
+
+func DeleteWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := webhookCollection.DeleteOne(curCtx, bson.M{"webhook_id": ctx.Param("webhook_id")})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook deletion failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+// PingWebhook queues a "ping" event for just this webhook so a new
+// subscriber can check its signature handling
+func PingWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var hook models.Webhook
+		err := webhookCollection.FindOne(curCtx, bson.M{"webhook_id": ctx.Param("webhook_id")}).Decode(&hook)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook was not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhook"})
+			return
+		}
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		eventId := primitive.NewObjectID().Hex()
+		if err := queueDeliveries(curCtx, []models.Webhook{hook}, eventId, "ping", now, gin.H{"webhook_id": hook.WebhookID}); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Ping was not queued"})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, gin.H{"webhook_id": hook.WebhookID, "event": "ping"})
+	}
+}
+
+// GetWebhookDeliveries is the delivery log, newest first, filtered by
+// webhook_id, event and status
+func GetWebhookDeliveries() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		listWebhookDeliveries(ctx, bson.M{})
+	}
+}
+
+// GetWebhookDeadLetters lists the deliveries that ran out of attempts
+func GetWebhookDeadLetters() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		listWebhookDeliveries(ctx, bson.M{"status": "DEAD"})
+	}
+}
+
+func listWebhookDeliveries(ctx *gin.Context, filter bson.M) {
+	curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	for _, key := range []string{"webhook_id", "event", "event_id", "status"} {
+		if value := ctx.Query(key); value != "" && filter[key] == nil {
+			filter[key] = value
+		}
+	}
+	recordPerPage, err := strconv.Atoi(ctx.Query("recordPerPage"))
+	if err != nil || recordPerPage < 1 || recordPerPage > 500 {
+		recordPerPage = 50
+	}
+	page, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	total, err := webhookDeliveryCollection.CountDocuments(curCtx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhook deliveries"})
+		return
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * recordPerPage)).
+		SetLimit(int64(recordPerPage))
+	cursor, err := webhookDeliveryCollection.Find(curCtx, filter, opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhook deliveries"})
+		return
+	}
+	deliveries := []models.WebhookDelivery{}
+	if err = cursor.All(curCtx, &deliveries); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhook deliveries"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"total_count": total,
+		"page":        page,
+		"deliveries":  deliveries,
+	})
+}
+
+func GetWebhookDelivery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var delivery models.WebhookDelivery
+		err := webhookDeliveryCollection.FindOne(curCtx, bson.M{"delivery_id": ctx.Param("delivery_id")}).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery was not found"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while fetching webhook delivery"})
+			return
+		}
+		ctx.JSON(http.StatusOK, delivery)
+	}
+}
+
+// replayDeliveries puts deliveries back in the queue with a fresh set of
+// attempts. The payload is sent again unchanged, event id included.
+func replayDeliveries(curCtx context.Context, filter bson.M) (int64, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := webhookDeliveryCollection.UpdateMany(curCtx, filter, bson.M{
+		"$set":   bson.M{"status": "PENDING", "attempts": 0, "next_attempt_at": now, "updated_at": now},
+		"$unset": bson.M{"dead_at": "", "delivered_at": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ReplayWebhookDelivery sends a delivery again, whatever became of it
+func ReplayWebhookDelivery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		deliveryId := ctx.Param("delivery_id")
+		replayed, err := replayDeliveries(curCtx, bson.M{"delivery_id": deliveryId})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook delivery was not replayed"})
+			return
+		}
+		if replayed == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery was not found"})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, gin.H{"delivery_id": deliveryId, "status": "PENDING"})
+	}
+}
+
+// ReplayWebhookDeadLetters requeues every dead delivery, or only those of
+// ?webhook_id=, e.g. once a subscriber is back up
+func ReplayWebhookDeadLetters() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{"status": "DEAD"}
+		if webhookId := ctx.Query("webhook_id"); webhookId != "" {
+			filter["webhook_id"] = webhookId
+		}
+		replayed, err := replayDeliveries(curCtx, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Dead letters were not replayed"})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, gin.H{"replayed": replayed})
+	}
+}
@@ -2,32 +2,65 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"os"
 	"time"
 )
 
+// DBinstance connects to MONGODB_URI, a local replica set by default.
+// The driver only dials on first use, RequireTransactions checks the
+// server.
 func DBinstance() *mongo.Client {
-	MongoDB := "mongodb://localhost:27017"
-	fmt.Println(MongoDB)
+	MongoDB := os.Getenv("MONGODB_URI")
+	if MongoDB == "" {
+		MongoDB = "mongodb://localhost:27017/?replicaSet=rs0"
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoDB))
+	// models only carry json tags, store fields under the same snake_case names
+	bsonOpts := &options.BSONOptions{UseJSONStructTags: true}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoDB).SetBSONOptions(bsonOpts))
 	if err != nil {
 		log.Fatal(err)
 	}
+	return client
+}
 
+// RequireTransactions fails unless the server is a replica set member or
+// a mongos. Orders and invoices are written in multi-document
+// transactions, which a standalone mongod refuses, so nothing should
+// start against one.
+func RequireTransactions(ctx context.Context) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := Client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return fmt.Errorf("MongoDB is not reachable: %w", err)
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return errors.New("MongoDB is a standalone server, transactions need a replica set: start mongod with --replSet rs0 and run rs.initiate() once")
+	}
 	fmt.Println("Connected to MongoDB")
-	return client
+	return nil
 }
 
 var Client *mongo.Client = DBinstance()
 
+func OpenDatabase(client *mongo.Client) *mongo.Database {
+	return client.Database("restraurant")
+}
+
 func OpenCollection(client *mongo.Client, colName string) *mongo.Collection {
-	var collection *mongo.Collection = client.Database("restraurant").Collection(colName)
+	var collection *mongo.Collection = OpenDatabase(client).Collection(colName)
 	return collection
 }
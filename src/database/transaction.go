@@ -0,0 +1,9 @@
+package database
+
+import (
+	"infinity/rms/txn"
+)
+
+// UnitOfWork runs multi-step writes as MongoDB transactions, see
+// txn.UnitOfWork for what commits and what rolls back
+var UnitOfWork txn.UnitOfWork = txn.NewMongo(Client)
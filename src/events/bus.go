@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+type subscription struct {
+	name    string
+	event   string
+	handler Handler
+}
+
+// Bus publishes events to an outbox and dispatches them to the
+// subscribers in this process
+type Bus struct {
+	outbox Outbox
+
+	// MaxAttempts is how often an event is tried before it is FAILED
+	MaxAttempts int
+	// RetryAfter is the wait after the first failure, doubling up to MaxWait
+	RetryAfter time.Duration
+	MaxWait    time.Duration
+	// Lease is how long a claimed event is left to this dispatcher
+	Lease time.Duration
+
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+func NewBus(outbox Outbox) *Bus {
+	return &Bus{
+		outbox:      outbox,
+		MaxAttempts: 10,
+		RetryAfter:  5 * time.Second,
+		MaxWait:     10 * time.Minute,
+		Lease:       time.Minute,
+	}
+}
+
+// Subscribe registers handler under a subscriber name for one event or
+// "*" for all. The name tracks which subscribers have had an event, so it
+// must stay the same between restarts.
+func (b *Bus) Subscribe(event string, name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions = append(b.subscriptions, subscription{name: name, event: event, handler: handler})
+}
+
+func (b *Bus) subscribers(event string) []subscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	matching := []subscription{}
+	for _, sub := range b.subscriptions {
+		if sub.event == event || sub.event == "*" {
+			matching = append(matching, sub)
+		}
+	}
+	return matching
+}
+
+// Publish writes an event to the outbox. ctx should be that of the unit
+// of work making the change.
+func (b *Bus) Publish(ctx context.Context, name string, key string, data interface{}) error {
+	event, err := New(name, key, data)
+	if err != nil {
+		return err
+	}
+	return b.outbox.Append(ctx, event)
+}
+
+// Dispatch hands every due event to its subscribers and returns once none
+// is left. Events aren't ordered, a retried event comes after newer ones.
+func (b *Bus) Dispatch(ctx context.Context) error {
+	for ctx.Err() == nil {
+		record, err := b.outbox.Claim(ctx, time.Now(), b.Lease)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return nil
+		}
+		if err := b.deliver(ctx, *record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bus) deliver(ctx context.Context, record Record) error {
+	failures := []string{}
+	for _, sub := range b.subscribers(record.Name) {
+		if contains(record.Delivered, sub.name) {
+			continue
+		}
+		if err := call(ctx, sub.handler, record.Event); err != nil {
+			failures = append(failures, sub.name+": "+err.Error())
+			continue
+		}
+		if err := b.outbox.Ack(ctx, record.ID, sub.name); err != nil {
+			return err
+		}
+		record.Delivered = append(record.Delivered, sub.name)
+	}
+
+	now := time.Now()
+	record.Attempts++
+	record.LastError = strings.Join(failures, "; ")
+	switch {
+	case len(failures) == 0:
+		record.Status = Done
+		record.DoneAt = &now
+		record.NextAttemptAt = nil
+	case record.Attempts >= b.MaxAttempts:
+		record.Status = Failed
+		record.NextAttemptAt = nil
+		log.Printf("events: %s %s failed for good: %s", record.Name, record.ID, record.LastError)
+	default:
+		next := now.Add(b.backoff(record.Attempts))
+		record.NextAttemptAt = &next
+	}
+	return b.outbox.Settle(ctx, record)
+}
+
+func (b *Bus) backoff(attempt int) time.Duration {
+	return Backoff(attempt, b.RetryAfter, b.MaxWait)
+}
+
+// Backoff is the wait before the next try after attempt failures, doubling
+// from base up to max. Webhook deliveries are retried on the same curve.
+func Backoff(attempt int, base time.Duration, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// call keeps a panicking handler from taking the dispatcher down
+func call(ctx context.Context, handler Handler, event Event) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
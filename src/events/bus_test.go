@@ -0,0 +1,214 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"infinity/rms/txn"
+)
+
+func newTestBus() (*Bus, *MemoryOutbox) {
+	outbox := NewMemoryOutbox()
+	bus := NewBus(outbox)
+	bus.RetryAfter = time.Hour
+	bus.MaxWait = 4 * time.Hour
+	return bus, outbox
+}
+
+// makeDue moves every pending event's next attempt into the past, as if
+// the backoff had run out
+func makeDue(outbox *MemoryOutbox) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	past := time.Now().Add(-time.Second)
+	for _, record := range outbox.records {
+		if record.Status == Pending {
+			record.NextAttemptAt = &past
+		}
+	}
+}
+
+func TestFailedSubscriberGetsEventAgain(t *testing.T) {
+	bus, outbox := newTestBus()
+	calls := map[string]int{}
+	bus.Subscribe(OrderCreated, "kitchen", func(ctx context.Context, event Event) error {
+		calls["kitchen"]++
+		return nil
+	})
+	bus.Subscribe("*", "webhooks", func(ctx context.Context, event Event) error {
+		calls["webhooks"]++
+		if calls["webhooks"] == 1 {
+			return errors.New("endpoint down")
+		}
+		return nil
+	})
+	if err := bus.Publish(context.Background(), OrderCreated, "order-1", map[string]string{"order_id": "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	record := outbox.Records()[0]
+	if record.Status != Pending || record.Attempts != 1 || record.LastError != "webhooks: endpoint down" {
+		t.Fatalf("after a failure got %+v", record)
+	}
+	if len(record.Delivered) != 1 || record.Delivered[0] != "kitchen" {
+		t.Fatalf("delivered to %v, want [kitchen]", record.Delivered)
+	}
+
+	// not due again until the backoff has passed
+	if err := bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls["webhooks"] != 1 {
+		t.Fatalf("retried before the backoff ran out")
+	}
+
+	makeDue(outbox)
+	if err := bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	record = outbox.Records()[0]
+	if record.Status != Done || record.DoneAt == nil || record.Attempts != 2 {
+		t.Fatalf("after the retry got %+v", record)
+	}
+	if calls["kitchen"] != 1 || calls["webhooks"] != 2 {
+		t.Fatalf("got calls %v, want the kitchen once and webhooks twice", calls)
+	}
+}
+
+func TestPanickingSubscriberIsRetried(t *testing.T) {
+	bus, outbox := newTestBus()
+	bus.Subscribe(InvoicePaid, "loyalty", func(ctx context.Context, event Event) error {
+		panic("nil map")
+	})
+	bus.Publish(context.Background(), InvoicePaid, "invoice-1", nil)
+
+	if err := bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if record := outbox.Records()[0]; record.Status != Pending || record.LastError != "loyalty: panic: nil map" {
+		t.Fatalf("got %+v", record)
+	}
+}
+
+func TestEventFailsAfterMaxAttempts(t *testing.T) {
+	bus, outbox := newTestBus()
+	bus.MaxAttempts = 3
+	bus.Subscribe("*", "webhooks", func(ctx context.Context, event Event) error {
+		return errors.New("endpoint down")
+	})
+	bus.Publish(context.Background(), OrderCreated, "order-1", nil)
+
+	for i := 0; i < 5; i++ {
+		makeDue(outbox)
+		if err := bus.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record := outbox.Records()[0]
+	if record.Status != Failed || record.Attempts != 3 || record.NextAttemptAt != nil {
+		t.Fatalf("got %+v, want FAILED after 3 attempts", record)
+	}
+}
+
+func TestBackoffDoublesUpToMaxWait(t *testing.T) {
+	bus, _ := newTestBus()
+	bus.RetryAfter = 5 * time.Second
+	bus.MaxWait = 30 * time.Second
+
+	want := map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 3: 20 * time.Second, 4: 30 * time.Second, 10: 30 * time.Second}
+	for attempt, wait := range want {
+		if got := bus.backoff(attempt); got != wait {
+			t.Errorf("attempt %d: got %s, want %s", attempt, got, wait)
+		}
+	}
+}
+
+func TestFailureSchedulesBackoff(t *testing.T) {
+	bus, outbox := newTestBus()
+	bus.Subscribe("*", "webhooks", func(ctx context.Context, event Event) error {
+		return errors.New("endpoint down")
+	})
+	bus.Publish(context.Background(), OrderCreated, "order-1", nil)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		makeDue(outbox)
+		before := time.Now()
+		if err := bus.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		next := outbox.Records()[0].NextAttemptAt
+		wait := bus.backoff(attempt)
+		if next == nil || next.Before(before.Add(wait)) || next.After(time.Now().Add(wait)) {
+			t.Fatalf("attempt %d: next attempt at %v, want %s from now", attempt, next, wait)
+		}
+	}
+}
+
+func TestClaimedEventReturnsWhenLeaseExpires(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	outbox.Append(context.Background(), Event{ID: "event-1", Name: OrderCreated, OccurredAt: time.Now().Add(-time.Minute)})
+	now := time.Now()
+
+	claimed, err := outbox.Claim(context.Background(), now, time.Minute)
+	if err != nil || claimed == nil {
+		t.Fatalf("got %v %v, want the event", claimed, err)
+	}
+	if again, _ := outbox.Claim(context.Background(), now.Add(59*time.Second), time.Minute); again != nil {
+		t.Fatal("claimed again while the lease held")
+	}
+	again, _ := outbox.Claim(context.Background(), now.Add(61*time.Second), time.Minute)
+	if again == nil || again.ID != "event-1" {
+		t.Fatal("not claimed again after the lease ran out")
+	}
+}
+
+func TestDispatchPicksUpEventOfLostDispatcher(t *testing.T) {
+	bus, outbox := newTestBus()
+	delivered := 0
+	bus.Subscribe(OrderCreated, "kitchen", func(ctx context.Context, event Event) error {
+		delivered++
+		return nil
+	})
+	bus.Publish(context.Background(), OrderCreated, "order-1", nil)
+
+	// another dispatcher claims the event and is never heard from again
+	if claimed, _ := outbox.Claim(context.Background(), time.Now(), bus.Lease); claimed == nil {
+		t.Fatal("nothing to claim")
+	}
+	bus.Dispatch(context.Background())
+	if delivered != 0 {
+		t.Fatal("delivered while another dispatcher held the lease")
+	}
+
+	// the lease runs out
+	makeDue(outbox)
+	bus.Dispatch(context.Background())
+	if delivered != 1 || outbox.Records()[0].Status != Done {
+		t.Fatalf("delivered %d times, status %s", delivered, outbox.Records()[0].Status)
+	}
+}
+
+func TestPublishInRolledBackUnitIsDropped(t *testing.T) {
+	bus, outbox := newTestBus()
+	unit := txn.NewMemory()
+
+	unit.Run(context.Background(), func(ctx context.Context) error {
+		bus.Publish(ctx, OrderCreated, "order-1", nil)
+		return errors.New("insert failed")
+	})
+	if len(outbox.Records()) != 0 {
+		t.Fatal("event of a rolled back unit was kept")
+	}
+
+	unit.Run(context.Background(), func(ctx context.Context) error {
+		return bus.Publish(ctx, OrderCreated, "order-2", nil)
+	})
+	if records := outbox.Records(); len(records) != 1 || records[0].Key != "order-2" {
+		t.Fatalf("got %v, want the committed event", records)
+	}
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Domain events the controllers publish
+const (
+	OrderCreated       = "OrderCreated"
+	OrderStatusChanged = "OrderStatusChanged"
+	OrderItemAdded     = "OrderItemAdded"
+	ItemVoided         = "ItemVoided"
+	InvoicePaid        = "InvoicePaid"
+	TableStatusChanged = "TableStatusChanged"
+)
+
+// Event is something that happened, Key being the id of what it happened
+// to, e.g. the order for OrderCreated
+type Event struct {
+	ID         string          `json:"event_id"`
+	Name       string          `json:"name"`
+	Key        string          `json:"key"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+func New(name string, key string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	occurredAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return Event{
+		ID:         primitive.NewObjectID().Hex(),
+		Name:       name,
+		Key:        key,
+		Data:       payload,
+		OccurredAt: occurredAt,
+	}, nil
+}
+
+// Decode unmarshals the event's data into v
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Handler reacts to an event. Events arrive at least once, so a handler
+// must cope with seeing the same event id again.
+type Handler func(ctx context.Context, event Event) error
+
+const (
+	Pending = "PENDING"
+	Done    = "DONE"
+	Failed  = "FAILED"
+)
+
+// Record is an event in the outbox with its progress. Delivered names the
+// subscribers that have handled it, so a retry only goes to the others.
+type Record struct {
+	Event
+	Status        string
+	Delivered     []string
+	Attempts      int
+	NextAttemptAt *time.Time
+	LastError     string
+	DoneAt        *time.Time
+}
+
+// Outbox stores events until every subscriber has handled them
+type Outbox interface {
+	// Append writes events with ctx, so inside a unit of work they are
+	// only stored if the change that caused them is
+	Append(ctx context.Context, events ...Event) error
+	// Claim returns the pending event due soonest, hiding it from other
+	// dispatchers for lease, or nil when none is due
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (*Record, error)
+	// Ack records that subscriber handled the event
+	Ack(ctx context.Context, eventId string, subscriber string) error
+	// Settle saves the outcome of a dispatch: status, attempts, next
+	// attempt, last error and done time
+	Settle(ctx context.Context, record Record) error
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"infinity/rms/txn"
+	"sync"
+	"time"
+)
+
+// MemoryOutbox keeps events in memory for tests. Events appended inside a
+// txn.Memory unit of work only show up once it commits. Nothing survives
+// a restart.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	records []*Record
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+func (o *MemoryOutbox) Append(ctx context.Context, events ...Event) error {
+	txn.Stage(ctx, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		for _, event := range events {
+			at := event.OccurredAt
+			o.records = append(o.records, &Record{Event: event, Status: Pending, Delivered: []string{}, NextAttemptAt: &at})
+		}
+	})
+	return nil
+}
+
+func (o *MemoryOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration) (*Record, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due *Record
+	for _, record := range o.records {
+		if record.Status != Pending || record.NextAttemptAt == nil || record.NextAttemptAt.After(now) {
+			continue
+		}
+		if due == nil || record.NextAttemptAt.Before(*due.NextAttemptAt) {
+			due = record
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+	leased := now.Add(lease)
+	due.NextAttemptAt = &leased
+	return copyRecord(due), nil
+}
+
+func (o *MemoryOutbox) Ack(ctx context.Context, eventId string, subscriber string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if record := o.find(eventId); record != nil && !contains(record.Delivered, subscriber) {
+		record.Delivered = append(record.Delivered, subscriber)
+	}
+	return nil
+}
+
+func (o *MemoryOutbox) Settle(ctx context.Context, settled Record) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if record := o.find(settled.ID); record != nil {
+		record.Status = settled.Status
+		record.Attempts = settled.Attempts
+		record.NextAttemptAt = settled.NextAttemptAt
+		record.LastError = settled.LastError
+		record.DoneAt = settled.DoneAt
+	}
+	return nil
+}
+
+// Records returns a copy of everything in the outbox, oldest first
+func (o *MemoryOutbox) Records() []Record {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	records := []Record{}
+	for _, record := range o.records {
+		records = append(records, *copyRecord(record))
+	}
+	return records
+}
+
+func (o *MemoryOutbox) find(eventId string) *Record {
+	for _, record := range o.records {
+		if record.ID == eventId {
+			return record
+		}
+	}
+	return nil
+}
+
+func copyRecord(record *Record) *Record {
+	copied := *record
+	copied.Delivered = append([]string{}, record.Delivered...)
+	return &copied
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoOutbox keeps the outbox in a collection. Appending with a session
+// context writes the events in the same transaction as the change.
+type MongoOutbox struct {
+	Collection *mongo.Collection
+}
+
+func NewMongoOutbox(collection *mongo.Collection) *MongoOutbox {
+	return &MongoOutbox{Collection: collection}
+}
+
+// outboxDocument is how a Record is stored, the data as JSON text
+type outboxDocument struct {
+	EventID       string     `json:"event_id"`
+	Name          string     `json:"name"`
+	Key           string     `json:"key"`
+	Data          string     `json:"data"`
+	OccurredAt    time.Time  `json:"occurred_at"`
+	Status        string     `json:"status"`
+	Delivered     []string   `json:"delivered"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	DoneAt        *time.Time `json:"done_at,omitempty"`
+}
+
+func (o *MongoOutbox) Append(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	documents := []interface{}{}
+	for _, event := range events {
+		at := event.OccurredAt
+		documents = append(documents, outboxDocument{
+			EventID:       event.ID,
+			Name:          event.Name,
+			Key:           event.Key,
+			Data:          string(event.Data),
+			OccurredAt:    event.OccurredAt,
+			Status:        Pending,
+			Delivered:     []string{},
+			NextAttemptAt: &at,
+		})
+	}
+	_, err := o.Collection.InsertMany(ctx, documents)
+	return err
+}
+
+func (o *MongoOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration) (*Record, error) {
+	var document outboxDocument
+	err := o.Collection.FindOneAndUpdate(ctx,
+		bson.M{"status": Pending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "occurred_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&document)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		Event: Event{
+			ID:         document.EventID,
+			Name:       document.Name,
+			Key:        document.Key,
+			Data:       json.RawMessage(document.Data),
+			OccurredAt: document.OccurredAt,
+		},
+		Status:        document.Status,
+		Delivered:     document.Delivered,
+		Attempts:      document.Attempts,
+		NextAttemptAt: document.NextAttemptAt,
+		LastError:     document.LastError,
+		DoneAt:        document.DoneAt,
+	}, nil
+}
+
+func (o *MongoOutbox) Ack(ctx context.Context, eventId string, subscriber string) error {
+	_, err := o.Collection.UpdateOne(ctx, bson.M{"event_id": eventId}, bson.M{
+		"$addToSet": bson.M{"delivered": subscriber},
+	})
+	return err
+}
+
+func (o *MongoOutbox) Settle(ctx context.Context, record Record) error {
+	_, err := o.Collection.UpdateOne(ctx, bson.M{"event_id": record.ID}, bson.M{
+		"$set": bson.M{
+			"status":          record.Status,
+			"attempts":        record.Attempts,
+			"next_attempt_at": record.NextAttemptAt,
+			"last_error":      record.LastError,
+			"done_at":         record.DoneAt,
+		},
+	})
+	return err
+}
@@ -0,0 +1,17 @@
+package helpers
+
+import "strings"
+
+// NormalizeGiftCardCode accepts codes typed without dashes or in lower
+// case
+func NormalizeGiftCardCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	var b strings.Builder
+	for i, r := range code {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
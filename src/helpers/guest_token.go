@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// GuestDetails are the claims of a guest ordering session; they only
+// grant access to the one table the QR code was printed for, and only
+// until the code is rotated
+type GuestDetails struct {
+	TableID   string
+	QRVersion int
+	jwt.StandardClaims
+}
+
+// guest keys are derived from SECRETKEY so guest tokens never pass as
+// staff tokens and the other way round
+func guestKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(SECRETKEY))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// TableQRToken is printed in the table's QR code; bumping the table's
+// qr_version invalidates every code printed before
+func TableQRToken(tableId string, version int) string {
+	payload := tableId + ":" + strconv.Itoa(version)
+	mac := hmac.New(sha256.New, guestKey("table-qr"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func ParseTableQRToken(token string) (tableId string, version int, err error) {
+	payloadPart, sigPart, found := strings.Cut(token, ".")
+	if !found {
+		return "", 0, errors.New("The QR code is invalid")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return "", 0, errors.New("The QR code is invalid")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return "", 0, errors.New("The QR code is invalid")
+	}
+
+	mac := hmac.New(sha256.New, guestKey("table-qr"))
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", 0, errors.New("The QR code is invalid")
+	}
+
+	tableId, versionPart, _ := strings.Cut(string(payload), ":")
+	version, err = strconv.Atoi(versionPart)
+	if err != nil {
+		return "", 0, errors.New("The QR code is invalid")
+	}
+	return tableId, version, nil
+}
+
+func GenerateGuestToken(tableId string, qrVersion int, ttl time.Duration) (string, error) {
+	claims := &GuestDetails{
+		TableID:   tableId,
+		QRVersion: qrVersion,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Local().Add(ttl).Unix(),
+			Subject:   "guest",
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(guestKey("guest-session"))
+}
+
+func ValidateGuestToken(signedToken string) (*GuestDetails, error) {
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&GuestDetails{},
+		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return guestKey("guest-session"), nil
+		},
+	)
+	if err != nil {
+		return nil, errors.New("The guest session is invalid or has expired")
+	}
+
+	claims, ok := token.Claims.(*GuestDetails)
+	if !ok || !token.Valid || claims.Subject != "guest" || claims.TableID == "" {
+		return nil, errors.New("The guest session is invalid or has expired")
+	}
+	return claims, nil
+}
@@ -0,0 +1,945 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLocale = "en"
+
+// SupportedLocales comes from SUPPORTED_LOCALES, e.g. "en,fr,es,de"
+func SupportedLocales() []string {
+	value := os.Getenv("SUPPORTED_LOCALES")
+	if value == "" {
+		return []string{"en", "fr", "es", "de"}
+	}
+	locales := []string{}
+	for _, locale := range strings.Split(value, ",") {
+		if locale = normalizeLocale(locale); locale != "" {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header
+// ordered by preference, e.g. "fr-CA,fr;q=0.8,en;q=0.5"
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	entries := []weighted{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := normalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			entries = append(entries, weighted{locale, q})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+
+	locales := []string{}
+	for _, entry := range entries {
+		locales = append(locales, entry.locale)
+	}
+	return locales
+}
+
+// ResolveLocales narrows the requested languages to the supported ones,
+// adding the base language after each regional one ("fr-ca" -> "fr") and
+// always ending with the default locale
+func ResolveLocales(requested []string) []string {
+	supported := map[string]bool{}
+	for _, locale := range SupportedLocales() {
+		supported[locale] = true
+	}
+
+	resolved := []string{}
+	seen := map[string]bool{}
+	add := func(locale string) {
+		if supported[locale] && !seen[locale] {
+			seen[locale] = true
+			resolved = append(resolved, locale)
+		}
+	}
+	for _, locale := range requested {
+		add(locale)
+		if base, _, found := strings.Cut(locale, "-"); found {
+			add(base)
+		}
+	}
+	if !seen[DefaultLocale] {
+		resolved = append(resolved, DefaultLocale)
+	}
+	return resolved
+}
+
+// Localize picks the first translation available for the given locales
+// and falls back to the untranslated value
+func Localize(translations map[string]string, fallback string, locales []string) string {
+	for _, locale := range locales {
+		if value, ok := translations[locale]; ok && value != "" {
+			return value
+		}
+	}
+	return fallback
+}
+
+// Translate looks an English message up in the catalog. A message built
+// with fmt.Sprintf matches the template it came from, e.g. "The 12:00 slot
+// is full" matches "The %s slot is full", and its values are put into the
+// translated template, which uses the same verbs in the same order.
+func Translate(message string, locales []string) string {
+	for _, locale := range locales {
+		if locale == DefaultLocale {
+			return message
+		}
+		if value, ok := catalog[locale][message]; ok {
+			return value
+		}
+		for _, template := range catalogTemplates {
+			value, ok := catalog[locale][template.key]
+			if !ok {
+				continue
+			}
+			if args, ok := template.match(message); ok {
+				return fmt.Sprintf(value, args...)
+			}
+		}
+	}
+	return message
+}
+
+// catalogTemplate matches messages formatted from a catalog key with %s,
+// %d or %q verbs
+type catalogTemplate struct {
+	key     string
+	verbs   []byte
+	pattern *regexp.Regexp
+}
+
+var templateVerb = regexp.MustCompile(`%[sdq]`)
+
+var catalogTemplates = func() []catalogTemplate {
+	templates := []catalogTemplate{}
+	seen := map[string]bool{}
+	for _, locale := range []string{"fr", "es", "de"} {
+		keys := []string{}
+		for key := range catalog[locale] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if seen[key] || !templateVerb.MatchString(key) {
+				continue
+			}
+			seen[key] = true
+
+			template := catalogTemplate{key: key}
+			pattern := "^"
+			last := 0
+			for _, loc := range templateVerb.FindAllStringIndex(key, -1) {
+				pattern += regexp.QuoteMeta(key[last:loc[0]])
+				verb := key[loc[0]+1]
+				switch verb {
+				case 'd':
+					pattern += `(-?\d+)`
+				case 'q':
+					pattern += `("(?:[^"\\]|\\.)*")`
+				default:
+					pattern += `(.+?)`
+				}
+				template.verbs = append(template.verbs, verb)
+				last = loc[1]
+			}
+			template.pattern = regexp.MustCompile(pattern + regexp.QuoteMeta(key[last:]) + "$")
+			templates = append(templates, template)
+		}
+	}
+	return templates
+}()
+
+func (t catalogTemplate) match(message string) ([]interface{}, bool) {
+	groups := t.pattern.FindStringSubmatch(message)
+	if groups == nil {
+		return nil, false
+	}
+	args := []interface{}{}
+	for i, verb := range t.verbs {
+		value := groups[i+1]
+		switch verb {
+		case 'd':
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			args = append(args, n)
+		case 'q':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, false
+			}
+			args = append(args, unquoted)
+		default:
+			args = append(args, value)
+		}
+	}
+	return args, true
+}
+
+var catalog = map[string]map[string]string{
+	"fr": {
+		"Token is Empty":                               "Le jeton est vide",
+		"Error occured while fetching":                 "Erreur lors de la récupération",
+		"Error occured while fetching the menu":        "Erreur lors de la récupération du menu",
+		"Error occured while fetching orders":          "Erreur lors de la récupération des commandes",
+		"Error occured while fetching invoice":         "Erreur lors de la récupération de la facture",
+		"Menu not founf":                               "Menu introuvable",
+		"Menu was not found":                           "Menu introuvable",
+		"Food was not found":                           "Plat introuvable",
+		"Order was not found":                          "Commande introuvable",
+		"Table wasn't found":                           "Table introuvable",
+		"Table was not found":                          "Table introuvable",
+		"Invoice was not found":                        "Facture introuvable",
+		"Food item was not created":                    "Le plat n'a pas été créé",
+		"Failed to create an order":                    "Impossible de créer la commande",
+		"Failed to create order items":                 "Impossible de créer les articles",
+		"Order items conflict with declared allergies": "Des articles contiennent des allergènes déclarés",
+		"user not found, login seems to be incorrect":  "Utilisateur introuvable, identifiants incorrects",
+		"Translation was not found":                    "Traduction introuvable",
+		"Unsupported entity":                           "Type d'entité non pris en charge",
+		"Receipt":                                      "Reçu",
+		"Table":                                        "Table",
+		"Date":                                         "Date",
+		"Total":                                        "Total",
+		"Subtotal":                                     "Sous-total",
+		"Discount":                                     "Remise",
+		"Packaging":                                    "Emballage",
+		"Delivery fee":                                 "Frais de livraison",
+		"REFUNDED":                                     "REMBOURSÉ",
+		"Payment":                                      "Paiement",
+		"Status":                                       "Statut",
+		"PAID":                                         "PAYÉ",
+		"PENDING":                                      "EN ATTENTE",
+		"CARD":                                         "CARTE",
+		"CASH":                                         "ESPÈCES",
+		"Thank you for your visit":                     "Merci de votre visite",
+		"Celery":                                       "Céleri",
+		"Cereals containing gluten":                    "Céréales contenant du gluten",
+		"Crustaceans":                                  "Crustacés",
+		"Eggs":                                         "Œufs",
+		"Fish":                                         "Poisson",
+		"Lupin":                                        "Lupin",
+		"Milk":                                         "Lait",
+		"Molluscs":                                     "Mollusques",
+		"Mustard":                                      "Moutarde",
+		"Tree nuts":                                    "Fruits à coque",
+		"Peanuts":                                      "Arachides",
+		"Sesame seeds":                                 "Graines de sésame",
+		"Soya":                                         "Soja",
+		"Sulphur dioxide and sulphites":                "Anhydride sulfureux et sulfites",
+		"A customer can not be merged into itself":                      "Un client ne peut pas être fusionné avec lui-même",
+		"A customer with this phone or email already exists":            "Un client avec ce téléphone ou cet e-mail existe déjà",
+		"A refunded invoice can't change status":                        "Une facture remboursée ne peut pas changer de statut",
+		"Allergen %s already exists":                                    "L'allergène %s existe déjà",
+		"Allergen was not created":                                      "L'allergène n'a pas été créé",
+		"Amount is more than the amount due":                            "Le montant dépasse le montant dû",
+		"Area updation failed":                                          "Échec de la mise à jour de la salle",
+		"Area was not created":                                          "La salle n'a pas été créée",
+		"Both checks have loyalty discounts for different customers":    "Les deux additions ont des remises fidélité de clients différents",
+		"Break could not be ended":                                      "Impossible de terminer la pause",
+		"Break could not be started":                                    "Impossible de commencer la pause",
+		"Clock in failed":                                               "Échec du pointage d'arrivée",
+		"Clock out failed":                                              "Échec du pointage de départ",
+		"Customer updation failed":                                      "Échec de la mise à jour du client",
+		"Customer was already merged":                                   "Le client a déjà été fusionné",
+		"Customer was not created":                                      "Le client n'a pas été créé",
+		"Customer was not found":                                        "Client introuvable",
+		"Day %s is already closed":                                      "La journée %s est déjà clôturée",
+		"Dead letters were not replayed":                                "Les événements en échec n'ont pas été rejoués",
+		"Delivery zone deletion failed":                                 "Échec de la suppression de la zone de livraison",
+		"Delivery zone updation failed":                                 "Échec de la mise à jour de la zone de livraison",
+		"Delivery zone was not created":                                 "La zone de livraison n'a pas été créée",
+		"Delivery zone was not found":                                   "Zone de livraison introuvable",
+		"Discount is more than the amount due":                          "La remise dépasse le montant dû",
+		"Error occured while checking allergens":                        "Erreur lors de la vérification des allergènes",
+		"Error occured while exporting menus":                           "Erreur lors de l'export des menus",
+		"Error occured while fetching all tables":                       "Erreur lors de la récupération des tables",
+		"Error occured while fetching allergens":                        "Erreur lors de la récupération des allergènes",
+		"Error occured while fetching areas":                            "Erreur lors de la récupération des salles",
+		"Error occured while fetching customers":                        "Erreur lors de la récupération des clients",
+		"Error occured while fetching delivery zones":                   "Erreur lors de la récupération des zones de livraison",
+		"Error occured while fetching events":                           "Erreur lors de la récupération des événements",
+		"Error occured while fetching gift card":                        "Erreur lors de la récupération de la carte cadeau",
+		"Error occured while fetching gift card transactions":           "Erreur lors de la récupération des opérations de la carte cadeau",
+		"Error occured while fetching gift cards":                       "Erreur lors de la récupération des cartes cadeaux",
+		"Error occured while fetching invoices":                         "Erreur lors de la récupération des factures",
+		"Error occured while fetching order item":                       "Erreur lors de la récupération de l'article",
+		"Error occured while fetching order items":                      "Erreur lors de la récupération des articles",
+		"Error occured while fetching platform items":                   "Erreur lors de la récupération des articles de la plateforme",
+		"Error occured while fetching section assignments":              "Erreur lors de la récupération des affectations de secteur",
+		"Error occured while fetching shifts":                           "Erreur lors de la récupération des services",
+		"Error occured while fetching staff":                            "Erreur lors de la récupération du personnel",
+		"Error occured while fetching table status":                     "Erreur lors de la récupération de l'état des tables",
+		"Error occured while fetching the audit log":                    "Erreur lors de la récupération du journal d'audit",
+		"Error occured while fetching the loyalty ledger":               "Erreur lors de la récupération du relevé de fidélité",
+		"Error occured while fetching the loyalty program":              "Erreur lors de la récupération du programme de fidélité",
+		"Error occured while fetching the waitlist":                     "Erreur lors de la récupération de la liste d'attente",
+		"Error occured while fetching time entries":                     "Erreur lors de la récupération des pointages",
+		"Error occured while fetching users":                            "Erreur lors de la récupération des utilisateurs",
+		"Error occured while fetching visits":                           "Erreur lors de la récupération des visites",
+		"Error occured while fetching webhook":                          "Erreur lors de la récupération du webhook",
+		"Error occured while fetching webhook deliveries":               "Erreur lors de la récupération des envois du webhook",
+		"Error occured while fetching webhook delivery":                 "Erreur lors de la récupération de l'envoi du webhook",
+		"Error occured while fetching webhooks":                         "Erreur lors de la récupération des webhooks",
+		"Error occurred while checking the email":                       "Erreur lors de la vérification de l'e-mail",
+		"Error occurred while checking the phone":                       "Erreur lors de la vérification du téléphone",
+		"Event was not retried":                                         "L'événement n'a pas été relancé",
+		"Every item was selected, move the order instead":               "Tous les articles sont sélectionnés, déplacez plutôt la commande",
+		"Failed to create a table item":                                 "Impossible de créer la table",
+		"Food is not a loyalty reward":                                  "Ce plat n'est pas une récompense fidélité",
+		"Food is not on the menu right now":                             "Ce plat n'est pas à la carte en ce moment",
+		"Food update failed":                                            "Échec de la mise à jour du plat",
+		"Gift card balance is too low":                                  "Le solde de la carte cadeau est insuffisant",
+		"Gift card has expired":                                         "La carte cadeau a expiré",
+		"Gift card is void":                                             "La carte cadeau est annulée",
+		"Gift card was not found":                                       "Carte cadeau introuvable",
+		"Guest session could not be created":                            "Impossible de créer la session invité",
+		"Guest token is Empty":                                          "Le jeton invité est vide",
+		"Image could not be decoded":                                    "Impossible de décoder l'image",
+		"Image could not be read":                                       "Impossible de lire l'image",
+		"Image could not be stored":                                     "Impossible d'enregistrer l'image",
+		"Image dimensions are too large":                                "Les dimensions de l'image sont trop grandes",
+		"Image is larger than %d bytes":                                 "L'image dépasse %d octets",
+		"Image was not found":                                           "Image introuvable",
+		"Invalid JSON: %s":                                              "JSON invalide : %s",
+		"Invalid email":                                                 "E-mail invalide",
+		"Invoice has no customer":                                       "La facture n'a pas de client",
+		"Invoice is not awaiting payment":                               "La facture n'est pas en attente de paiement",
+		"Kindly retype the time":                                        "Veuillez ressaisir l'heure",
+		"Loyalty program is not enabled":                                "Le programme de fidélité n'est pas activé",
+		"Loyalty program updation failed":                               "Échec de la mise à jour du programme de fidélité",
+		"Menu import failed: %s":                                        "Échec de l'import des menus : %s",
+		"Menu update failed":                                            "Échec de la mise à jour du menu",
+		"No failed event with that id":                                  "Aucun événement en échec avec cet identifiant",
+		"No free table fits this party":                                 "Aucune table libre ne convient à ce groupe",
+		"No translatable fields given":                                  "Aucun champ traduisible fourni",
+		"Not enough points":                                             "Pas assez de points",
+		"Nothing to pay":                                                "Rien à payer",
+		"Notification failed: %s":                                       "Échec de la notification : %s",
+		"Only takeaway and delivery orders have a status":               "Seules les commandes à emporter et en livraison ont un statut",
+		"Order Item updation failed":                                    "Échec de la mise à jour de l'article",
+		"Order can't move from %s to %s":                                "La commande ne peut pas passer de %s à %s",
+		"Order has no items":                                            "La commande n'a aucun article",
+		"Order is already on that table":                                "La commande est déjà sur cette table",
+		"Order is already paid":                                         "La commande est déjà payée",
+		"Order is closed, items can't be added":                         "La commande est clôturée, impossible d'ajouter des articles",
+		"Order is not waiting to be released":                           "La commande n'attend pas d'être envoyée en cuisine",
+		"Order is scheduled for %s and not released to the kitchen yet": "La commande est prévue pour %s et n'a pas encore été envoyée en cuisine",
+		"Order item is already voided":                                  "L'article est déjà annulé",
+		"Order item was not found":                                      "Article introuvable",
+		"Order status changed in the meantime":                          "Le statut de la commande a changé entre-temps",
+		"Order updation failed":                                         "Échec de la mise à jour de la commande",
+		"Party has no phone or email":                                   "Le groupe n'a ni téléphone ni e-mail",
+		"Party is no longer waiting":                                    "Le groupe n'attend plus",
+		"Party was not added to the waitlist":                           "Le groupe n'a pas été ajouté à la liste d'attente",
+		"Party was not found":                                           "Groupe introuvable",
+		"Ping was not queued":                                           "Le ping n'a pas été mis en file d'attente",
+		"Platform is not configured":                                    "La plateforme n'est pas configurée",
+		"Platform item deletion failed":                                 "Échec de la suppression de l'article de la plateforme",
+		"Platform item was not saved":                                   "L'article de la plateforme n'a pas été enregistré",
+		"Points can only be redeemed on unpaid invoices":                "Les points ne s'utilisent que sur des factures impayées",
+		"QR code could not be generated":                                "Impossible de générer le QR code",
+		"Reward item is not on this order":                              "L'article offert n'est pas sur cette commande",
+		"Section assignment deletion failed":                            "Échec de la suppression de l'affectation de secteur",
+		"Section assignment was not created":                            "L'affectation de secteur n'a pas été créée",
+		"Section assignment was not found":                              "Affectation de secteur introuvable",
+		"Section is already assigned for part of this time":             "Le secteur est déjà affecté sur une partie de ce créneau",
+		"Send either points or food_id":                                 "Envoyez soit points soit food_id",
+		"Shift belongs to someone else":                                 "Ce service appartient à quelqu'un d'autre",
+		"Shift deletion failed":                                         "Échec de la suppression du service",
+		"Shift updation failed":                                         "Échec de la mise à jour du service",
+		"Shift was not created":                                         "Le service n'a pas été créé",
+		"Shift was not found":                                           "Service introuvable",
+		"Some order items do not belong to this order":                  "Certains articles n'appartiennent pas à cette commande",
+		"Source table has no open order":                                "La table d'origine n'a pas de commande ouverte",
+		"Staff member is already clocked in":                            "Le membre du personnel a déjà pointé",
+		"Staff member is already on a break":                            "Le membre du personnel est déjà en pause",
+		"Staff member is not active":                                    "Le membre du personnel n'est pas actif",
+		"Staff member is not clocked in":                                "Le membre du personnel n'a pas pointé",
+		"Staff member is not on a break":                                "Le membre du personnel n'est pas en pause",
+		"Staff member was not created":                                  "Le membre du personnel n'a pas été créé",
+		"Staff member was not found":                                    "Membre du personnel introuvable",
+		"Staff updation failed":                                         "Échec de la mise à jour du personnel",
+		"Status can only be set to WAITING, CANCELLED or NO_SHOW":       "Le statut ne peut être que WAITING, CANCELLED ou NO_SHOW",
+		"Table already has an open order":                               "La table a déjà une commande ouverte",
+		"Table already has an open order, merge the tables instead":     "La table a déjà une commande ouverte, fusionnez plutôt les tables",
+		"Table is not free":                                             "La table n'est pas libre",
+		"Table is too small for this party":                             "La table est trop petite pour ce groupe",
+		"Table updation failed":                                         "Échec de la mise à jour de la table",
+		"The %s slot is full":                                           "Le créneau de %s est complet",
+		"The QR code is invalid":                                        "Le QR code n'est pas valide",
+		"The day hasn't started yet":                                    "La journée n'a pas encore commencé",
+		"The file field is required":                                    "Le champ file est obligatoire",
+		"The guest session is invalid or has expired":                   "La session invité est invalide ou a expiré",
+		"The image field is required":                                   "Le champ image est obligatoire",
+		"The token is invalid":                                          "Le jeton n'est pas valide",
+		"This email or phone number already in Use":                     "Cet e-mail ou ce numéro de téléphone est déjà utilisé",
+		"Token is Expaired":                                             "Le jeton a expiré",
+		"Too many requests":                                             "Trop de requêtes",
+		"Translation update failed":                                     "Échec de la mise à jour de la traduction",
+		"Unable to get all the order items":                             "Impossible de récupérer tous les articles",
+		"Unknown allergen %s":                                           "Allergène inconnu %s",
+		"Unknown dietary tag %s":                                        "Régime alimentaire inconnu %s",
+		"Unmapped items: %s":                                            "Articles non associés : %s",
+		"Unsupported image type %s":                                     "Type d'image non pris en charge %s",
+		"Unsupported locale %s":                                         "Langue non prise en charge %s",
+		"User not created":                                              "L'utilisateur n'a pas été créé",
+		"User update failed":                                            "Échec de la mise à jour de l'utilisateur",
+		"User was not found":                                            "Utilisateur introuvable",
+		"Waitlist updation failed":                                      "Échec de la mise à jour de la liste d'attente",
+		"We don't deliver to %s":                                        "Nous ne livrons pas à %s",
+		"Webhook deletion failed":                                       "Échec de la suppression du webhook",
+		"Webhook delivery was not found":                                "Envoi du webhook introuvable",
+		"Webhook delivery was not replayed":                             "L'envoi du webhook n'a pas été rejoué",
+		"Webhook updation failed":                                       "Échec de la mise à jour du webhook",
+		"Webhook was not created":                                       "Le webhook n'a pas été créé",
+		"Webhook was not found":                                         "Webhook introuvable",
+		"birthday must be a date like 2006-01-02":                       "birthday doit être une date comme 2006-01-02",
+		"contact_name and contact_phone are required":                   "contact_name et contact_phone sont obligatoires",
+		"course must be one of %s":                                      "course doit être parmi %s",
+		"covers must be at least 1":                                     "covers doit être au moins 1",
+		"date must look like 2006-01-02":                                "date doit ressembler à 2006-01-02",
+		"delivery_address is required":                                  "delivery_address est obligatoire",
+		"double_point_days must be weekdays or dates like 2006-01-02":   "double_point_days doit contenir des jours de la semaine ou des dates comme 2006-01-02",
+		"end_time must be after start_time":                             "end_time doit être après start_time",
+		"expires_at must be in the future":                              "expires_at doit être dans le futur",
+		"from and to must be dates like 2006-01-02":                     "from et to doivent être des dates comme 2006-01-02",
+		"from must be a date or an RFC3339 time":                        "from doit être une date ou une heure RFC3339",
+		"login or password is incorrect":                                "identifiant ou mot de passe incorrect",
+		"order_items can't be empty":                                    "order_items ne peut pas être vide",
+		"packaging_fee can't be negative":                               "packaging_fee ne peut pas être négatif",
+		"party_size is required":                                        "party_size est obligatoire",
+		"party_size must be at least 1":                                 "party_size doit être au moins 1",
+		"pickup_time must be in the future":                             "pickup_time doit être dans le futur",
+		"prep_minutes must be at least 1":                               "prep_minutes doit être au moins 1",
+		"q must be at least 2 characters":                               "q doit contenir au moins 2 caractères",
+		"scheduled_for must be in the future":                           "scheduled_for doit être dans le futur",
+		"start_time and end_time are required and end_time must be after start_time": "start_time et end_time sont obligatoires et end_time doit être après start_time",
+		"tip can not be negative":              "tip ne peut pas être négatif",
+		"to must be a date or an RFC3339 time": "to doit être une date ou une heure RFC3339",
+		"type must be TAKEAWAY or DELIVERY":    "type doit être TAKEAWAY ou DELIVERY",
+		"unit_price is required":               "unit_price est obligatoire",
+		"unknown event %q, use one of %s or *": "événement inconnu %q, utilisez %s ou *",
+	},
+	"es": {
+		"Token is Empty":                               "El token está vacío",
+		"Error occured while fetching":                 "Error al obtener los datos",
+		"Error occured while fetching the menu":        "Error al obtener el menú",
+		"Error occured while fetching orders":          "Error al obtener los pedidos",
+		"Error occured while fetching invoice":         "Error al obtener la factura",
+		"Menu not founf":                               "Menú no encontrado",
+		"Menu was not found":                           "Menú no encontrado",
+		"Food was not found":                           "Plato no encontrado",
+		"Order was not found":                          "Pedido no encontrado",
+		"Table wasn't found":                           "Mesa no encontrada",
+		"Table was not found":                          "Mesa no encontrada",
+		"Invoice was not found":                        "Factura no encontrada",
+		"Food item was not created":                    "No se creó el plato",
+		"Failed to create an order":                    "No se pudo crear el pedido",
+		"Failed to create order items":                 "No se pudieron crear los artículos",
+		"Order items conflict with declared allergies": "Hay artículos con alérgenos declarados",
+		"user not found, login seems to be incorrect":  "Usuario no encontrado, credenciales incorrectas",
+		"Translation was not found":                    "Traducción no encontrada",
+		"Unsupported entity":                           "Tipo de entidad no admitido",
+		"Receipt":                                      "Recibo",
+		"Table":                                        "Mesa",
+		"Date":                                         "Fecha",
+		"Total":                                        "Total",
+		"Subtotal":                                     "Subtotal",
+		"Discount":                                     "Descuento",
+		"Packaging":                                    "Envase",
+		"Delivery fee":                                 "Gastos de envío",
+		"REFUNDED":                                     "REEMBOLSADO",
+		"Payment":                                      "Pago",
+		"Status":                                       "Estado",
+		"PAID":                                         "PAGADO",
+		"PENDING":                                      "PENDIENTE",
+		"CARD":                                         "TARJETA",
+		"CASH":                                         "EFECTIVO",
+		"Thank you for your visit":                     "Gracias por su visita",
+		"Celery":                                       "Apio",
+		"Cereals containing gluten":                    "Cereales con gluten",
+		"Crustaceans":                                  "Crustáceos",
+		"Eggs":                                         "Huevos",
+		"Fish":                                         "Pescado",
+		"Lupin":                                        "Altramuces",
+		"Milk":                                         "Leche",
+		"Molluscs":                                     "Moluscos",
+		"Mustard":                                      "Mostaza",
+		"Tree nuts":                                    "Frutos de cáscara",
+		"Peanuts":                                      "Cacahuetes",
+		"Sesame seeds":                                 "Granos de sésamo",
+		"Soya":                                         "Soja",
+		"Sulphur dioxide and sulphites":                "Dióxido de azufre y sulfitos",
+		"A customer can not be merged into itself":                      "Un cliente no se puede fusionar consigo mismo",
+		"A customer with this phone or email already exists":            "Ya existe un cliente con este teléfono o correo",
+		"A refunded invoice can't change status":                        "Una factura reembolsada no puede cambiar de estado",
+		"Allergen %s already exists":                                    "El alérgeno %s ya existe",
+		"Allergen was not created":                                      "No se creó el alérgeno",
+		"Amount is more than the amount due":                            "El importe supera el importe pendiente",
+		"Area updation failed":                                          "No se pudo actualizar la zona",
+		"Area was not created":                                          "No se creó la zona",
+		"Both checks have loyalty discounts for different customers":    "Ambas cuentas tienen descuentos de fidelidad de clientes distintos",
+		"Break could not be ended":                                      "No se pudo terminar la pausa",
+		"Break could not be started":                                    "No se pudo iniciar la pausa",
+		"Clock in failed":                                               "No se pudo fichar la entrada",
+		"Clock out failed":                                              "No se pudo fichar la salida",
+		"Customer updation failed":                                      "No se pudo actualizar el cliente",
+		"Customer was already merged":                                   "El cliente ya se fusionó",
+		"Customer was not created":                                      "No se creó el cliente",
+		"Customer was not found":                                        "Cliente no encontrado",
+		"Day %s is already closed":                                      "El día %s ya está cerrado",
+		"Dead letters were not replayed":                                "No se reprocesaron los eventos fallidos",
+		"Delivery zone deletion failed":                                 "No se pudo eliminar la zona de reparto",
+		"Delivery zone updation failed":                                 "No se pudo actualizar la zona de reparto",
+		"Delivery zone was not created":                                 "No se creó la zona de reparto",
+		"Delivery zone was not found":                                   "Zona de reparto no encontrada",
+		"Discount is more than the amount due":                          "El descuento supera el importe pendiente",
+		"Error occured while checking allergens":                        "Error al comprobar los alérgenos",
+		"Error occured while exporting menus":                           "Error al exportar los menús",
+		"Error occured while fetching all tables":                       "Error al obtener las mesas",
+		"Error occured while fetching allergens":                        "Error al obtener los alérgenos",
+		"Error occured while fetching areas":                            "Error al obtener las zonas",
+		"Error occured while fetching customers":                        "Error al obtener los clientes",
+		"Error occured while fetching delivery zones":                   "Error al obtener las zonas de reparto",
+		"Error occured while fetching events":                           "Error al obtener los eventos",
+		"Error occured while fetching gift card":                        "Error al obtener la tarjeta regalo",
+		"Error occured while fetching gift card transactions":           "Error al obtener los movimientos de la tarjeta regalo",
+		"Error occured while fetching gift cards":                       "Error al obtener las tarjetas regalo",
+		"Error occured while fetching invoices":                         "Error al obtener las facturas",
+		"Error occured while fetching order item":                       "Error al obtener el artículo",
+		"Error occured while fetching order items":                      "Error al obtener los artículos",
+		"Error occured while fetching platform items":                   "Error al obtener los artículos de la plataforma",
+		"Error occured while fetching section assignments":              "Error al obtener las asignaciones de sector",
+		"Error occured while fetching shifts":                           "Error al obtener los turnos",
+		"Error occured while fetching staff":                            "Error al obtener el personal",
+		"Error occured while fetching table status":                     "Error al obtener el estado de las mesas",
+		"Error occured while fetching the audit log":                    "Error al obtener el registro de auditoría",
+		"Error occured while fetching the loyalty ledger":               "Error al obtener el historial de fidelidad",
+		"Error occured while fetching the loyalty program":              "Error al obtener el programa de fidelidad",
+		"Error occured while fetching the waitlist":                     "Error al obtener la lista de espera",
+		"Error occured while fetching time entries":                     "Error al obtener los fichajes",
+		"Error occured while fetching users":                            "Error al obtener los usuarios",
+		"Error occured while fetching visits":                           "Error al obtener las visitas",
+		"Error occured while fetching webhook":                          "Error al obtener el webhook",
+		"Error occured while fetching webhook deliveries":               "Error al obtener los envíos del webhook",
+		"Error occured while fetching webhook delivery":                 "Error al obtener el envío del webhook",
+		"Error occured while fetching webhooks":                         "Error al obtener los webhooks",
+		"Error occurred while checking the email":                       "Error al comprobar el correo",
+		"Error occurred while checking the phone":                       "Error al comprobar el teléfono",
+		"Event was not retried":                                         "No se reintentó el evento",
+		"Every item was selected, move the order instead":               "Se seleccionaron todos los artículos, mueva el pedido",
+		"Failed to create a table item":                                 "No se pudo crear la mesa",
+		"Food is not a loyalty reward":                                  "El plato no es una recompensa de fidelidad",
+		"Food is not on the menu right now":                             "El plato no está en la carta ahora mismo",
+		"Food update failed":                                            "No se pudo actualizar el plato",
+		"Gift card balance is too low":                                  "El saldo de la tarjeta regalo es insuficiente",
+		"Gift card has expired":                                         "La tarjeta regalo ha caducado",
+		"Gift card is void":                                             "La tarjeta regalo está anulada",
+		"Gift card was not found":                                       "Tarjeta regalo no encontrada",
+		"Guest session could not be created":                            "No se pudo crear la sesión de invitado",
+		"Guest token is Empty":                                          "El token de invitado está vacío",
+		"Image could not be decoded":                                    "No se pudo decodificar la imagen",
+		"Image could not be read":                                       "No se pudo leer la imagen",
+		"Image could not be stored":                                     "No se pudo guardar la imagen",
+		"Image dimensions are too large":                                "Las dimensiones de la imagen son demasiado grandes",
+		"Image is larger than %d bytes":                                 "La imagen supera los %d bytes",
+		"Image was not found":                                           "Imagen no encontrada",
+		"Invalid JSON: %s":                                              "JSON no válido: %s",
+		"Invalid email":                                                 "Correo no válido",
+		"Invoice has no customer":                                       "La factura no tiene cliente",
+		"Invoice is not awaiting payment":                               "La factura no está pendiente de pago",
+		"Kindly retype the time":                                        "Vuelva a escribir la hora",
+		"Loyalty program is not enabled":                                "El programa de fidelidad no está activado",
+		"Loyalty program updation failed":                               "No se pudo actualizar el programa de fidelidad",
+		"Menu import failed: %s":                                        "No se pudo importar los menús: %s",
+		"Menu update failed":                                            "No se pudo actualizar el menú",
+		"No failed event with that id":                                  "No hay ningún evento fallido con ese id",
+		"No free table fits this party":                                 "No hay ninguna mesa libre para este grupo",
+		"No translatable fields given":                                  "No se indicó ningún campo traducible",
+		"Not enough points":                                             "No hay suficientes puntos",
+		"Nothing to pay":                                                "No hay nada que pagar",
+		"Notification failed: %s":                                       "No se pudo enviar el aviso: %s",
+		"Only takeaway and delivery orders have a status":               "Solo los pedidos para llevar y a domicilio tienen estado",
+		"Order Item updation failed":                                    "No se pudo actualizar el artículo",
+		"Order can't move from %s to %s":                                "El pedido no puede pasar de %s a %s",
+		"Order has no items":                                            "El pedido no tiene artículos",
+		"Order is already on that table":                                "El pedido ya está en esa mesa",
+		"Order is already paid":                                         "El pedido ya está pagado",
+		"Order is closed, items can't be added":                         "El pedido está cerrado, no se pueden añadir artículos",
+		"Order is not waiting to be released":                           "El pedido no está pendiente de enviarse a cocina",
+		"Order is scheduled for %s and not released to the kitchen yet": "El pedido está programado para %s y aún no se ha enviado a cocina",
+		"Order item is already voided":                                  "El artículo ya está anulado",
+		"Order item was not found":                                      "Artículo no encontrado",
+		"Order status changed in the meantime":                          "El estado del pedido cambió mientras tanto",
+		"Order updation failed":                                         "No se pudo actualizar el pedido",
+		"Party has no phone or email":                                   "El grupo no tiene teléfono ni correo",
+		"Party is no longer waiting":                                    "El grupo ya no está esperando",
+		"Party was not added to the waitlist":                           "No se añadió el grupo a la lista de espera",
+		"Party was not found":                                           "Grupo no encontrado",
+		"Ping was not queued":                                           "No se puso el ping en cola",
+		"Platform is not configured":                                    "La plataforma no está configurada",
+		"Platform item deletion failed":                                 "No se pudo eliminar el artículo de la plataforma",
+		"Platform item was not saved":                                   "No se guardó el artículo de la plataforma",
+		"Points can only be redeemed on unpaid invoices":                "Los puntos solo se canjean en facturas sin pagar",
+		"QR code could not be generated":                                "No se pudo generar el código QR",
+		"Reward item is not on this order":                              "El artículo de recompensa no está en este pedido",
+		"Section assignment deletion failed":                            "No se pudo eliminar la asignación de sector",
+		"Section assignment was not created":                            "No se creó la asignación de sector",
+		"Section assignment was not found":                              "Asignación de sector no encontrada",
+		"Section is already assigned for part of this time":             "El sector ya está asignado durante parte de este tiempo",
+		"Send either points or food_id":                                 "Envíe points o food_id",
+		"Shift belongs to someone else":                                 "El turno es de otra persona",
+		"Shift deletion failed":                                         "No se pudo eliminar el turno",
+		"Shift updation failed":                                         "No se pudo actualizar el turno",
+		"Shift was not created":                                         "No se creó el turno",
+		"Shift was not found":                                           "Turno no encontrado",
+		"Some order items do not belong to this order":                  "Algunos artículos no pertenecen a este pedido",
+		"Source table has no open order":                                "La mesa de origen no tiene un pedido abierto",
+		"Staff member is already clocked in":                            "El empleado ya ha fichado la entrada",
+		"Staff member is already on a break":                            "El empleado ya está en pausa",
+		"Staff member is not active":                                    "El empleado no está activo",
+		"Staff member is not clocked in":                                "El empleado no ha fichado la entrada",
+		"Staff member is not on a break":                                "El empleado no está en pausa",
+		"Staff member was not created":                                  "No se creó el empleado",
+		"Staff member was not found":                                    "Empleado no encontrado",
+		"Staff updation failed":                                         "No se pudo actualizar el personal",
+		"Status can only be set to WAITING, CANCELLED or NO_SHOW":       "El estado solo puede ser WAITING, CANCELLED o NO_SHOW",
+		"Table already has an open order":                               "La mesa ya tiene un pedido abierto",
+		"Table already has an open order, merge the tables instead":     "La mesa ya tiene un pedido abierto, una las mesas",
+		"Table is not free":                                             "La mesa no está libre",
+		"Table is too small for this party":                             "La mesa es demasiado pequeña para este grupo",
+		"Table updation failed":                                         "No se pudo actualizar la mesa",
+		"The %s slot is full":                                           "La franja de las %s está completa",
+		"The QR code is invalid":                                        "El código QR no es válido",
+		"The day hasn't started yet":                                    "El día aún no ha empezado",
+		"The file field is required":                                    "El campo file es obligatorio",
+		"The guest session is invalid or has expired":                   "La sesión de invitado no es válida o ha caducado",
+		"The image field is required":                                   "El campo image es obligatorio",
+		"The token is invalid":                                          "El token no es válido",
+		"This email or phone number already in Use":                     "Este correo o teléfono ya está en uso",
+		"Token is Expaired":                                             "El token ha caducado",
+		"Too many requests":                                             "Demasiadas solicitudes",
+		"Translation update failed":                                     "No se pudo actualizar la traducción",
+		"Unable to get all the order items":                             "No se pudieron obtener todos los artículos",
+		"Unknown allergen %s":                                           "Alérgeno desconocido %s",
+		"Unknown dietary tag %s":                                        "Etiqueta dietética desconocida %s",
+		"Unmapped items: %s":                                            "Artículos sin asociar: %s",
+		"Unsupported image type %s":                                     "Tipo de imagen no admitido %s",
+		"Unsupported locale %s":                                         "Idioma no admitido %s",
+		"User not created":                                              "No se creó el usuario",
+		"User update failed":                                            "No se pudo actualizar el usuario",
+		"User was not found":                                            "Usuario no encontrado",
+		"Waitlist updation failed":                                      "No se pudo actualizar la lista de espera",
+		"We don't deliver to %s":                                        "No repartimos a %s",
+		"Webhook deletion failed":                                       "No se pudo eliminar el webhook",
+		"Webhook delivery was not found":                                "Envío del webhook no encontrado",
+		"Webhook delivery was not replayed":                             "No se reenvió el envío del webhook",
+		"Webhook updation failed":                                       "No se pudo actualizar el webhook",
+		"Webhook was not created":                                       "No se creó el webhook",
+		"Webhook was not found":                                         "Webhook no encontrado",
+		"birthday must be a date like 2006-01-02":                       "birthday debe ser una fecha como 2006-01-02",
+		"contact_name and contact_phone are required":                   "contact_name y contact_phone son obligatorios",
+		"course must be one of %s":                                      "course debe ser uno de %s",
+		"covers must be at least 1":                                     "covers debe ser al menos 1",
+		"date must look like 2006-01-02":                                "date debe tener el formato 2006-01-02",
+		"delivery_address is required":                                  "delivery_address es obligatorio",
+		"double_point_days must be weekdays or dates like 2006-01-02":   "double_point_days debe contener días de la semana o fechas como 2006-01-02",
+		"end_time must be after start_time":                             "end_time debe ser posterior a start_time",
+		"expires_at must be in the future":                              "expires_at debe estar en el futuro",
+		"from and to must be dates like 2006-01-02":                     "from y to deben ser fechas como 2006-01-02",
+		"from must be a date or an RFC3339 time":                        "from debe ser una fecha o una hora RFC3339",
+		"login or password is incorrect":                                "usuario o contraseña incorrectos",
+		"order_items can't be empty":                                    "order_items no puede estar vacío",
+		"packaging_fee can't be negative":                               "packaging_fee no puede ser negativo",
+		"party_size is required":                                        "party_size es obligatorio",
+		"party_size must be at least 1":                                 "party_size debe ser al menos 1",
+		"pickup_time must be in the future":                             "pickup_time debe estar en el futuro",
+		"prep_minutes must be at least 1":                               "prep_minutes debe ser al menos 1",
+		"q must be at least 2 characters":                               "q debe tener al menos 2 caracteres",
+		"scheduled_for must be in the future":                           "scheduled_for debe estar en el futuro",
+		"start_time and end_time are required and end_time must be after start_time": "start_time y end_time son obligatorios y end_time debe ser posterior a start_time",
+		"tip can not be negative":              "tip no puede ser negativo",
+		"to must be a date or an RFC3339 time": "to debe ser una fecha o una hora RFC3339",
+		"type must be TAKEAWAY or DELIVERY":    "type debe ser TAKEAWAY o DELIVERY",
+		"unit_price is required":               "unit_price es obligatorio",
+		"unknown event %q, use one of %s or *": "evento desconocido %q, use uno de %s o *",
+	},
+	"de": {
+		"Token is Empty":                               "Das Token ist leer",
+		"Error occured while fetching":                 "Fehler beim Abrufen",
+		"Error occured while fetching the menu":        "Fehler beim Abrufen der Speisekarte",
+		"Error occured while fetching orders":          "Fehler beim Abrufen der Bestellungen",
+		"Error occured while fetching invoice":         "Fehler beim Abrufen der Rechnung",
+		"Menu not founf":                               "Speisekarte nicht gefunden",
+		"Menu was not found":                           "Speisekarte nicht gefunden",
+		"Food was not found":                           "Gericht nicht gefunden",
+		"Order was not found":                          "Bestellung nicht gefunden",
+		"Table wasn't found":                           "Tisch nicht gefunden",
+		"Table was not found":                          "Tisch nicht gefunden",
+		"Invoice was not found":                        "Rechnung nicht gefunden",
+		"Food item was not created":                    "Gericht wurde nicht angelegt",
+		"Failed to create an order":                    "Bestellung konnte nicht angelegt werden",
+		"Failed to create order items":                 "Positionen konnten nicht angelegt werden",
+		"Order items conflict with declared allergies": "Positionen enthalten angegebene Allergene",
+		"user not found, login seems to be incorrect":  "Benutzer nicht gefunden, Anmeldung fehlerhaft",
+		"Translation was not found":                    "Übersetzung nicht gefunden",
+		"Unsupported entity":                           "Nicht unterstützter Entitätstyp",
+		"Receipt":                                      "Beleg",
+		"Table":                                        "Tisch",
+		"Date":                                         "Datum",
+		"Total":                                        "Summe",
+		"Subtotal":                                     "Zwischensumme",
+		"Discount":                                     "Rabatt",
+		"Packaging":                                    "Verpackung",
+		"Delivery fee":                                 "Liefergebühr",
+		"REFUNDED":                                     "ERSTATTET",
+		"Payment":                                      "Zahlung",
+		"Status":                                       "Status",
+		"PAID":                                         "BEZAHLT",
+		"PENDING":                                      "OFFEN",
+		"CARD":                                         "KARTE",
+		"CASH":                                         "BAR",
+		"Thank you for your visit":                     "Vielen Dank für Ihren Besuch",
+		"Celery":                                       "Sellerie",
+		"Cereals containing gluten":                    "Glutenhaltiges Getreide",
+		"Crustaceans":                                  "Krebstiere",
+		"Eggs":                                         "Eier",
+		"Fish":                                         "Fisch",
+		"Lupin":                                        "Lupinen",
+		"Milk":                                         "Milch",
+		"Molluscs":                                     "Weichtiere",
+		"Mustard":                                      "Senf",
+		"Tree nuts":                                    "Schalenfrüchte",
+		"Peanuts":                                      "Erdnüsse",
+		"Sesame seeds":                                 "Sesamsamen",
+		"Soya":                                         "Soja",
+		"Sulphur dioxide and sulphites":                "Schwefeldioxid und Sulfite",
+		"A customer can not be merged into itself":                      "Ein Kunde kann nicht mit sich selbst zusammengeführt werden",
+		"A customer with this phone or email already exists":            "Ein Kunde mit dieser Telefonnummer oder E-Mail existiert bereits",
+		"A refunded invoice can't change status":                        "Eine erstattete Rechnung kann ihren Status nicht ändern",
+		"Allergen %s already exists":                                    "Das Allergen %s existiert bereits",
+		"Allergen was not created":                                      "Allergen wurde nicht angelegt",
+		"Amount is more than the amount due":                            "Der Betrag ist höher als der offene Betrag",
+		"Area updation failed":                                          "Bereich konnte nicht aktualisiert werden",
+		"Area was not created":                                          "Bereich wurde nicht angelegt",
+		"Both checks have loyalty discounts for different customers":    "Beide Rechnungen haben Treuerabatte verschiedener Kunden",
+		"Break could not be ended":                                      "Pause konnte nicht beendet werden",
+		"Break could not be started":                                    "Pause konnte nicht begonnen werden",
+		"Clock in failed":                                               "Einstempeln fehlgeschlagen",
+		"Clock out failed":                                              "Ausstempeln fehlgeschlagen",
+		"Customer updation failed":                                      "Kunde konnte nicht aktualisiert werden",
+		"Customer was already merged":                                   "Der Kunde wurde bereits zusammengeführt",
+		"Customer was not created":                                      "Kunde wurde nicht angelegt",
+		"Customer was not found":                                        "Kunde nicht gefunden",
+		"Day %s is already closed":                                      "Der Tag %s ist bereits abgeschlossen",
+		"Dead letters were not replayed":                                "Fehlgeschlagene Ereignisse wurden nicht erneut verarbeitet",
+		"Delivery zone deletion failed":                                 "Liefergebiet konnte nicht gelöscht werden",
+		"Delivery zone updation failed":                                 "Liefergebiet konnte nicht aktualisiert werden",
+		"Delivery zone was not created":                                 "Liefergebiet wurde nicht angelegt",
+		"Delivery zone was not found":                                   "Liefergebiet nicht gefunden",
+		"Discount is more than the amount due":                          "Der Rabatt ist höher als der offene Betrag",
+		"Error occured while checking allergens":                        "Fehler beim Prüfen der Allergene",
+		"Error occured while exporting menus":                           "Fehler beim Exportieren der Speisekarten",
+		"Error occured while fetching all tables":                       "Fehler beim Abrufen der Tische",
+		"Error occured while fetching allergens":                        "Fehler beim Abrufen der Allergene",
+		"Error occured while fetching areas":                            "Fehler beim Abrufen der Bereiche",
+		"Error occured while fetching customers":                        "Fehler beim Abrufen der Kunden",
+		"Error occured while fetching delivery zones":                   "Fehler beim Abrufen der Liefergebiete",
+		"Error occured while fetching events":                           "Fehler beim Abrufen der Ereignisse",
+		"Error occured while fetching gift card":                        "Fehler beim Abrufen der Geschenkkarte",
+		"Error occured while fetching gift card transactions":           "Fehler beim Abrufen der Geschenkkartenbuchungen",
+		"Error occured while fetching gift cards":                       "Fehler beim Abrufen der Geschenkkarten",
+		"Error occured while fetching invoices":                         "Fehler beim Abrufen der Rechnungen",
+		"Error occured while fetching order item":                       "Fehler beim Abrufen der Position",
+		"Error occured while fetching order items":                      "Fehler beim Abrufen der Positionen",
+		"Error occured while fetching platform items":                   "Fehler beim Abrufen der Plattformartikel",
+		"Error occured while fetching section assignments":              "Fehler beim Abrufen der Revierzuteilungen",
+		"Error occured while fetching shifts":                           "Fehler beim Abrufen der Schichten",
+		"Error occured while fetching staff":                            "Fehler beim Abrufen des Personals",
+		"Error occured while fetching table status":                     "Fehler beim Abrufen des Tischstatus",
+		"Error occured while fetching the audit log":                    "Fehler beim Abrufen des Prüfprotokolls",
+		"Error occured while fetching the loyalty ledger":               "Fehler beim Abrufen des Treuekontos",
+		"Error occured while fetching the loyalty program":              "Fehler beim Abrufen des Treueprogramms",
+		"Error occured while fetching the waitlist":                     "Fehler beim Abrufen der Warteliste",
+		"Error occured while fetching time entries":                     "Fehler beim Abrufen der Zeiteinträge",
+		"Error occured while fetching users":                            "Fehler beim Abrufen der Benutzer",
+		"Error occured while fetching visits":                           "Fehler beim Abrufen der Besuche",
+		"Error occured while fetching webhook":                          "Fehler beim Abrufen des Webhooks",
+		"Error occured while fetching webhook deliveries":               "Fehler beim Abrufen der Webhook-Zustellungen",
+		"Error occured while fetching webhook delivery":                 "Fehler beim Abrufen der Webhook-Zustellung",
+		"Error occured while fetching webhooks":                         "Fehler beim Abrufen der Webhooks",
+		"Error occurred while checking the email":                       "Fehler beim Prüfen der E-Mail",
+		"Error occurred while checking the phone":                       "Fehler beim Prüfen der Telefonnummer",
+		"Event was not retried":                                         "Ereignis wurde nicht erneut versucht",
+		"Every item was selected, move the order instead":               "Alle Positionen sind ausgewählt, verschieben Sie stattdessen die Bestellung",
+		"Failed to create a table item":                                 "Tisch konnte nicht angelegt werden",
+		"Food is not a loyalty reward":                                  "Das Gericht ist keine Treueprämie",
+		"Food is not on the menu right now":                             "Das Gericht steht gerade nicht auf der Karte",
+		"Food update failed":                                            "Gericht konnte nicht aktualisiert werden",
+		"Gift card balance is too low":                                  "Das Guthaben der Geschenkkarte reicht nicht aus",
+		"Gift card has expired":                                         "Die Geschenkkarte ist abgelaufen",
+		"Gift card is void":                                             "Die Geschenkkarte ist ungültig",
+		"Gift card was not found":                                       "Geschenkkarte nicht gefunden",
+		"Guest session could not be created":                            "Gastsitzung konnte nicht angelegt werden",
+		"Guest token is Empty":                                          "Das Gast-Token ist leer",
+		"Image could not be decoded":                                    "Bild konnte nicht dekodiert werden",
+		"Image could not be read":                                       "Bild konnte nicht gelesen werden",
+		"Image could not be stored":                                     "Bild konnte nicht gespeichert werden",
+		"Image dimensions are too large":                                "Die Bildabmessungen sind zu groß",
+		"Image is larger than %d bytes":                                 "Das Bild ist größer als %d Bytes",
+		"Image was not found":                                           "Bild nicht gefunden",
+		"Invalid JSON: %s":                                              "Ungültiges JSON: %s",
+		"Invalid email":                                                 "Ungültige E-Mail",
+		"Invoice has no customer":                                       "Die Rechnung hat keinen Kunden",
+		"Invoice is not awaiting payment":                               "Die Rechnung wartet nicht auf Zahlung",
+		"Kindly retype the time":                                        "Bitte geben Sie die Zeit erneut ein",
+		"Loyalty program is not enabled":                                "Das Treueprogramm ist nicht aktiviert",
+		"Loyalty program updation failed":                               "Treueprogramm konnte nicht aktualisiert werden",
+		"Menu import failed: %s":                                        "Import der Speisekarten fehlgeschlagen: %s",
+		"Menu update failed":                                            "Speisekarte konnte nicht aktualisiert werden",
+		"No failed event with that id":                                  "Kein fehlgeschlagenes Ereignis mit dieser ID",
+		"No free table fits this party":                                 "Kein freier Tisch passt zu dieser Gruppe",
+		"No translatable fields given":                                  "Keine übersetzbaren Felder angegeben",
+		"Not enough points":                                             "Nicht genug Punkte",
+		"Nothing to pay":                                                "Nichts zu bezahlen",
+		"Notification failed: %s":                                       "Benachrichtigung fehlgeschlagen: %s",
+		"Only takeaway and delivery orders have a status":               "Nur Abhol- und Lieferbestellungen haben einen Status",
+		"Order Item updation failed":                                    "Position konnte nicht aktualisiert werden",
+		"Order can't move from %s to %s":                                "Die Bestellung kann nicht von %s zu %s wechseln",
+		"Order has no items":                                            "Die Bestellung hat keine Positionen",
+		"Order is already on that table":                                "Die Bestellung ist bereits an diesem Tisch",
+		"Order is already paid":                                         "Die Bestellung ist bereits bezahlt",
+		"Order is closed, items can't be added":                         "Die Bestellung ist abgeschlossen, es können keine Positionen hinzugefügt werden",
+		"Order is not waiting to be released":                           "Die Bestellung wartet nicht auf Freigabe",
+		"Order is scheduled for %s and not released to the kitchen yet": "Die Bestellung ist für %s geplant und noch nicht an die Küche freigegeben",
+		"Order item is already voided":                                  "Die Position ist bereits storniert",
+		"Order item was not found":                                      "Position nicht gefunden",
+		"Order status changed in the meantime":                          "Der Bestellstatus hat sich inzwischen geändert",
+		"Order updation failed":                                         "Bestellung konnte nicht aktualisiert werden",
+		"Party has no phone or email":                                   "Die Gruppe hat weder Telefonnummer noch E-Mail",
+		"Party is no longer waiting":                                    "Die Gruppe wartet nicht mehr",
+		"Party was not added to the waitlist":                           "Die Gruppe wurde nicht zur Warteliste hinzugefügt",
+		"Party was not found":                                           "Gruppe nicht gefunden",
+		"Ping was not queued":                                           "Ping wurde nicht eingereiht",
+		"Platform is not configured":                                    "Die Plattform ist nicht eingerichtet",
+		"Platform item deletion failed":                                 "Plattformartikel konnte nicht gelöscht werden",
+		"Platform item was not saved":                                   "Plattformartikel wurde nicht gespeichert",
+		"Points can only be redeemed on unpaid invoices":                "Punkte können nur bei unbezahlten Rechnungen eingelöst werden",
+		"QR code could not be generated":                                "QR-Code konnte nicht erzeugt werden",
+		"Reward item is not on this order":                              "Die Prämie ist nicht in dieser Bestellung",
+		"Section assignment deletion failed":                            "Revierzuteilung konnte nicht gelöscht werden",
+		"Section assignment was not created":                            "Revierzuteilung wurde nicht angelegt",
+		"Section assignment was not found":                              "Revierzuteilung nicht gefunden",
+		"Section is already assigned for part of this time":             "Das Revier ist für einen Teil dieser Zeit bereits zugeteilt",
+		"Send either points or food_id":                                 "Senden Sie entweder points oder food_id",
+		"Shift belongs to someone else":                                 "Die Schicht gehört jemand anderem",
+		"Shift deletion failed":                                         "Schicht konnte nicht gelöscht werden",
+		"Shift updation failed":                                         "Schicht konnte nicht aktualisiert werden",
+		"Shift was not created":                                         "Schicht wurde nicht angelegt",
+		"Shift was not found":                                           "Schicht nicht gefunden",
+		"Some order items do not belong to this order":                  "Einige Positionen gehören nicht zu dieser Bestellung",
+		"Source table has no open order":                                "Der Ausgangstisch hat keine offene Bestellung",
+		"Staff member is already clocked in":                            "Der Mitarbeiter ist bereits eingestempelt",
+		"Staff member is already on a break":                            "Der Mitarbeiter ist bereits in der Pause",
+		"Staff member is not active":                                    "Der Mitarbeiter ist nicht aktiv",
+		"Staff member is not clocked in":                                "Der Mitarbeiter ist nicht eingestempelt",
+		"Staff member is not on a break":                                "Der Mitarbeiter ist nicht in der Pause",
+		"Staff member was not created":                                  "Mitarbeiter wurde nicht angelegt",
+		"Staff member was not found":                                    "Mitarbeiter nicht gefunden",
+		"Staff updation failed":                                         "Personal konnte nicht aktualisiert werden",
+		"Status can only be set to WAITING, CANCELLED or NO_SHOW":       "Der Status kann nur WAITING, CANCELLED oder NO_SHOW sein",
+		"Table already has an open order":                               "Der Tisch hat bereits eine offene Bestellung",
+		"Table already has an open order, merge the tables instead":     "Der Tisch hat bereits eine offene Bestellung, führen Sie stattdessen die Tische zusammen",
+		"Table is not free":                                             "Der Tisch ist nicht frei",
+		"Table is too small for this party":                             "Der Tisch ist zu klein für diese Gruppe",
+		"Table updation failed":                                         "Tisch konnte nicht aktualisiert werden",
+		"The %s slot is full":                                           "Das Zeitfenster um %s ist voll",
+		"The QR code is invalid":                                        "Der QR-Code ist ungültig",
+		"The day hasn't started yet":                                    "Der Tag hat noch nicht begonnen",
+		"The file field is required":                                    "Das Feld file ist erforderlich",
+		"The guest session is invalid or has expired":                   "Die Gastsitzung ist ungültig oder abgelaufen",
+		"The image field is required":                                   "Das Feld image ist erforderlich",
+		"The token is invalid":                                          "Das Token ist ungültig",
+		"This email or phone number already in Use":                     "Diese E-Mail oder Telefonnummer wird bereits verwendet",
+		"Token is Expaired":                                             "Das Token ist abgelaufen",
+		"Too many requests":                                             "Zu viele Anfragen",
+		"Translation update failed":                                     "Übersetzung konnte nicht aktualisiert werden",
+		"Unable to get all the order items":                             "Es konnten nicht alle Positionen abgerufen werden",
+		"Unknown allergen %s":                                           "Unbekanntes Allergen %s",
+		"Unknown dietary tag %s":                                        "Unbekannte Ernährungsangabe %s",
+		"Unmapped items: %s":                                            "Nicht zugeordnete Artikel: %s",
+		"Unsupported image type %s":                                     "Nicht unterstützter Bildtyp %s",
+		"Unsupported locale %s":                                         "Nicht unterstützte Sprache %s",
+		"User not created":                                              "Benutzer wurde nicht angelegt",
+		"User update failed":                                            "Benutzer konnte nicht aktualisiert werden",
+		"User was not found":                                            "Benutzer nicht gefunden",
+		"Waitlist updation failed":                                      "Warteliste konnte nicht aktualisiert werden",
+		"We don't deliver to %s":                                        "Wir liefern nicht nach %s",
+		"Webhook deletion failed":                                       "Webhook konnte nicht gelöscht werden",
+		"Webhook delivery was not found":                                "Webhook-Zustellung nicht gefunden",
+		"Webhook delivery was not replayed":                             "Webhook-Zustellung wurde nicht erneut gesendet",
+		"Webhook updation failed":                                       "Webhook konnte nicht aktualisiert werden",
+		"Webhook was not created":                                       "Webhook wurde nicht angelegt",
+		"Webhook was not found":                                         "Webhook nicht gefunden",
+		"birthday must be a date like 2006-01-02":                       "birthday muss ein Datum wie 2006-01-02 sein",
+		"contact_name and contact_phone are required":                   "contact_name und contact_phone sind erforderlich",
+		"course must be one of %s":                                      "course muss einer von %s sein",
+		"covers must be at least 1":                                     "covers muss mindestens 1 sein",
+		"date must look like 2006-01-02":                                "date muss wie 2006-01-02 aussehen",
+		"delivery_address is required":                                  "delivery_address ist erforderlich",
+		"double_point_days must be weekdays or dates like 2006-01-02":   "double_point_days muss Wochentage oder Daten wie 2006-01-02 enthalten",
+		"end_time must be after start_time":                             "end_time muss nach start_time liegen",
+		"expires_at must be in the future":                              "expires_at muss in der Zukunft liegen",
+		"from and to must be dates like 2006-01-02":                     "from und to müssen Daten wie 2006-01-02 sein",
+		"from must be a date or an RFC3339 time":                        "from muss ein Datum oder eine RFC3339-Zeit sein",
+		"login or password is incorrect":                                "Anmeldename oder Passwort ist falsch",
+		"order_items can't be empty":                                    "order_items darf nicht leer sein",
+		"packaging_fee can't be negative":                               "packaging_fee darf nicht negativ sein",
+		"party_size is required":                                        "party_size ist erforderlich",
+		"party_size must be at least 1":                                 "party_size muss mindestens 1 sein",
+		"pickup_time must be in the future":                             "pickup_time muss in der Zukunft liegen",
+		"prep_minutes must be at least 1":                               "prep_minutes muss mindestens 1 sein",
+		"q must be at least 2 characters":                               "q muss mindestens 2 Zeichen lang sein",
+		"scheduled_for must be in the future":                           "scheduled_for muss in der Zukunft liegen",
+		"start_time and end_time are required and end_time must be after start_time": "start_time und end_time sind erforderlich und end_time muss nach start_time liegen",
+		"tip can not be negative":              "tip darf nicht negativ sein",
+		"to must be a date or an RFC3339 time": "to muss ein Datum oder eine RFC3339-Zeit sein",
+		"type must be TAKEAWAY or DELIVERY":    "type muss TAKEAWAY oder DELIVERY sein",
+		"unit_price is required":               "unit_price ist erforderlich",
+		"unknown event %q, use one of %s or *": "unbekanntes Ereignis %q, verwenden Sie %s oder *",
+	},
+}
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCatalogLocalesHaveTheSameKeys(t *testing.T) {
+	for key := range catalog["fr"] {
+		for _, locale := range []string{"es", "de"} {
+			if _, ok := catalog[locale][key]; !ok {
+				t.Errorf("%s has no %q", locale, key)
+			}
+		}
+	}
+	if len(catalog["es"]) != len(catalog["fr"]) || len(catalog["de"]) != len(catalog["fr"]) {
+		t.Errorf("fr has %d keys, es %d, de %d", len(catalog["fr"]), len(catalog["es"]), len(catalog["de"]))
+	}
+}
+
+func TestCatalogTranslationsKeepTheVerbs(t *testing.T) {
+	for locale, messages := range catalog {
+		for key, value := range messages {
+			want := templateVerb.FindAllString(key, -1)
+			if got := templateVerb.FindAllString(value, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %q has verbs %v, want %v", locale, key, got, want)
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		message string
+		locales []string
+		want    string
+	}{
+		{"Order was not found", []string{"fr"}, "Commande introuvable"},
+		{"Order was not found", []string{"en", "fr"}, "Order was not found"},
+		{"Order was not found", []string{"it", "de"}, "Bestellung nicht gefunden"},
+		{"The 12:30 slot is full", []string{"fr"}, "Le créneau de 12:30 est complet"},
+		{"Order can't move from READY to PENDING", []string{"es"}, "El pedido no puede pasar de READY a PENDING"},
+		{"Image is larger than 5242880 bytes", []string{"de"}, "Das Bild ist größer als 5242880 Bytes"},
+		{`unknown event "order.eaten", use one of order.created or *`, []string{"fr"}, `événement inconnu "order.eaten", utilisez order.created ou *`},
+		{"Image is larger than many bytes", []string{"de"}, "Image is larger than many bytes"},
+		{"Something nobody translated", []string{"fr"}, "Something nobody translated"},
+	}
+	for _, c := range cases {
+		if got := Translate(c.message, c.locales); got != c.want {
+			t.Errorf("%q in %v: got %q, want %q", c.message, c.locales, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var ThumbnailWidths = []int{128, 320, 640}
+
+var AllowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+const MaxImagePixels = 40000000
+
+type ProcessedImage struct {
+	ContentType string
+	Extension   string
+	Original    []byte
+	Thumbnails  map[int][]byte
+}
+
+// ProcessImage checks the upload really is an allowed image and renders
+// one thumbnail per ThumbnailWidths entry, never upscaling
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := AllowedImageTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported image type %s", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("Image could not be read")
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, errors.New("Image dimensions are too large")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("Image could not be decoded")
+	}
+
+	processed := &ProcessedImage{
+		ContentType: contentType,
+		Extension:   ext,
+		Original:    data,
+		Thumbnails:  map[int][]byte{},
+	}
+
+	for _, width := range ThumbnailWidths {
+		thumb := resize(src, width)
+		var buf bytes.Buffer
+		switch contentType {
+		case "image/png":
+			err = png.Encode(&buf, thumb)
+		case "image/gif":
+			err = gif.Encode(&buf, thumb, nil)
+		default:
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, err
+		}
+		processed.Thumbnails[width] = buf.Bytes()
+	}
+	return processed, nil
+}
+
+// resize box-filters src down to the given width keeping the aspect ratio
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= width {
+		return src
+	}
+	height := srcH * width / srcW
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := bounds.Min.Y + (y+1)*srcH/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := bounds.Min.X + (x+1)*srcW/width
+			if x1 == x0 {
+				x1++
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"context"
+	"fmt"
 	"infinity/rms/database"
 	"infinity/rms/models"
 	"log"
@@ -42,12 +43,12 @@ func GenerateAllTokens(user *models.User) (string, string, error) {
 		},
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString([]byte(SECRETKEY))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRETKEY))
 	if err != nil {
 		log.Panic(err)
 	}
 
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodES256, refreshClaims).SignedString([]byte(SECRETKEY))
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRETKEY))
 	if err != nil {
 		log.Panic(err)
 	}
@@ -92,9 +93,16 @@ func ValidateToken(signedToken string) (claims * SignedDetails, msg string) {
 		signedToken,
 		&SignedDetails{},
 		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method")
+			}
 			return []byte(SECRETKEY), nil
 		},
 	)
+	if err != nil {
+		msg = err.Error()
+		return
+	}
 
 	claims, ok := token.Claims.(*SignedDetails)
 	if !ok {
@@ -107,7 +115,5 @@ func ValidateToken(signedToken string) (claims * SignedDetails, msg string) {
 		return
 	}
 
-	msg = err.Error()
-
 	return claims, msg
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"log"
 	"os"
+	"time"
 
+	controller "infinity/rms/controllers"
+	"infinity/rms/database"
 	middleware "infinity/rms/middleware"
+	"infinity/rms/migrations"
 	routes "infinity/rms/routes"
+	"infinity/rms/scheduler"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,9 +23,34 @@ func main() {
 		port = "8000"
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := database.RequireTransactions(ctx)
+	cancel()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// MIGRATE_ON_START=false leaves migrations to rmsctl migrate
+	if os.Getenv("MIGRATE_ON_START") != "false" {
+		if err := migrations.New(database.OpenDatabase(database.Client)).Up(context.Background(), 0); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	go scheduler.Every(context.Background(), time.Minute, "release scheduled orders", controller.ReleaseScheduledOrders)
+	go scheduler.Every(context.Background(), time.Minute, "alert late kitchen tickets", controller.AlertLateTickets)
+	go scheduler.Every(context.Background(), 5*time.Second, "dispatch events", controller.DispatchEvents)
+	go scheduler.Every(context.Background(), 15*time.Second, "deliver webhooks", controller.DeliverWebhooks)
+
 	router := gin.New()
 	router.Use(gin.Logger())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Locale())
+	router.Use(middleware.Audit())
 	routes.UserRoutes(router)
+	routes.PublicImageRoutes(router)
+	routes.GuestRoutes(router)
+	routes.PlatformWebhookRoutes(router)
 	router.Use(middleware.Auth())
 
 	routes.FoodRoutes(router)
@@ -27,6 +59,22 @@ func main() {
 	routes.OrderRoutes(router)
 	routes.OrderItemRoutes(router)
 	routes.TableRoutes(router)
+	routes.AllergenRoutes(router)
+	routes.KitchenRoutes(router)
+	routes.ImageRoutes(router)
+	routes.TranslationRoutes(router)
+	routes.FloorRoutes(router)
+	routes.WaitlistRoutes(router)
+	routes.StaffRoutes(router)
+	routes.ServerRoutes(router)
+	routes.CustomerRoutes(router)
+	routes.LoyaltyRoutes(router)
+	routes.GiftCardRoutes(router)
+	routes.DeliveryRoutes(router)
+	routes.PlatformRoutes(router)
+	routes.WebhookRoutes(router)
+	routes.EventRoutes(router)
+	routes.AuditRoutes(router)
 
 	router.Run(":" + port)
 }
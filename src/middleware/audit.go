@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"infinity/rms/database"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var auditCollection *mongo.Collection = database.OpenCollection(database.Client, "auditLog")
+
+type auditedEntity struct {
+	collection string
+	idField    string
+	param      string
+}
+
+// auditedEntities maps the first path segment to the documents it changes
+var auditedEntities = map[string]auditedEntity{
+	"allergens":      {"allergen", "allergen_id", "allergen_id"},
+	"areas":          {"area", "area_id", "area_id"},
+	"customers":      {"customer", "customer_id", "customer_id"},
+	"delivery-zones": {"deliveryZone", "zone_id", "zone_id"},
+	"foods":          {"food", "food_id", "food_id"},
+	"gift-cards":     {"giftCard", "code", "code"},
+	"invoices":       {"invoice", "invoice_id", "invoice_id"},
+	"menus":          {"menu", "menu_id", "menu_id"},
+	"orders":         {"order", "order_id", "order_id"},
+	"orderItems":     {"orderItem", "order_item_id", "orderItem_id"},
+	"sections":       {"sectionAssignment", "assignment_id", "assignment_id"},
+	"shifts":         {"shift", "shift_id", "shift_id"},
+	"staff":          {"staff", "staff_id", "staff_id"},
+	"tables":         {"table", "table_id", "table_id"},
+	"users":          {"users", "user_id", "user_id"},
+	"waitlist":       {"waitlist", "waitlist_id", "waitlist_id"},
+	"webhooks":       {"webhook", "webhook_id", "webhook_id"},
+}
+
+// ids of entities that are stored in a canonical form, the way their
+// handlers look them up
+var auditIdNormalizers = map[string]func(string) string{
+	"gift-cards": helpers.NormalizeGiftCardCode,
+}
+
+// routes that change nothing they could diff, logged under their own
+// action
+var auditActions = map[string]string{"/users/login": "LOGIN"}
+
+// fields that never go into the log
+var auditRedacted = map[string]bool{"password": true, "token": true, "refresh_token": true, "secret": true}
+
+// Audit appends an entry to the audit log for every successful POST,
+// PUT, PATCH and DELETE, with a field by field diff of the document it
+// changed. It runs before authentication, so requests nobody is logged
+// in for are logged too, with the kind of caller as the actor.
+func Audit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			ctx.Next()
+			return
+		}
+
+		route := ctx.FullPath()
+		segments := strings.Split(strings.Trim(route, "/"), "/")
+		entityName := segments[0]
+		entityId := ""
+		if entityName == "translations" {
+			entityName = ctx.Param("entity")
+			entityId = ctx.Param("entity_id")
+		}
+		entity, known := auditedEntities[entityName]
+		if known && entityId == "" {
+			entityId = ctx.Param(entity.param)
+		}
+		if normalize, ok := auditIdNormalizers[entityName]; ok && entityId != "" {
+			entityId = normalize(entityId)
+		}
+
+		curCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var before bson.M
+		if known && entityId != "" {
+			before = loadAudited(curCtx, entity, bson.M{entity.idField: entityId})
+		}
+
+		writer := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		if ctx.Writer.Status() >= 400 {
+			return
+		}
+
+		entry := models.AuditEntry{
+			Method:    ctx.Request.Method,
+			Route:     route,
+			Path:      ctx.Request.URL.Path,
+			Entity:    entityName,
+			EntityID:  entityId,
+			Actor:     auditActor(ctx, route),
+			UserID:    ctx.GetString("uid"),
+			UserEmail: ctx.GetString("email"),
+			UserName:  strings.TrimSpace(ctx.GetString("first_name") + " " + ctx.GetString("last_name")),
+			RequestID: ctx.GetString("request_id"),
+			IP:        ctx.ClientIP(),
+			Status:    ctx.Writer.Status(),
+		}
+
+		if action, ok := auditActions[route]; ok {
+			entry.Action = action
+			recordAudit(curCtx, entry, nil, nil)
+			return
+		}
+		if !known {
+			recordAudit(curCtx, entry, nil, nil)
+			return
+		}
+		if entityId != "" {
+			recordAudit(curCtx, entry, before, loadAudited(curCtx, entity, bson.M{entity.idField: entityId}))
+			return
+		}
+
+		// creates only learn the new id from the response
+		created := createdIds(writer.body.Bytes(), entity.idField)
+		if len(created) == 0 {
+			recordAudit(curCtx, entry, nil, nil)
+			return
+		}
+		for _, filter := range created {
+			after := loadAudited(curCtx, entity, filter)
+			if id, ok := after[entity.idField].(string); ok {
+				entry.EntityID = id
+			}
+			recordAudit(curCtx, entry, nil, after)
+		}
+	}
+}
+
+// auditActor is "user" for a logged in user and otherwise says who
+// called: a guest at a table, a delivery platform or anyone at all
+func auditActor(ctx *gin.Context, route string) string {
+	switch {
+	case ctx.GetString("uid") != "":
+		return "user"
+	case strings.HasPrefix(route, "/guest"):
+		return "guest"
+	case strings.HasPrefix(route, "/platforms/"):
+		return "platform"
+	}
+	return "anonymous"
+}
+
+func loadAudited(curCtx context.Context, entity auditedEntity, filter bson.M) bson.M {
+	var doc bson.M
+	err := database.OpenCollection(database.Client, entity.collection).FindOne(curCtx, filter).Decode(&doc)
+	if err != nil {
+		return nil
+	}
+	return doc
+}
+
+// createdIds reads the ids out of a create response, which is either the
+// new document or an InsertOne/InsertMany result
+func createdIds(body []byte, idField string) []bson.M {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	if id, ok := payload[idField].(string); ok && id != "" {
+		return []bson.M{{idField: id}}
+	}
+	ids := []interface{}{payload["InsertedID"]}
+	if many, ok := payload["InsertedIDs"].([]interface{}); ok {
+		ids = many
+	}
+	filters := []bson.M{}
+	for _, id := range ids {
+		hex, _ := id.(string)
+		if objectId, err := primitive.ObjectIDFromHex(hex); err == nil {
+			filters = append(filters, bson.M{"_id": objectId})
+		}
+	}
+	return filters
+}
+
+func recordAudit(curCtx context.Context, entry models.AuditEntry, before bson.M, after bson.M) {
+	switch {
+	case entry.Action != "":
+	case before == nil && after != nil:
+		entry.Action = "CREATE"
+	case before != nil && after == nil:
+		entry.Action = "DELETE"
+	case entry.Method == http.MethodDelete:
+		entry.Action = "DELETE"
+	case entry.Method == http.MethodPost && entry.EntityID == "":
+		entry.Action = "CREATE"
+	default:
+		entry.Action = "UPDATE"
+	}
+	entry.Changes = auditDiff(before, after)
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	entry.ID = primitive.NewObjectID()
+	entry.AuditID = entry.ID.Hex()
+
+	if _, err := auditCollection.InsertOne(curCtx, entry); err != nil {
+		log.Println("audit:", err)
+	}
+}
+
+func auditDiff(before bson.M, after bson.M) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			changes[key] = models.AuditChange{Before: value}
+		}
+	}
+	for key, value := range after {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes[key] = models.AuditChange{Before: before[key], After: value}
+		}
+	}
+	delete(changes, "_id")
+	delete(changes, "updated_at")
+	for key := range changes {
+		if auditRedacted[key] {
+			changes[key] = models.AuditChange{Before: "[redacted]", After: "[redacted]"}
+		}
+	}
+	return changes
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -21,9 +21,11 @@ func Auth() gin.HandlerFunc{
 
 		claims, err := helpers.ValidateToken(clientToken)
 		if err != "" {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
+			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"error": err,
 			})
+			ctx.Abort()
+			return
 		}
 
 		ctx.Set("email", claims.Email)
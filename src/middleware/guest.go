@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"infinity/rms/database"
+	"infinity/rms/helpers"
+	"infinity/rms/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var guestTableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
+
+// GuestAuth only admits guest session tokens and exposes the table the
+// session is scoped to as "guest_table_id". Sessions opened from a QR
+// code that has since been rotated are turned away.
+func GuestAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		guestToken := ctx.Request.Header.Get("guest-token")
+		if guestToken == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Guest token is Empty",
+			})
+			ctx.Abort()
+			return
+		}
+
+		claims, err := helpers.ValidateGuestToken(guestToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			ctx.Abort()
+			return
+		}
+
+		curCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var table models.Table
+		err = guestTableCollection.FindOne(curCtx, bson.M{"table_id": claims.TableID}).Decode(&table)
+		if err != nil || table.QRVersion != claims.QRVersion {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error": "The guest session is invalid or has expired",
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set("guest_table_id", claims.TableID)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"infinity/rms/helpers"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale resolves the response language from ?lang= or Accept-Language
+// and translates {"error": ...} bodies into it
+func Locale() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requested := helpers.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+		if lang := ctx.Query("lang"); lang != "" {
+			requested = append([]string{strings.ToLower(lang)}, requested...)
+		}
+		locales := helpers.ResolveLocales(requested)
+
+		ctx.Set("locales", locales)
+		ctx.Set("locale", locales[0])
+		ctx.Header("Content-Language", locales[0])
+		ctx.Header("Vary", "Accept-Language")
+
+		if locales[0] == helpers.DefaultLocale {
+			ctx.Next()
+			return
+		}
+
+		writer := &errorTranslator{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+		writer.flush(locales)
+	}
+}
+
+type errorTranslator struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+}
+
+func (w *errorTranslator) holds() bool {
+	return w.Status() >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+func (w *errorTranslator) Write(data []byte) (int, error) {
+	if w.buffered || (!w.ResponseWriter.Written() && w.holds()) {
+		w.buffered = true
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorTranslator) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *errorTranslator) flush(locales []string) {
+	if !w.buffered {
+		return
+	}
+	body := w.body.Bytes()
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if message, ok := payload["error"].(string); ok {
+			payload["error"] = helpers.Translate(message, locales)
+			if translated, err := json.Marshal(payload); err == nil {
+				body = translated
+			}
+		}
+	}
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit allows each client perMinute requests per minute with bursts
+// of up to perMinute. Clients are keyed by IP and, for guest sessions, the
+// table they are scoped to.
+func RateLimit(perMinute int) gin.HandlerFunc {
+	var mu sync.Mutex
+	buckets := map[string]*bucket{}
+	rate := float64(perMinute) / 60
+	lastSweep := time.Now()
+
+	return func(ctx *gin.Context) {
+		key := ctx.ClientIP() + "|" + ctx.GetString("guest_table_id")
+		now := time.Now()
+
+		mu.Lock()
+		if now.Sub(lastSweep) > 10*time.Minute {
+			for k, b := range buckets {
+				if now.Sub(b.last) > 10*time.Minute {
+					delete(buckets, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{tokens: float64(perMinute), last: now}
+			buckets[key] = b
+		}
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > float64(perMinute) {
+			b.tokens = float64(perMinute)
+		}
+		b.last = now
+
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		wait := (1 - b.tokens) / rate
+		mu.Unlock()
+
+		if !allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(wait)+1))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many requests",
+			})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID tags every request with an id, reusing the caller's
+// X-Request-ID when it sends a sensible one
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			buf := make([]byte, 16)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+		ctx.Set("request_id", id)
+		ctx.Header("X-Request-ID", id)
+		ctx.Next()
+	}
+}
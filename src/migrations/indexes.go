@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index is an index a collection should have
+type Index struct {
+	Collection string
+	Keys       bson.D
+	Unique     bool
+	// Partial limits the index to matching documents, so a unique index
+	// can skip documents without the field
+	Partial bson.M
+}
+
+// hasString matches documents where field is set to a string
+func hasString(field string) bson.M {
+	return bson.M{field: bson.M{"$type": "string"}}
+}
+
+func key(fields ...string) bson.D {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return keys
+}
+
+// Indexes covers the business ids every lookup goes by, the fields that
+// must be unique and the queues the background jobs poll
+var Indexes = []Index{
+	{Collection: "users", Keys: key("user_id"), Unique: true},
+	{Collection: "users", Keys: key("email"), Unique: true, Partial: hasString("email")},
+	{Collection: "users", Keys: key("phone"), Unique: true, Partial: hasString("phone")},
+
+	{Collection: "menu", Keys: key("menu_id"), Unique: true},
+	{Collection: "menu", Keys: key("sku"), Partial: hasString("sku")},
+	{Collection: "food", Keys: key("food_id"), Unique: true},
+	{Collection: "food", Keys: key("menu_id")},
+	{Collection: "food", Keys: key("sku"), Partial: hasString("sku")},
+	{Collection: "allergen", Keys: key("allergen_id"), Unique: true},
+	{Collection: "allergen", Keys: key("code"), Unique: true},
+
+	{Collection: "area", Keys: key("area_id"), Unique: true},
+	{Collection: "table", Keys: key("table_id"), Unique: true},
+	{Collection: "table", Keys: key("table_number")},
+
+	{Collection: "order", Keys: key("order_id"), Unique: true},
+	{Collection: "order", Keys: key("table_id")},
+	{Collection: "order", Keys: key("customer_id"), Partial: hasString("customer_id")},
+	{Collection: "order", Keys: key("scheduled_for"), Partial: bson.M{"scheduled_for": bson.M{"$type": "date"}}},
+	{Collection: "order", Keys: key("platform", "external_order_id"), Unique: true, Partial: hasString("external_order_id")},
+	{Collection: "orderItem", Keys: key("order_item_id"), Unique: true},
+	{Collection: "orderItem", Keys: key("order_id")},
+	{Collection: "invoice", Keys: key("invoice_id"), Unique: true},
+	{Collection: "invoice", Keys: key("order_id")},
+	{Collection: "invoice", Keys: key("paid_at"), Partial: bson.M{"paid_at": bson.M{"$type": "date"}}},
+
+	{Collection: "customer", Keys: key("customer_id"), Unique: true},
+	{Collection: "customer", Keys: key("phone"), Partial: hasString("phone")},
+	{Collection: "customer", Keys: key("email"), Partial: hasString("email")},
+	{Collection: "loyaltyProgram", Keys: key("program_id"), Unique: true},
+	{Collection: "loyaltyLedger", Keys: key("entry_id"), Unique: true},
+	{Collection: "loyaltyLedger", Keys: key("customer_id")},
+	{Collection: "loyaltyLedger", Keys: key("invoice_id"), Partial: hasString("invoice_id")},
+	{Collection: "giftCard", Keys: key("gift_card_id"), Unique: true},
+	{Collection: "giftCard", Keys: key("code"), Unique: true},
+	{Collection: "giftCardTransaction", Keys: key("transaction_id"), Unique: true},
+	{Collection: "giftCardTransaction", Keys: key("gift_card_id")},
+	{Collection: "waitlist", Keys: key("waitlist_id"), Unique: true},
+
+	{Collection: "staff", Keys: key("staff_id"), Unique: true},
+	{Collection: "staff", Keys: key("user_id"), Partial: hasString("user_id")},
+	{Collection: "shift", Keys: key("shift_id"), Unique: true},
+	{Collection: "shift", Keys: key("staff_id")},
+	{Collection: "timeEntry", Keys: key("time_entry_id"), Unique: true},
+	{Collection: "timeEntry", Keys: key("staff_id")},
+	{Collection: "sectionAssignment", Keys: key("assignment_id"), Unique: true},
+	{Collection: "sectionAssignment", Keys: key("server_id")},
+	{Collection: "businessDay", Keys: key("date"), Unique: true},
+
+	{Collection: "deliveryZone", Keys: key("zone_id"), Unique: true},
+	{Collection: "platformItem", Keys: key("platform", "external_item_id"), Unique: true},
+
+	{Collection: "outbox", Keys: key("event_id"), Unique: true},
+	{Collection: "outbox", Keys: key("status", "next_attempt_at")},
+	{Collection: "webhook", Keys: key("webhook_id"), Unique: true},
+	{Collection: "webhookDelivery", Keys: key("delivery_id"), Unique: true},
+	{Collection: "webhookDelivery", Keys: key("event_id", "webhook_id"), Unique: true},
+	{Collection: "webhookDelivery", Keys: key("status", "next_attempt_at")},
+	{Collection: "auditLog", Keys: key("audit_id"), Unique: true},
+	{Collection: "auditLog", Keys: key("entity", "entity_id")},
+
+	{Collection: "schemaMigration", Keys: key("version"), Unique: true, Partial: bson.M{"version": bson.M{"$exists": true}}},
+}
+
+// Name is the index name MongoDB would pick for the keys
+func (index Index) Name() string {
+	parts := []string{}
+	for _, k := range index.Keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", k.Key, k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func (index Index) model() mongo.IndexModel {
+	opts := options.Index().SetName(index.Name())
+	if index.Unique {
+		opts.SetUnique(true)
+	}
+	if index.Partial != nil {
+		opts.SetPartialFilterExpression(index.Partial)
+	}
+	return mongo.IndexModel{Keys: index.Keys, Options: opts}
+}
+
+// EnsureIndexes creates the declared indexes that are missing. One whose
+// options changed is dropped and built again. A unique index fails to
+// build while the collection holds duplicates, which have to be cleaned up
+// by hand.
+func (m *Migrator) EnsureIndexes(ctx context.Context) error {
+	for _, index := range m.Indexes {
+		indexes := m.DB.Collection(index.Collection).Indexes()
+		_, err := indexes.CreateOne(ctx, index.model())
+		if isIndexConflict(err) {
+			log.Printf("migrations: rebuilding index %s on %s", index.Name(), index.Collection)
+			if _, err = indexes.DropOne(ctx, index.Name()); err == nil {
+				_, err = indexes.CreateOne(ctx, index.model())
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("migrations: index %s on %s: %w", index.Name(), index.Collection, err)
+		}
+	}
+	return nil
+}
+
+// isIndexConflict reports an index that exists under the same name with
+// other options or keys
+func isIndexConflict(err error) bool {
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.Code == 85 || commandErr.Code == 86
+	}
+	return false
+}
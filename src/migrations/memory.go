@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the records and the lock in memory for tests
+type MemoryStore struct {
+	mu          sync.Mutex
+	records     map[int]Applied
+	owner       string
+	lockedUntil time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[int]Applied{}}
+}
+
+func (s *MemoryStore) Applied(ctx context.Context) ([]Applied, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := []Applied{}
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+func (s *MemoryStore) Record(ctx context.Context, applied Applied) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[applied.Version] = applied
+	return nil
+}
+
+func (s *MemoryStore) Forget(ctx context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, version)
+	return nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, owner string, now time.Time, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != "" && !s.lockedUntil.Before(now) {
+		return ErrLocked
+	}
+	s.owner, s.lockedUntil = owner, until
+	return nil
+}
+
+func (s *MemoryStore) Unlock(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
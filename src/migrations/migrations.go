@@ -0,0 +1,294 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration is a versioned change to the stored data. Up and Down are not
+// run in a transaction, so they should be safe to run again after failing
+// half way; a migration is only recorded once Up returns nil. Down is nil
+// for a migration that can't be undone.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// Applied is the record kept for every migration that has been run
+type Applied struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// Status is a known migration and whether it has been applied
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Reversible  bool       `json:"reversible"`
+	AppliedAt   *time.Time `json:"applied_at"`
+}
+
+var ErrLocked = errors.New("migrations: another process is migrating")
+
+// Migrator applies migrations and indexes to a database, keeping the
+// applied versions and its lock in Store
+type Migrator struct {
+	DB         *mongo.Database
+	Store      Store
+	Migrations []Migration
+	Indexes    []Index
+	// Lease is how long the migration lock is held before another process
+	// may take it over
+	Lease time.Duration
+	// Wait is how long to wait for another process's lock before giving up
+	Wait time.Duration
+}
+
+// New returns a Migrator for this repo's migrations and indexes
+func New(db *mongo.Database) *Migrator {
+	return &Migrator{
+		DB:         db,
+		Store:      NewMongoStore(db),
+		Migrations: All,
+		Indexes:    Indexes,
+		Lease:      10 * time.Minute,
+		Wait:       time.Minute,
+	}
+}
+
+// Latest is the highest known version
+func (m *Migrator) Latest() int {
+	latest := 0
+	for _, migration := range m.Migrations {
+		if migration.Version > latest {
+			latest = migration.Version
+		}
+	}
+	return latest
+}
+
+// Up applies pending migrations up to and including target, or all of
+// them when target is 0, then creates the declared indexes
+func (m *Migrator) Up(ctx context.Context, target int) error {
+	migrations, err := m.sorted()
+	if err != nil {
+		return err
+	}
+	if target == 0 {
+		target = m.Latest()
+	}
+	return m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if migration.Version > target {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := migration.Up(ctx, m.DB); err != nil {
+				return fmt.Errorf("migrations: %d %s: %w", migration.Version, migration.Description, err)
+			}
+			appliedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			err := m.Store.Record(ctx, Applied{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   appliedAt,
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("migrations: applied %d %s", migration.Version, migration.Description)
+		}
+		return m.EnsureIndexes(ctx)
+	})
+}
+
+// Down rolls back applied migrations newer than target, newest first. It
+// stops at the first one that can't be undone.
+func (m *Migrator) Down(ctx context.Context, target int) error {
+	migrations, err := m.sorted()
+	if err != nil {
+		return err
+	}
+	return m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if migration.Version <= target {
+				break
+			}
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migrations: %d %s can not be undone", migration.Version, migration.Description)
+			}
+			if err := migration.Down(ctx, m.DB); err != nil {
+				return fmt.Errorf("migrations: undoing %d %s: %w", migration.Version, migration.Description, err)
+			}
+			if err := m.Store.Forget(ctx, migration.Version); err != nil {
+				return err
+			}
+			log.Printf("migrations: undid %d %s", migration.Version, migration.Description)
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration in order, and any applied version
+// this build doesn't know about
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := m.sorted()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, migration := range migrations {
+		status := Status{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Reversible:  migration.Down != nil,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, Status{Version: record.Version, Description: record.Description, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Version is the highest applied version, 0 when none is
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Rerun runs Up for every migration newer than since, applied or not,
+// records every migration up to the latest and creates the indexes. It
+// brings data restored from schema version since up to date.
+func (m *Migrator) Rerun(ctx context.Context, since int) error {
+	migrations, err := m.sorted()
+	if err != nil {
+		return err
+	}
+	return m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if migration.Version > since {
+				if err := migration.Up(ctx, m.DB); err != nil {
+					return fmt.Errorf("migrations: %d %s: %w", migration.Version, migration.Description, err)
+				}
+				log.Printf("migrations: reran %d %s", migration.Version, migration.Description)
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			appliedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			err := m.Store.Record(ctx, Applied{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   appliedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return m.EnsureIndexes(ctx)
+	})
+}
+
+func (m *Migrator) sorted() ([]Migration, error) {
+	migrations := append([]Migration{}, m.Migrations...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version < 1 || migration.Up == nil {
+			return nil, fmt.Errorf("migrations: %d %s needs a positive version and an Up", migration.Version, migration.Description)
+		}
+		if i > 0 && migrations[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("migrations: version %d is used twice", migration.Version)
+		}
+	}
+	return migrations, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]Applied, error) {
+	records, err := m.Store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int]Applied{}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// locked runs fn holding the migration lock, so instances starting
+// together don't migrate twice
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	owner := lockOwner()
+	deadline := time.Now().Add(m.Wait)
+	for {
+		now := time.Now()
+		err := m.Store.Lock(ctx, owner, now, now.Add(m.Lease))
+		if err == nil {
+			break
+		}
+		if err != ErrLocked || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	defer m.Store.Unlock(context.Background(), owner)
+	return fn()
+}
+
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), primitive.NewObjectID().Hex())
+}
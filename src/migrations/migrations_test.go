@@ -0,0 +1,190 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newTestMigrator has migrations 1 to 3, listed out of order, that log
+// what they do to ran. Migration 2 can't be undone.
+func newTestMigrator(ran *[]string) *Migrator {
+	step := func(name string) func(ctx context.Context, db *mongo.Database) error {
+		return func(ctx context.Context, db *mongo.Database) error {
+			*ran = append(*ran, name)
+			return nil
+		}
+	}
+	return &Migrator{
+		Store: NewMemoryStore(),
+		Migrations: []Migration{
+			{Version: 3, Description: "three", Up: step("up 3"), Down: step("down 3")},
+			{Version: 1, Description: "one", Up: step("up 1"), Down: step("down 1")},
+			{Version: 2, Description: "two", Up: step("up 2")},
+		},
+		Lease: time.Minute,
+	}
+}
+
+func versions(t *testing.T, m *Migrator) []int {
+	t.Helper()
+	records, err := m.Store.Applied(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := []int{}
+	for _, record := range records {
+		applied = append(applied, record.Version)
+	}
+	return applied
+}
+
+func TestUpRunsPendingInVersionOrder(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+
+	if err := m.Up(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"up 1", "up 2", "up 3"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+	if version, _ := m.Version(context.Background()); version != 3 {
+		t.Fatalf("at version %d, want 3", version)
+	}
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+	m.Migrations[2].Up = func(ctx context.Context, db *mongo.Database) error {
+		return errors.New("disk full")
+	}
+
+	if err := m.Up(context.Background(), 0); err == nil {
+		t.Fatal("a failed migration passed")
+	}
+	if got := versions(t, m); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("recorded %v, want only 1", got)
+	}
+	// the lock was given back
+	if err := m.Store.Lock(context.Background(), "next", time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("lock still held: %v", err)
+	}
+}
+
+func TestMigrationsNeedUniquePositiveVersions(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+	m.Migrations = append(m.Migrations, Migration{Version: 3, Description: "again", Up: m.Migrations[0].Up})
+	if err := m.Up(context.Background(), 0); err == nil {
+		t.Fatal("ran with version 3 twice")
+	}
+	m.Migrations = []Migration{{Version: 0, Description: "zero", Up: m.Migrations[0].Up}}
+	if err := m.Up(context.Background(), 0); err == nil {
+		t.Fatal("ran a migration with version 0")
+	}
+	if len(ran) != 0 {
+		t.Fatalf("ran %v", ran)
+	}
+}
+
+func TestUpWaitsForAnotherProcess(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+	now := time.Now()
+
+	m.Store.Lock(context.Background(), "other", now, now.Add(time.Hour))
+	if err := m.Up(context.Background(), 0); err != ErrLocked {
+		t.Fatalf("got %v, want ErrLocked", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("ran %v while another process held the lock", ran)
+	}
+
+	// a process that died holding the lock loses it when the lease runs out
+	m.Store.Unlock(context.Background(), "other")
+	m.Store.Lock(context.Background(), "dead", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if err := m.Up(context.Background(), 0); err != nil {
+		t.Fatalf("expired lock not taken over: %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+	m.Up(context.Background(), 1)
+	// a version applied by a newer build
+	m.Store.Record(context.Background(), Applied{Version: 9, Description: "nine", AppliedAt: time.Now()})
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, status := range statuses {
+		got = append(got, fmt.Sprintf("%d %v %v", status.Version, status.Reversible, status.AppliedAt != nil))
+	}
+	want := []string{"1 true true", "2 false false", "3 true false", "9 false true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDownUndoesNewestFirst(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+	m.Up(context.Background(), 0)
+	ran = ran[:0]
+
+	// 3 is undone, then 2 can't be
+	if err := m.Down(context.Background(), 0); err == nil {
+		t.Fatal("undid a migration without a Down")
+	}
+	if want := []string{"down 3"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+	if got := versions(t, m); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("applied %v, want [1 2]", got)
+	}
+
+	m.Migrations[2].Down = func(ctx context.Context, db *mongo.Database) error {
+		ran = append(ran, "down 2")
+		return nil
+	}
+	if err := m.Down(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(t, m); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("applied %v, want [1]", got)
+	}
+	if want := []string{"down 3", "down 2"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestRerunBringsRestoredDataUpToDate(t *testing.T) {
+	ran := []string{}
+	m := newTestMigrator(&ran)
+	m.Up(context.Background(), 2)
+	ran = ran[:0]
+
+	// data restored from version 1 gets 2 again and 3 for the first time
+	if err := m.Rerun(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"up 2", "up 3"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+	if got := versions(t, m); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("applied %v, want [1 2 3]", got)
+	}
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store keeps the applied migrations and the lock that keeps two
+// processes from migrating at once
+type Store interface {
+	Applied(ctx context.Context) ([]Applied, error)
+	Record(ctx context.Context, applied Applied) error
+	Forget(ctx context.Context, version int) error
+	// Lock takes the lock for owner until the given time, or returns
+	// ErrLocked while someone else holds it past now
+	Lock(ctx context.Context, owner string, now time.Time, until time.Time) error
+	Unlock(ctx context.Context, owner string) error
+}
+
+const lockID = "lock"
+
+// MongoStore keeps the records and the lock in the schemaMigration
+// collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{collection: db.Collection("schemaMigration")}
+}
+
+func (s *MongoStore) Applied(ctx context.Context) ([]Applied, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"version": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+	records := []Applied{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *MongoStore) Record(ctx context.Context, applied Applied) error {
+	_, err := s.collection.InsertOne(ctx, applied)
+	return err
+}
+
+func (s *MongoStore) Forget(ctx context.Context, version int) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"version": version})
+	return err
+}
+
+func (s *MongoStore) Lock(ctx context.Context, owner string, now time.Time, until time.Time) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": lockID, "locked_until": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": owner, "locked_until": until}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLocked
+	}
+	return err
+}
+
+func (s *MongoStore) Unlock(ctx context.Context, owner string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	return err
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// All is every data migration, in any order. Never change or renumber one
+// that has shipped, add a new version instead.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "give orders without a type DINE_IN",
+		// orders from before delivery and takeaway have no type, and
+		// listings already treat them as dine-in
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("order").UpdateMany(ctx,
+				bson.M{"type": bson.M{"$in": bson.A{nil, ""}}},
+				bson.M{"$set": bson.M{"type": "DINE_IN"}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "give audit entries without an actor the user actor",
+		// only logged in users were audited before guests and platforms
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("auditLog").UpdateMany(ctx,
+				bson.M{"actor": bson.M{"$in": bson.A{nil, ""}}},
+				bson.M{"$set": bson.M{"actor": "user"}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "give paid invoices a paid_at from their updated_at",
+		// invoices paid before paid_at was recorded; a tip added since
+		// has moved updated_at, but nothing better is left
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("invoice").UpdateMany(ctx,
+				bson.M{"payment_status": "PAID", "paid_at": nil},
+				bson.A{bson.M{"$set": bson.M{"paid_at": "$updated_at"}}},
+			)
+			return err
+		},
+	},
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EU 14 regulated allergens
+var StandardAllergens = []string{
+	"CELERY",
+	"GLUTEN",
+	"CRUSTACEANS",
+	"EGGS",
+	"FISH",
+	"LUPIN",
+	"MILK",
+	"MOLLUSCS",
+	"MUSTARD",
+	"NUTS",
+	"PEANUTS",
+	"SESAME",
+	"SOYA",
+	"SULPHITES",
+}
+
+var StandardAllergenNames = map[string]string{
+	"CELERY":      "Celery",
+	"GLUTEN":      "Cereals containing gluten",
+	"CRUSTACEANS": "Crustaceans",
+	"EGGS":        "Eggs",
+	"FISH":        "Fish",
+	"LUPIN":       "Lupin",
+	"MILK":        "Milk",
+	"MOLLUSCS":    "Molluscs",
+	"MUSTARD":     "Mustard",
+	"NUTS":        "Tree nuts",
+	"PEANUTS":     "Peanuts",
+	"SESAME":      "Sesame seeds",
+	"SOYA":        "Soya",
+	"SULPHITES":   "Sulphur dioxide and sulphites",
+}
+
+var DietaryTags = []string{
+	"VEGAN",
+	"VEGETARIAN",
+	"HALAL",
+	"KOSHER",
+	"GLUTEN_FREE",
+	"DAIRY_FREE",
+}
+
+type Allergen struct {
+	ID               primitive.ObjectID `bson:"_id" json:"id"`
+	Code             *string            `json:"code" validate:"required,min=2,max=50"`
+	Name             *string            `json:"name" validate:"required"`
+	NameTranslations map[string]string  `json:"name_translations,omitempty"`
+	AllergenID       string             `json:"allergen_id"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
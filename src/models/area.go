@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Area struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Name      *string            `json:"name" validate:"required,min=2,max=100"`
+	Width     *float64           `json:"width,omitempty"`
+	Height    *float64           `json:"height,omitempty"`
+	SortOrder *int               `json:"sort_order,omitempty"`
+	AreaID    string             `json:"area_id"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
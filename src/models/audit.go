@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditEntry struct {
+	ID        primitive.ObjectID     `bson:"_id" json:"id"`
+	Action    string                 `json:"action"`
+	Method    string                 `json:"method"`
+	Route     string                 `json:"route"`
+	Path      string                 `json:"path"`
+	Entity    string                 `json:"entity"`
+	EntityID  string                 `json:"entity_id,omitempty"`
+	Actor     string                 `json:"actor"`
+	UserID    string                 `json:"user_id,omitempty"`
+	UserEmail string                 `json:"user_email,omitempty"`
+	UserName  string                 `json:"user_name,omitempty"`
+	RequestID string                 `json:"request_id"`
+	IP        string                 `json:"ip"`
+	Status    int                    `json:"status"`
+	Changes   map[string]AuditChange `json:"changes,omitempty"`
+	AuditID   string                 `json:"audit_id"`
+	CreatedAt time.Time              `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BusinessDay is the record left when a day is closed, with its takings
+type BusinessDay struct {
+	ID                primitive.ObjectID `bson:"_id" json:"id"`
+	Date              string             `json:"date"`
+	Orders            int64              `json:"orders"`
+	PaidInvoices      int                `json:"paid_invoices"`
+	Sales             float64            `json:"sales"`
+	Tips              float64            `json:"tips"`
+	SalesByMethod     map[string]float64 `json:"sales_by_method"`
+	VoidedItems       int64              `json:"voided_items"`
+	OpenOrders        []string           `json:"open_orders"`
+	ClockedOut        []string           `json:"clocked_out"`
+	WaitlistCancelled int64              `json:"waitlist_cancelled"`
+	ClosedBy          string             `json:"closed_by,omitempty"`
+	ClosedAt          time.Time          `json:"closed_at"`
+	BusinessDayID     string             `json:"business_day_id"`
+}
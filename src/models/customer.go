@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Customer struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	FirstName   *string            `json:"first_name" validate:"required,min=1,max=100"`
+	LastName    *string            `json:"last_name,omitempty" validate:"omitempty,max=100"`
+	Phone       *string            `json:"phone,omitempty"`
+	Email       *string            `json:"email,omitempty" validate:"omitempty,email"`
+	Birthday    *string            `json:"birthday,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Preferences []string           `json:"preferences,omitempty"`
+	Allergies   []string           `json:"allergies,omitempty"`
+	Notes       *string            `json:"notes,omitempty"`
+	MergedInto  *string            `json:"merged_into,omitempty"`
+	CustomerID  string             `json:"customer_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
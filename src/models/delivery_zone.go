@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeliveryZone prices delivery to the postal codes starting with one of
+// its prefixes
+type DeliveryZone struct {
+	ID            primitive.ObjectID `bson:"_id" json:"id"`
+	Name          *string            `json:"name" validate:"required,min=2,max=100"`
+	PostalCodes   []string           `json:"postal_codes" validate:"required,min=1"`
+	Fee           *float64           `json:"fee" validate:"required,min=0"`
+	FreeAbove     *float64           `json:"free_above,omitempty" validate:"omitempty,min=0"`
+	EstimatedMins *int               `json:"estimated_minutes,omitempty" validate:"omitempty,min=1"`
+	Active        *bool              `json:"active,omitempty"`
+	ZoneID        string             `json:"zone_id"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
@@ -6,12 +6,20 @@ import (
 )
 
 type Food struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Name      *string            `json:"name" validate:"required,min=2,max=100"`
-	Price     *float64           `json:"price" validate:"required"`
-	FoodImage *string            `json:"food_image" validate:"required"`
-	FoodId    string             `json:"food_id"`
-	MenuId    *string            `json:"menu_id" validate:"required"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
+	ID               primitive.ObjectID `bson:"_id" json:"id"`
+	Name             *string            `json:"name" validate:"required,min=2,max=100"`
+	NameTranslations map[string]string  `json:"name_translations,omitempty"`
+	Price            *float64           `json:"price" validate:"required"`
+	FoodImage        *string            `json:"food_image"`
+	FoodId           string             `json:"food_id"`
+	MenuId           *string            `json:"menu_id" validate:"required"`
+	SKU              *string            `json:"sku,omitempty"`
+	Allergens        []string           `json:"allergens,omitempty"`
+	DietaryTags      []string           `json:"dietary_tags,omitempty"`
+	PackagingFee     *float64           `json:"packaging_fee,omitempty" validate:"omitempty,min=0"`
+	Course           *string            `json:"course,omitempty" validate:"omitempty,eq=STARTER|eq=MAIN|eq=DESSERT"`
+	Station          *string            `json:"station,omitempty" validate:"omitempty,min=2,max=30"`
+	PrepMinutes      *int               `json:"prep_minutes,omitempty" validate:"omitempty,min=1"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
 }
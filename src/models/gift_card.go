@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GiftCard struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Code           string             `json:"code"`
+	InitialBalance float64            `json:"initial_balance"`
+	Balance        float64            `json:"balance"`
+	Status         string             `json:"status" validate:"eq=ACTIVE|eq=VOID"`
+	CustomerID     *string            `json:"customer_id,omitempty"`
+	ExpiresAt      *time.Time         `json:"expires_at,omitempty"`
+	GiftCardID     string             `json:"gift_card_id"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// GiftCardTransaction is one movement of a card's balance. Issues and
+// top ups are sales paid with PaymentMethod.
+type GiftCardTransaction struct {
+	ID            primitive.ObjectID `bson:"_id" json:"id"`
+	GiftCardID    string             `json:"gift_card_id"`
+	Type          string             `json:"type" validate:"eq=ISSUE|eq=TOP_UP|eq=REDEEM|eq=REFUND|eq=VOID"`
+	Amount        float64            `json:"amount"`
+	BalanceAfter  float64            `json:"balance_after"`
+	PaymentMethod *string            `json:"payment_method,omitempty"`
+	InvoiceID     *string            `json:"invoice_id,omitempty"`
+	UserID        string             `json:"user_id,omitempty"`
+	TransactionID string             `json:"transaction_id"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
@@ -9,9 +9,34 @@ type Invoice struct {
 	ID             primitive.ObjectID `bson:"_id"`
 	InvoiceId      string             `json:"invoice_id"`
 	OrderId        string             `json:"order_id"`
-	PaymentMethod  *string            `json:"payment_method" validate:"eq=CARD|eq=CASH|eq="`
-	PaymentStatus  *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PAID"`
+	PaymentMethod  *string            `json:"payment_method" validate:"eq=CARD|eq=CASH|eq=GIFT_CARD|eq="`
+	PaymentStatus  *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PAID|eq=REFUNDED"`
+	Tip            *float64           `json:"tip,omitempty" validate:"omitempty,min=0"`
+	CustomerID     *string            `json:"customer_id,omitempty"`
+	Discounts      []InvoiceDiscount  `json:"discounts,omitempty"`
+	Payments       []InvoicePayment   `json:"payments,omitempty"`
 	PaymentDueData time.Time          `json:"payment_due_data"`
 	CreatedAt      time.Time          `json:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at"`
+	// PaidAt is when the invoice became PAID, a tip added later only
+	// moves updated_at
+	PaidAt         *time.Time         `json:"paid_at,omitempty"`
+}
+
+type InvoiceDiscount struct {
+	Type    string  `json:"type" validate:"eq=LOYALTY_POINTS|eq=LOYALTY_REWARD"`
+	Amount  float64 `json:"amount"`
+	Points  int     `json:"points,omitempty"`
+	FoodID  *string `json:"food_id,omitempty"`
+	EntryID string  `json:"entry_id,omitempty"`
+}
+
+// InvoicePayment is a part payment recorded against the invoice, like a
+// gift card covering some of the bill
+type InvoicePayment struct {
+	Method        string    `json:"method" validate:"eq=GIFT_CARD"`
+	Amount        float64   `json:"amount"`
+	GiftCardID    *string   `json:"gift_card_id,omitempty"`
+	TransactionID string    `json:"transaction_id,omitempty"`
+	PaidAt        time.Time `json:"paid_at"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LoyaltyItem struct {
+	FoodID string `json:"food_id" validate:"required"`
+	Points int    `json:"points" validate:"min=1"`
+}
+
+type LoyaltyTier struct {
+	Name       string  `json:"name" validate:"required"`
+	Threshold  int     `json:"threshold" validate:"min=0"`
+	Multiplier float64 `json:"multiplier" validate:"omitempty,min=1"`
+}
+
+// LoyaltyProgram holds the earn and burn rules, there is one per
+// restaurant
+type LoyaltyProgram struct {
+	ProgramID       string        `json:"program_id"`
+	Enabled         bool          `json:"enabled"`
+	PointsPerUnit   float64       `json:"points_per_unit" validate:"min=0"`
+	PointValue      float64       `json:"point_value" validate:"min=0"`
+	BonusItems      []LoyaltyItem `json:"bonus_items" validate:"dive"`
+	RewardItems     []LoyaltyItem `json:"reward_items" validate:"dive"`
+	DoublePointDays []string      `json:"double_point_days"`
+	Tiers           []LoyaltyTier `json:"tiers" validate:"dive"`
+	ExpiryDays      int           `json:"expiry_days" validate:"min=0"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// LoyaltyEntry is one line of a customer's points ledger. Credits keep
+// the points not yet spent or expired in Remaining.
+type LoyaltyEntry struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	CustomerID  string             `json:"customer_id"`
+	InvoiceID   *string            `json:"invoice_id,omitempty"`
+	Type        string             `json:"type" validate:"eq=EARN|eq=REDEEM|eq=EXPIRE|eq=REVERSAL|eq=ADJUST"`
+	Points      int                `json:"points"`
+	Remaining   int                `json:"remaining"`
+	ExpiresAt   *time.Time         `json:"expires_at,omitempty"`
+	ReversalOf  *string            `json:"reversal_of,omitempty"`
+	Description string             `json:"description,omitempty"`
+	EntryID     string             `json:"entry_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
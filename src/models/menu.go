@@ -1,17 +1,20 @@
 package models
 
 import (
-	"time"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type Menu struct {
-	ID        primitive.ObjectID `bson:"_id"`
-	Name      string             `json:"name" validate:"required"`
-	Category  string             `json:"category" validate:"required"`
-	StartDate *time.Time         `json:"start_date"`
-	EndDate   *time.Time         `json:"end_date"`
-	MenuId    string             `json:"food_id"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID                   primitive.ObjectID `bson:"_id"`
+	Name                 string             `json:"name" validate:"required"`
+	Category             string             `json:"category" validate:"required"`
+	NameTranslations     map[string]string  `json:"name_translations,omitempty"`
+	CategoryTranslations map[string]string  `json:"category_translations,omitempty"`
+	StartDate            *time.Time         `json:"start_date"`
+	EndDate              *time.Time         `json:"end_date"`
+	MenuId               string             `json:"menu_id"`
+	SKU                  string             `json:"sku,omitempty"`
+	CreatedAt            time.Time          `json:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at"`
 }
@@ -1,15 +1,50 @@
 package models
 
 import (
-	"time"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type Order struct {
-	ID        primitive.ObjectID `bson:"_id"`
-	OrderDate time.Time          `json:"order_date,omitempty"`
-	OrderID   string             `json:"order_id,omitempty"`
-	TableID   *string            `json:"table_id,omitempty"`
-	CreatedAt time.Time          `json:"created_at,omitempty"`
-	UpdatedAt time.Time          `json:"updated_at,omitempty"`
+	ID                primitive.ObjectID  `bson:"_id"`
+	OrderDate         time.Time           `json:"order_date,omitempty"`
+	OrderID           string              `json:"order_id,omitempty"`
+	Type              string              `json:"type,omitempty" validate:"omitempty,eq=DINE_IN|eq=TAKEAWAY|eq=DELIVERY"`
+	TableID           *string             `json:"table_id,omitempty"`
+	Allergies         []string            `json:"allergies,omitempty"`
+	MergedInto        *string             `json:"merged_into,omitempty"`
+	ServerID          *string             `json:"server_id,omitempty"`
+	Covers            *int                `json:"covers,omitempty" validate:"omitempty,min=1"`
+	CustomerID        *string             `json:"customer_id,omitempty"`
+	ContactName       *string             `json:"contact_name,omitempty"`
+	ContactPhone      *string             `json:"contact_phone,omitempty"`
+	PickupTime        *time.Time          `json:"pickup_time,omitempty"`
+	ScheduledFor      *time.Time          `json:"scheduled_for,omitempty"`
+	ReleasedAt        *time.Time          `json:"released_at,omitempty"`
+	DeliveryAddress   *DeliveryAddress    `json:"delivery_address,omitempty"`
+	DeliveryZoneID    *string             `json:"delivery_zone_id,omitempty"`
+	DeliveryFee       *float64            `json:"delivery_fee,omitempty"`
+	FreeDeliveryAbove *float64            `json:"free_delivery_above,omitempty"`
+	Status            *string             `json:"status,omitempty"`
+	Notes             *string             `json:"notes,omitempty"`
+	HeldCourses       []string            `json:"held_courses,omitempty"`
+	Platform          *string             `json:"platform,omitempty"`
+	ExternalOrderID   *string             `json:"external_order_id,omitempty"`
+	StatusHistory     []OrderStatusChange `json:"status_history,omitempty"`
+	CreatedAt         time.Time           `json:"created_at,omitempty"`
+	UpdatedAt         time.Time           `json:"updated_at,omitempty"`
+}
+
+type DeliveryAddress struct {
+	Line1      string `json:"line1" validate:"required"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city" validate:"required"`
+	PostalCode string `json:"postal_code" validate:"required"`
+	Notes      string `json:"notes,omitempty"`
+}
+
+type OrderStatusChange struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	UserID string    `json:"user_id,omitempty"`
 }
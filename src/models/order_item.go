@@ -12,6 +12,18 @@ type OrderItem struct {
 	FoodID      *string            `json:"food_id,omitempty"`
 	OrderItemID string             `json:"order_item_id,omitempty"`
 	OrderID     string             `json:"order_id,omitempty"`
+	Status      string             `json:"status,omitempty" validate:"eq=PENDING_CONFIRMATION|eq=CONFIRMED|eq=VOIDED|eq="`
+	Source      string             `json:"source,omitempty"`
+	Notes       string             `json:"notes,omitempty"`
+	Course      string             `json:"course,omitempty" validate:"omitempty,eq=STARTER|eq=MAIN|eq=DESSERT"`
+	FiredAt     *time.Time         `json:"fired_at,omitempty"`
+	BumpedAt    *time.Time         `json:"bumped_at,omitempty"`
+	Station     string             `json:"station,omitempty"`
+	PrepMinutes int                `json:"prep_minutes,omitempty"`
+	AlertedAt   *time.Time         `json:"sla_alerted_at,omitempty"`
+	VoidedAt    *time.Time         `json:"voided_at,omitempty"`
+	VoidReason  string             `json:"void_reason,omitempty"`
+	VoidedBy    string             `json:"voided_by,omitempty"`
 	CreatedAt   time.Time          `json:"created_at,omitempty"`
 	UpdatedAt   time.Time          `json:"updated_at,omitempty"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlatformItem maps a delivery app's item id to one of our foods
+type PlatformItem struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Platform       string             `json:"platform"`
+	ExternalItemID *string            `json:"external_item_id" validate:"required"`
+	FoodID         *string            `json:"food_id" validate:"required"`
+	Quantity       *string            `json:"quantity,omitempty" validate:"omitempty,eq=S|eq=M|eq=L"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SectionAssignment struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Section      *string            `json:"section" validate:"required"`
+	ServerID     *string            `json:"server_id" validate:"required"`
+	ShiftID      *string            `json:"shift_id,omitempty"`
+	StartTime    *time.Time         `json:"start_time"`
+	EndTime      *time.Time         `json:"end_time"`
+	AssignmentID string             `json:"assignment_id"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
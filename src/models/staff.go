@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Staff struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	FirstName  *string            `json:"first_name" validate:"required,min=2,max=100"`
+	LastName   *string            `json:"last_name" validate:"required,min=2,max=100"`
+	Role       *string            `json:"role" validate:"required,eq=MANAGER|eq=HOST|eq=SERVER|eq=BARTENDER|eq=RUNNER|eq=COOK|eq=DISHWASHER"`
+	HourlyRate *float64           `json:"hourly_rate" validate:"required,min=0"`
+	Phone      *string            `json:"phone,omitempty"`
+	Email      *string            `json:"email,omitempty" validate:"omitempty,email"`
+	UserID     *string            `json:"user_id,omitempty"`
+	Active     *bool              `json:"active,omitempty"`
+	StaffID    string             `json:"staff_id"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+type Shift struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	StaffID   *string            `json:"staff_id" validate:"required"`
+	Role      *string            `json:"role,omitempty"`
+	StartTime *time.Time         `json:"start_time" validate:"required"`
+	EndTime   *time.Time         `json:"end_time" validate:"required,gtfield=StartTime"`
+	Notes     *string            `json:"notes,omitempty"`
+	ShiftID   string             `json:"shift_id"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+type Break struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+	Paid  bool       `json:"paid"`
+}
+
+type TimeEntry struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	StaffID     string             `json:"staff_id"`
+	ShiftID     *string            `json:"shift_id,omitempty"`
+	HourlyRate  float64            `json:"hourly_rate"`
+	ClockIn     time.Time          `json:"clock_in"`
+	ClockOut    *time.Time         `json:"clock_out,omitempty"`
+	Breaks      []Break            `json:"breaks"`
+	TimeEntryID string             `json:"time_entry_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
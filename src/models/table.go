@@ -10,6 +10,16 @@ type Table struct {
 	NumberOfGuests *int               `json:"number_of_guests,omitempty"`
 	TableNumber    *int               `json:"table_number,omitempty"`
 	TableID        string             `json:"table_id,omitempty"`
+	QRVersion      int                `json:"qr_version,omitempty"`
+	Status         *string            `json:"status,omitempty" validate:"omitempty,eq=AVAILABLE|eq=NEEDS_CLEANING|eq=RESERVED"`
+	StatusAt       *time.Time         `json:"status_at,omitempty"`
+	AreaID         *string            `json:"area_id,omitempty"`
+	Section        *string            `json:"section,omitempty"`
+	X              *float64           `json:"x,omitempty"`
+	Y              *float64           `json:"y,omitempty"`
+	Shape          *string            `json:"shape,omitempty" validate:"omitempty,eq=ROUND|eq=SQUARE|eq=RECTANGLE|eq=BOOTH"`
+	Seats          *int               `json:"seats,omitempty" validate:"omitempty,min=1"`
+	ServerID       *string            `json:"server_id,omitempty"`
 	CreatedAt      time.Time          `json:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WaitlistEntry struct {
+	ID                primitive.ObjectID `bson:"_id" json:"id"`
+	PartyName         *string            `json:"party_name" validate:"required,min=1,max=100"`
+	PartySize         *int               `json:"party_size" validate:"required,min=1,max=50"`
+	Phone             *string            `json:"phone,omitempty"`
+	Email             *string            `json:"email,omitempty" validate:"omitempty,email"`
+	Notes             *string            `json:"notes,omitempty"`
+	CustomerID        *string            `json:"customer_id,omitempty"`
+	Status            string             `json:"status" validate:"eq=WAITING|eq=NOTIFIED|eq=SEATED|eq=CANCELLED|eq=NO_SHOW"`
+	Position          int                `json:"position"`
+	QuotedWaitMinutes int                `json:"quoted_wait_minutes"`
+	NotifiedAt        *time.Time         `json:"notified_at,omitempty"`
+	SeatedAt          *time.Time         `json:"seated_at,omitempty"`
+	TableID           *string            `json:"table_id,omitempty"`
+	OrderID           *string            `json:"order_id,omitempty"`
+	WaitlistID        string             `json:"waitlist_id"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook is another system's subscription to some of our events. Events
+// holds event names or "*" for all of them.
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	URL         *string            `json:"url" validate:"required,url"`
+	Events      []string           `json:"events" validate:"required,min=1"`
+	Description *string            `json:"description,omitempty" validate:"omitempty,max=200"`
+	Secret      string             `json:"secret,omitempty"`
+	Active      *bool              `json:"active,omitempty"`
+	WebhookID   string             `json:"webhook_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// WebhookDelivery is one event on its way to one webhook. It stays
+// PENDING while it is being retried and ends up DELIVERED or DEAD.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id" json:"id"`
+	DeliveryID    string             `json:"delivery_id"`
+	EventID       string             `json:"event_id"`
+	Event         string             `json:"event"`
+	WebhookID     string             `json:"webhook_id"`
+	Payload       string             `json:"payload"`
+	Status        string             `json:"status"`
+	Attempts      int                `json:"attempts"`
+	NextAttemptAt *time.Time         `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time         `json:"delivered_at,omitempty"`
+	DeadAt        *time.Time         `json:"dead_at,omitempty"`
+	LastError     string             `json:"last_error,omitempty"`
+	Log           []WebhookAttempt   `json:"log"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Message is a short text for a guest or staff member
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages, e.g. "your table is ready" to a waiting party
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// NewFromEnv returns a WebhookNotifier when NOTIFY_WEBHOOK_URL is set and
+// a LogNotifier otherwise
+func NewFromEnv() Notifier {
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		return &WebhookNotifier{URL: url}
+	}
+	return LogNotifier{}
+}
+
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, message Message) error {
+	log.Printf("notify %s: %s - %s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// WebhookNotifier posts the message as JSON, for an SMS or email gateway
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("notify: webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPStatusClient posts {"order_id", "status"} to the platform, signed the
+// same way the platform signs its webhooks
+type HTTPStatusClient struct {
+	Adapter Adapter
+	URL     string
+	Secret  string
+	Client  *http.Client
+}
+
+func (c *HTTPStatusClient) UpdateStatus(ctx context.Context, externalId string, status string) error {
+	platformStatus, ok := c.Adapter.Status(status)
+	if !ok {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]string{"order_id": externalId, "status": platformStatus})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(c.Adapter.Sign(payload, c.Secret))
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: status update answered %s", c.Adapter.Name(), resp.Status)
+	}
+	return nil
+}
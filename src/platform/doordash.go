@@ -0,0 +1,107 @@
+package platform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DoorDash signs the body with a base64 HMAC-SHA256 in
+// X-DoorDash-Signature and sends amounts in cents
+type DoorDash struct{}
+
+type doorDashOrder struct {
+	ID              string `json:"id"`
+	FulfillmentType string `json:"fulfillment_type"`
+	Customer        struct {
+		FirstName   string `json:"first_name"`
+		LastName    string `json:"last_name"`
+		PhoneNumber string `json:"phone_number"`
+	} `json:"customer"`
+	DeliveryAddress *struct {
+		Street       string `json:"street"`
+		Subpremise   string `json:"subpremise"`
+		City         string `json:"city"`
+		ZipCode      string `json:"zip_code"`
+		Instructions string `json:"address_instructions"`
+	} `json:"delivery_address"`
+	Items []struct {
+		MerchantSuppliedID  string `json:"merchant_supplied_id"`
+		Name                string `json:"name"`
+		Quantity            int    `json:"quantity"`
+		Price               int64  `json:"price"`
+		SpecialInstructions string `json:"special_instructions"`
+	} `json:"items"`
+	EstimatedPickupTime *time.Time `json:"estimated_pickup_time"`
+	DeliveryFee         int64      `json:"delivery_fee"`
+}
+
+func (DoorDash) Name() string { return "doordash" }
+
+func (DoorDash) Verify(header http.Header, body []byte, secret string) error {
+	got, _ := base64.StdEncoding.DecodeString(header.Get("X-DoorDash-Signature"))
+	return checkSignature(got, body, secret)
+}
+
+func (DoorDash) Parse(body []byte) (ExternalOrder, error) {
+	var payload doorDashOrder
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ExternalOrder{}, err
+	}
+	if payload.ID == "" {
+		return ExternalOrder{}, fmt.Errorf("doordash: order id is missing")
+	}
+
+	order := ExternalOrder{
+		Platform:   "doordash",
+		ExternalID: payload.ID,
+		Type:       "DELIVERY",
+		Customer: Customer{
+			Name:  strings.TrimSpace(payload.Customer.FirstName + " " + payload.Customer.LastName),
+			Phone: payload.Customer.PhoneNumber,
+		},
+		PickupTime:  payload.EstimatedPickupTime,
+		DeliveryFee: float64(payload.DeliveryFee) / 100,
+	}
+	if payload.FulfillmentType == "pickup" {
+		order.Type = "TAKEAWAY"
+	}
+	if payload.DeliveryAddress != nil {
+		order.Address = &Address{
+			Line1:      payload.DeliveryAddress.Street,
+			Line2:      payload.DeliveryAddress.Subpremise,
+			City:       payload.DeliveryAddress.City,
+			PostalCode: payload.DeliveryAddress.ZipCode,
+			Notes:      payload.DeliveryAddress.Instructions,
+		}
+	}
+	for _, item := range payload.Items {
+		order.Items = append(order.Items, ExternalItem{
+			ExternalID: item.MerchantSuppliedID,
+			Name:       item.Name,
+			Quantity:   item.Quantity,
+			UnitPrice:  float64(item.Price) / 100,
+			Notes:      item.SpecialInstructions,
+		})
+	}
+	return order, nil
+}
+
+func (DoorDash) Status(status string) (string, bool) {
+	switch status {
+	case "PREPARING":
+		return "CONFIRMED", true
+	case "READY_FOR_PICKUP":
+		return "READY", true
+	case "CANCELLED":
+		return "CANCELLED", true
+	}
+	return "", false
+}
+
+func (DoorDash) Sign(body []byte, secret string) (string, string) {
+	return "X-DoorDash-Signature", base64.StdEncoding.EncodeToString(sign(body, secret))
+}
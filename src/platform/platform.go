@@ -0,0 +1,112 @@
+package platform
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBadSignature = errors.New("platform: signature does not match")
+	ErrUnknown      = errors.New("platform: not configured")
+)
+
+// ExternalOrder is an order from a delivery app in our own terms, whatever
+// shape the app sent it in
+type ExternalOrder struct {
+	Platform    string         `json:"platform"`
+	ExternalID  string         `json:"external_id"`
+	DisplayID   string         `json:"display_id,omitempty"`
+	Type        string         `json:"type"`
+	Customer    Customer       `json:"customer"`
+	Address     *Address       `json:"address,omitempty"`
+	Items       []ExternalItem `json:"items"`
+	PickupTime  *time.Time     `json:"pickup_time,omitempty"`
+	DeliveryFee float64        `json:"delivery_fee,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+}
+
+type Customer struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+type Address struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	Notes      string `json:"notes,omitempty"`
+}
+
+type ExternalItem struct {
+	ExternalID string  `json:"external_id"`
+	Name       string  `json:"name"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price"`
+	Notes      string  `json:"notes,omitempty"`
+}
+
+// Adapter turns one platform's webhook into an ExternalOrder
+type Adapter interface {
+	Name() string
+	// Verify checks the webhook signature against the shared secret
+	Verify(header http.Header, body []byte, secret string) error
+	Parse(body []byte) (ExternalOrder, error)
+	// Status names our order status in the platform's vocabulary; false
+	// means the platform has no matching status
+	Status(status string) (string, bool)
+	// Sign gives the header that carries our signature on outbound calls
+	Sign(body []byte, secret string) (string, string)
+}
+
+// StatusClient tells a platform that an order moved on
+type StatusClient interface {
+	UpdateStatus(ctx context.Context, externalId string, status string) error
+}
+
+// Integration is a configured platform
+type Integration struct {
+	Adapter Adapter
+	Secret  string
+	Client  StatusClient
+}
+
+var adapters = []Adapter{UberEats{}, DoorDash{}}
+
+// FromEnv enables every platform with a PLATFORM_<NAME>_SECRET. Status
+// updates are pushed when PLATFORM_<NAME>_STATUS_URL is set too.
+func FromEnv() map[string]Integration {
+	integrations := map[string]Integration{}
+	for _, adapter := range adapters {
+		prefix := "PLATFORM_" + strings.ToUpper(adapter.Name()) + "_"
+		secret := os.Getenv(prefix + "SECRET")
+		if secret == "" {
+			continue
+		}
+		integration := Integration{Adapter: adapter, Secret: secret}
+		if url := os.Getenv(prefix + "STATUS_URL"); url != "" {
+			integration.Client = &HTTPStatusClient{Adapter: adapter, URL: url, Secret: secret}
+		}
+		integrations[adapter.Name()] = integration
+	}
+	return integrations
+}
+
+func sign(body []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func checkSignature(got []byte, body []byte, secret string) error {
+	if len(got) == 0 || !hmac.Equal(got, sign(body, secret)) {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testSecret = "shh"
+
+var testOrders = map[string]string{
+	"ubereats": `{
+		"id": "ue-1",
+		"display_id": "A1B2",
+		"type": "DELIVERY_BY_UBER",
+		"eater": {"first_name": "Ada", "last_name": "Lovelace", "phone": "555-0100"},
+		"cart": {
+			"items": [
+				{"id": "burger-1", "title": "Burger", "quantity": 2, "price": {"unit_price": {"amount": 1250}}},
+				{"id": "fries-1", "title": "Fries", "quantity": 1, "price": {"unit_price": {"amount": 399}}, "special_instructions": "no salt"}
+			]
+		},
+		"delivery": {"location": {"street_address": "1 Main St", "city": "Springfield", "postal_code": "12345"}}
+	}`,
+	"doordash": `{
+		"id": "dd-1",
+		"fulfillment_type": "delivery",
+		"customer": {"first_name": "Ada", "last_name": "Lovelace", "phone_number": "555-0100"},
+		"delivery_address": {"street": "1 Main St", "city": "Springfield", "zip_code": "12345"},
+		"items": [
+			{"merchant_supplied_id": "burger-1", "name": "Burger", "quantity": 2, "price": 1250},
+			{"merchant_supplied_id": "fries-1", "name": "Fries", "quantity": 1, "price": 399, "special_instructions": "no salt"}
+		],
+		"delivery_fee": 299
+	}`,
+}
+
+// webhookServer stands in for the order webhook: it verifies and parses
+// what it is sent and answers with the parsed order
+func webhookServer(adapter Adapter) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := adapter.Verify(r.Header, body, testSecret); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		order, err := adapter.Parse(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(order)
+	}))
+}
+
+func postWebhook(t *testing.T, url string, body []byte, header string, value string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestWebhookSignature(t *testing.T) {
+	for _, adapter := range adapters {
+		t.Run(adapter.Name(), func(t *testing.T) {
+			server := webhookServer(adapter)
+			defer server.Close()
+			body := []byte(testOrders[adapter.Name()])
+			header, good := adapter.Sign(body, testSecret)
+			_, wrongSecret := adapter.Sign(body, "not the secret")
+			_, otherBody := adapter.Sign([]byte(`{"id": "forged"}`), testSecret)
+
+			cases := []struct {
+				name   string
+				header string
+				value  string
+				want   int
+			}{
+				{"good", header, good, http.StatusOK},
+				{"wrong secret", header, wrongSecret, http.StatusUnauthorized},
+				{"other body", header, otherBody, http.StatusUnauthorized},
+				{"garbled", header, "%%%", http.StatusUnauthorized},
+				{"missing", "", "", http.StatusUnauthorized},
+			}
+			for _, c := range cases {
+				resp := postWebhook(t, server.URL, body, c.header, c.value)
+				resp.Body.Close()
+				if resp.StatusCode != c.want {
+					t.Errorf("%s signature: got %d, want %d", c.name, resp.StatusCode, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMapsExternalItems(t *testing.T) {
+	for _, adapter := range adapters {
+		t.Run(adapter.Name(), func(t *testing.T) {
+			server := webhookServer(adapter)
+			defer server.Close()
+			body := []byte(testOrders[adapter.Name()])
+			header, signature := adapter.Sign(body, testSecret)
+
+			resp := postWebhook(t, server.URL, body, header, signature)
+			defer resp.Body.Close()
+			var order ExternalOrder
+			if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+				t.Fatal(err)
+			}
+
+			if order.Platform != adapter.Name() || order.Type != "DELIVERY" {
+				t.Errorf("got platform %q type %q", order.Platform, order.Type)
+			}
+			if order.Customer.Name != "Ada Lovelace" || order.Address == nil || order.Address.City != "Springfield" {
+				t.Errorf("customer or address not mapped: %+v %+v", order.Customer, order.Address)
+			}
+			want := []ExternalItem{
+				{ExternalID: "burger-1", Name: "Burger", Quantity: 2, UnitPrice: 12.5},
+				{ExternalID: "fries-1", Name: "Fries", Quantity: 1, UnitPrice: 3.99, Notes: "no salt"},
+			}
+			if len(order.Items) != len(want) {
+				t.Fatalf("got %d items, want %d", len(order.Items), len(want))
+			}
+			for i := range want {
+				if order.Items[i] != want[i] {
+					t.Errorf("item %d: got %+v, want %+v", i, order.Items[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsOrderWithoutID(t *testing.T) {
+	for _, adapter := range adapters {
+		if _, err := adapter.Parse([]byte(`{"items": []}`)); err == nil {
+			t.Errorf("%s: parsed an order without an id", adapter.Name())
+		}
+	}
+}
+
+func TestStatusClientPushesSignedStatus(t *testing.T) {
+	want := map[string]string{"ubereats": "accepted", "doordash": "CONFIRMED"}
+	for _, adapter := range adapters {
+		t.Run(adapter.Name(), func(t *testing.T) {
+			var got map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if err := adapter.Verify(r.Header, body, testSecret); err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				json.Unmarshal(body, &got)
+			}))
+			defer server.Close()
+
+			client := &HTTPStatusClient{Adapter: adapter, URL: server.URL, Secret: testSecret}
+			if err := client.UpdateStatus(context.Background(), "ext-1", "PREPARING"); err != nil {
+				t.Fatal(err)
+			}
+			if got["order_id"] != "ext-1" || got["status"] != want[adapter.Name()] {
+				t.Errorf("platform got %v", got)
+			}
+		})
+	}
+}
+
+func TestStatusClientSkipsUnmappedStatus(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	client := &HTTPStatusClient{Adapter: DoorDash{}, URL: server.URL, Secret: testSecret}
+	if err := client.UpdateStatus(context.Background(), "ext-1", "DELIVERED"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("pushed a status DoorDash has no name for")
+	}
+}
+
+func TestStatusClientReportsRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := &HTTPStatusClient{Adapter: UberEats{}, URL: server.URL, Secret: testSecret}
+	if err := client.UpdateStatus(context.Background(), "ext-1", "PREPARING"); err == nil {
+		t.Fatal("want an error for a 502")
+	}
+}
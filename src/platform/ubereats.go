@@ -0,0 +1,119 @@
+package platform
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UberEats signs the body with a hex HMAC-SHA256 in X-Uber-Signature and
+// sends amounts in cents
+type UberEats struct{}
+
+type uberEatsOrder struct {
+	ID        string `json:"id"`
+	DisplayID string `json:"display_id"`
+	Type      string `json:"type"`
+	Eater     struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Phone     string `json:"phone"`
+	} `json:"eater"`
+	Cart struct {
+		Items []struct {
+			ID       string `json:"id"`
+			Title    string `json:"title"`
+			Quantity int    `json:"quantity"`
+			Price    struct {
+				UnitPrice struct {
+					Amount int64 `json:"amount"`
+				} `json:"unit_price"`
+			} `json:"price"`
+			SpecialInstructions string `json:"special_instructions"`
+		} `json:"items"`
+		SpecialInstructions string `json:"special_instructions"`
+	} `json:"cart"`
+	Delivery *struct {
+		Location struct {
+			StreetAddress string `json:"street_address"`
+			UnitNumber    string `json:"unit_number"`
+			City          string `json:"city"`
+			PostalCode    string `json:"postal_code"`
+		} `json:"location"`
+		Notes string `json:"notes"`
+	} `json:"delivery"`
+	EstimatedReadyForPickupAt *time.Time `json:"estimated_ready_for_pickup_at"`
+}
+
+func (UberEats) Name() string { return "ubereats" }
+
+func (UberEats) Verify(header http.Header, body []byte, secret string) error {
+	got, _ := hex.DecodeString(header.Get("X-Uber-Signature"))
+	return checkSignature(got, body, secret)
+}
+
+func (UberEats) Parse(body []byte) (ExternalOrder, error) {
+	var payload uberEatsOrder
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ExternalOrder{}, err
+	}
+	if payload.ID == "" {
+		return ExternalOrder{}, fmt.Errorf("ubereats: order id is missing")
+	}
+
+	order := ExternalOrder{
+		Platform:   "ubereats",
+		ExternalID: payload.ID,
+		DisplayID:  payload.DisplayID,
+		Type:       "DELIVERY",
+		Customer: Customer{
+			Name:  strings.TrimSpace(payload.Eater.FirstName + " " + payload.Eater.LastName),
+			Phone: payload.Eater.Phone,
+		},
+		PickupTime: payload.EstimatedReadyForPickupAt,
+		Notes:      payload.Cart.SpecialInstructions,
+	}
+	if payload.Type == "PICK_UP" {
+		order.Type = "TAKEAWAY"
+	}
+	if payload.Delivery != nil {
+		order.Address = &Address{
+			Line1:      payload.Delivery.Location.StreetAddress,
+			Line2:      payload.Delivery.Location.UnitNumber,
+			City:       payload.Delivery.Location.City,
+			PostalCode: payload.Delivery.Location.PostalCode,
+			Notes:      payload.Delivery.Notes,
+		}
+	}
+	for _, item := range payload.Cart.Items {
+		order.Items = append(order.Items, ExternalItem{
+			ExternalID: item.ID,
+			Name:       item.Title,
+			Quantity:   item.Quantity,
+			UnitPrice:  float64(item.Price.UnitPrice.Amount) / 100,
+			Notes:      item.SpecialInstructions,
+		})
+	}
+	return order, nil
+}
+
+func (UberEats) Status(status string) (string, bool) {
+	switch status {
+	case "PREPARING":
+		return "accepted", true
+	case "READY_FOR_PICKUP":
+		return "ready_for_pickup", true
+	case "CANCELLED":
+		return "denied", true
+	case "PICKED_UP", "OUT_FOR_DELIVERY", "DELIVERED":
+		return "completed", true
+	}
+	return "", false
+}
+
+func (UberEats) Sign(body []byte, secret string) (string, string) {
+	return "X-Uber-Signature", hex.EncodeToString(sign(body, secret))
+}
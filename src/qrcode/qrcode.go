@@ -0,0 +1,463 @@
+// Package qrcode encodes short byte strings, such as table ordering links,
+// as QR codes (byte mode, error correction level M) and renders them as
+// PNG or SVG.
+package qrcode
+
+import (
+	"errors"
+)
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+type QRCode struct {
+	Version int
+	Size    int
+	Modules [][]bool
+}
+
+// level M tables indexed by version
+var eccCodewordsPerBlock = []int{-1,
+	10, 16, 26, 18, 24, 16, 18, 22, 22, 26,
+	30, 22, 22, 24, 24, 28, 28, 26, 26, 26,
+	26, 28, 28, 28, 28, 28, 28, 28, 28, 28,
+	28, 28, 28, 28, 28, 28, 28, 28, 28, 28,
+}
+
+var numErrorCorrectionBlocks = []int{-1,
+	1, 1, 1, 2, 2, 4, 4, 4, 5, 5,
+	5, 8, 9, 9, 10, 10, 11, 13, 14, 16,
+	17, 17, 18, 20, 21, 23, 25, 26, 28, 29,
+	31, 33, 35, 37, 38, 40, 43, 45, 47, 49,
+}
+
+// level M format bits
+const eccFormatBits = 0
+
+func Encode(data []byte) (*QRCode, error) {
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if 4+charCountBits(v)+len(data)*8 <= numDataCodewords(v)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := encodeData(data, version)
+	codewords = addErrorCorrection(codewords, version)
+
+	qr := newQRCode(version)
+	isFunction := qr.drawFunctionPatterns()
+	qr.drawCodewords(codewords, isFunction)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask, isFunction)
+		qr.drawFormatBits(mask)
+		penalty := qr.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		// masking is an xor, applying it again undoes it
+		qr.applyMask(mask, isFunction)
+	}
+	qr.applyMask(bestMask, isFunction)
+	qr.drawFormatBits(bestMask)
+	return qr, nil
+}
+
+func newQRCode(version int) *QRCode {
+	size := version*4 + 17
+	modules := make([][]bool, size)
+	for i := range modules {
+		modules[i] = make([]bool, size)
+	}
+	return &QRCode{Version: version, Size: size, Modules: modules}
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func numRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func numDataCodewords(version int) int {
+	return numRawDataModules(version)/8 - eccCodewordsPerBlock[version]*numErrorCorrectionBlocks[version]
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) appendBits(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 != 0)
+	}
+}
+
+func encodeData(data []byte, version int) []byte {
+	capacity := numDataCodewords(version) * 8
+
+	var bits bitBuffer
+	bits.appendBits(0x4, 4)
+	bits.appendBits(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.appendBits(int(b), 8)
+	}
+
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.appendBits(0, terminator)
+	bits.appendBits(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.appendBits(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i>>3] |= 1 << uint(7-i&7)
+		}
+	}
+	return codewords
+}
+
+// addErrorCorrection splits the data into blocks, appends each block's
+// Reed-Solomon codewords and interleaves the result
+func addErrorCorrection(data []byte, version int) []byte {
+	numBlocks := numErrorCorrectionBlocks[version]
+	blockEccLen := eccCodewordsPerBlock[version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := reedSolomonDivisor(blockEccLen)
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i < numBlocks; i++ {
+		dataLen := shortBlockLen - blockEccLen
+		if i >= numShortBlocks {
+			dataLen++
+		}
+		block := append([]byte{}, data[k:k+dataLen]...)
+		k += dataLen
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			// skip the padding byte of short blocks
+			if i != shortBlockLen-blockEccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func gfMultiply(x byte, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	size := version*4 + 17
+	numAlign := version/7 + 2
+	step := (version*4 + numAlign*2 + 1) / (numAlign*2 - 2) * 2
+	if version == 32 {
+		step = 26
+	}
+	positions := make([]int, numAlign)
+	positions[0] = 6
+	for i, pos := numAlign-1, size-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func (qr *QRCode) set(x int, y int, dark bool, isFunction [][]bool) {
+	qr.Modules[y][x] = dark
+	if isFunction != nil {
+		isFunction[y][x] = true
+	}
+}
+
+func (qr *QRCode) drawFunctionPatterns() [][]bool {
+	isFunction := make([][]bool, qr.Size)
+	for i := range isFunction {
+		isFunction[i] = make([]bool, qr.Size)
+	}
+
+	for i := 0; i < qr.Size; i++ {
+		qr.set(6, i, i%2 == 0, isFunction)
+		qr.set(i, 6, i%2 == 0, isFunction)
+	}
+
+	for _, center := range [][2]int{{3, 3}, {qr.Size - 4, 3}, {3, qr.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x < 0 || x >= qr.Size || y < 0 || y >= qr.Size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				qr.set(x, y, dist != 2 && dist != 4, isFunction)
+			}
+		}
+	}
+
+	positions := alignmentPositions(qr.Version)
+	last := len(positions) - 1
+	for i, px := range positions {
+		for j, py := range positions {
+			// the three corners already hold finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.set(px+dx, py+dy, max(abs(dx), abs(dy)) != 1, isFunction)
+				}
+			}
+		}
+	}
+
+	// reserve the format areas, the real bits are drawn after masking
+	qr.drawFormatBitsInto(0, isFunction)
+
+	if qr.Version >= 7 {
+		rem := qr.Version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := qr.Version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := (bits>>uint(i))&1 != 0
+			a := qr.Size - 11 + i%3
+			b := i / 3
+			qr.set(a, b, dark, isFunction)
+			qr.set(b, a, dark, isFunction)
+		}
+	}
+	return isFunction
+}
+
+func formatBits(mask int) int {
+	data := eccFormatBits<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func (qr *QRCode) drawFormatBits(mask int) {
+	qr.drawFormatBitsInto(mask, nil)
+}
+
+func (qr *QRCode) drawFormatBitsInto(mask int, isFunction [][]bool) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		qr.set(8, i, bit(i), isFunction)
+	}
+	qr.set(8, 7, bit(6), isFunction)
+	qr.set(8, 8, bit(7), isFunction)
+	qr.set(7, 8, bit(8), isFunction)
+	for i := 9; i < 15; i++ {
+		qr.set(14-i, 8, bit(i), isFunction)
+	}
+
+	for i := 0; i < 8; i++ {
+		qr.set(qr.Size-1-i, 8, bit(i), isFunction)
+	}
+	for i := 8; i < 15; i++ {
+		qr.set(8, qr.Size-15+i, bit(i), isFunction)
+	}
+	qr.set(8, qr.Size-8, true, isFunction)
+}
+
+func (qr *QRCode) drawCodewords(data []byte, isFunction [][]bool) {
+	i := 0
+	for right := qr.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < qr.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = qr.Size - 1 - vert
+				}
+				if !isFunction[y][x] && i < len(data)*8 {
+					qr.Modules[y][x] = (data[i>>3]>>uint(7-i&7))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (qr *QRCode) applyMask(mask int, isFunction [][]bool) {
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !isFunction[y][x] {
+				qr.Modules[y][x] = !qr.Modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol with the four rules of ISO 18004 section 7.8.3
+func (qr *QRCode) penalty() int {
+	result := 0
+	size := qr.Size
+	at := func(x int, y int, horizontal bool) bool {
+		if horizontal {
+			return qr.Modules[y][x]
+		}
+		return qr.Modules[x][y]
+	}
+
+	for _, horizontal := range []bool{true, false} {
+		for y := 0; y < size; y++ {
+			run := 1
+			for x := 1; x < size; x++ {
+				if at(x, y, horizontal) == at(x-1, y, horizontal) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				result += 3 + run - 5
+			}
+
+			for x := 0; x+10 < size; x++ {
+				pattern := []bool{true, false, true, true, true, false, true, false, false, false, false}
+				forward, backward := true, true
+				for k, dark := range pattern {
+					if at(x+k, y, horizontal) != dark {
+						forward = false
+					}
+					if at(x+k, y, horizontal) != pattern[len(pattern)-1-k] {
+						backward = false
+					}
+				}
+				if forward {
+					result += 40
+				}
+				if backward {
+					result += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if qr.Modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := qr.Modules[y][x]
+				if c == qr.Modules[y][x+1] && c == qr.Modules[y+1][x] && c == qr.Modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+	total := size * size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * 10
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,154 @@
+package qrcode
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var goldenCases = []struct {
+	name    string
+	data    string
+	version int
+}{
+	{"short", "rms", 1},
+	{"guest-link", "http://localhost:8000/guest?t=dDEyOjM.q9Xk2mJ1vLr0s8P", 4},
+	{"version-info", "https://rms.example.com/guest?t=" + strings.Repeat("ab12", 20), 7},
+	{"long-count", "https://rms.example.com/guest?t=" + strings.Repeat("x9Yz", 50), 11},
+}
+
+// text draws the symbol one row per line, # for dark and . for light
+func text(qr *QRCode) []byte {
+	var buf bytes.Buffer
+	for _, row := range qr.Modules {
+		for _, dark := range row {
+			if dark {
+				buf.WriteByte('#')
+			} else {
+				buf.WriteByte('.')
+			}
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			qr, err := Encode([]byte(c.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if qr.Version != c.version || qr.Size != c.version*4+17 {
+				t.Fatalf("got version %d size %d, want version %d", qr.Version, qr.Size, c.version)
+			}
+
+			got := text(qr)
+			golden := filepath.Join("testdata", c.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("symbol differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// level M format strings, mask 0 to 7, from the table in ISO 18004 annex C
+var specFormatBits = []string{
+	"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+	"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+}
+
+func bitsOf(s string) int {
+	n := 0
+	for _, c := range s {
+		n = n<<1 | int(c-'0')
+	}
+	return n
+}
+
+func TestFormatBitsMatchSpec(t *testing.T) {
+	for mask, want := range specFormatBits {
+		if got := formatBits(mask); got != bitsOf(want) {
+			t.Errorf("mask %d: got %015b, want %s", mask, got, want)
+		}
+	}
+}
+
+// the format and version information a scanner reads first has to be
+// what the spec lists for the symbol
+func TestEncodedSymbolInformation(t *testing.T) {
+	versionBits := map[int]string{
+		7:  "000111110010010100",
+		11: "001011101111110110",
+	}
+	for _, c := range goldenCases {
+		qr, err := Encode([]byte(c.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		format := 0
+		for i := 14; i >= 0; i-- {
+			x, y := 8, qr.Size-15+i
+			if i < 8 {
+				x, y = qr.Size-1-i, 8
+			}
+			format <<= 1
+			if qr.Modules[y][x] {
+				format |= 1
+			}
+		}
+		mask := -1
+		for m, bits := range specFormatBits {
+			if bitsOf(bits) == format {
+				mask = m
+			}
+		}
+		if mask < 0 {
+			t.Errorf("%s: format bits %015b are not level M", c.name, format)
+		}
+
+		if want, ok := versionBits[c.version]; ok {
+			version := 0
+			for i := 17; i >= 0; i-- {
+				version <<= 1
+				if qr.Modules[i/3][qr.Size-11+i%3] {
+					version |= 1
+				}
+			}
+			if version != bitsOf(want) {
+				t.Errorf("%s: version bits %018b, want %s", c.name, version, want)
+			}
+		}
+	}
+}
+
+// the 1-M "HELLO WORLD" example of ISO 18004 annex I
+func TestReedSolomonMatchesSpecExample(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(make([]byte, 2400)); err != ErrTooLong {
+		t.Fatalf("got %v, want ErrTooLong", err)
+	}
+}
@@ -0,0 +1,71 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// quietZone is the blank border, in modules, scanners need around a code
+const quietZone = 4
+
+func (qr *QRCode) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (qr.Size + quietZone*2) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y, row := range qr.Modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+	return img
+}
+
+func (qr *QRCode) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, qr.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG draws every dark module as part of a single path
+func (qr *QRCode) SVG(scale int) []byte {
+	if scale < 1 {
+		scale = 1
+	}
+	width := qr.Size + quietZone*2
+
+	var path strings.Builder
+	for y, row := range qr.Modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		width*scale, width*scale, width, width)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#FFFFFF"/>`+"\n")
+	fmt.Fprintf(&b, `<path d="%s" fill="#000000"/>`+"\n", path.String())
+	fmt.Fprintf(&b, "</svg>\n")
+	return b.Bytes()
+}
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func AllergenRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/allergens", controller.GetAllergens())
+	incomingRoutes.POST("/allergens", controller.CreateAllergen())
+}
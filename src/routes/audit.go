@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func AuditRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/audit", controller.GetAuditLog())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func CustomerRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/customers", controller.GetCustomers())
+	incomingRoutes.GET("/customers/search", controller.SearchCustomers())
+	incomingRoutes.GET("/customers/duplicates", controller.GetDuplicateCustomers())
+	incomingRoutes.GET("/customers/:customer_id", controller.GetCustomer())
+	incomingRoutes.GET("/customers/:customer_id/visits", controller.GetCustomerVisits())
+	incomingRoutes.POST("/customers", controller.CreateCustomer())
+	incomingRoutes.PATCH("/customers/:customer_id", controller.UpdateCustomer())
+	incomingRoutes.POST("/customers/:customer_id/merge", controller.MergeCustomer())
+}
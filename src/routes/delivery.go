@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func DeliveryRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/orders/queue", controller.GetOrderQueue())
+	incomingRoutes.PATCH("/orders/:order_id/status", controller.UpdateOrderStatus())
+	incomingRoutes.GET("/delivery-zones", controller.GetDeliveryZones())
+	incomingRoutes.GET("/delivery-zones/quote", controller.GetDeliveryQuote())
+	incomingRoutes.POST("/delivery-zones", controller.CreateDeliveryZone())
+	incomingRoutes.PATCH("/delivery-zones/:zone_id", controller.UpdateDeliveryZone())
+	incomingRoutes.DELETE("/delivery-zones/:zone_id", controller.DeleteDeliveryZone())
+}
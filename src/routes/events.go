@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func EventRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/events", controller.GetOutboxEvents())
+	incomingRoutes.POST("/events/:event_id/retry", controller.RetryOutboxEvent())
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func FloorRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/floor", controller.GetFloor())
+	incomingRoutes.GET("/areas", controller.GetAreas())
+	incomingRoutes.POST("/areas", controller.CreateArea())
+	incomingRoutes.PATCH("/areas/:area_id", controller.UpdateArea())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func GiftCardRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/gift-cards", controller.GetGiftCards())
+	incomingRoutes.GET("/gift-cards/:code", controller.GetGiftCard())
+	incomingRoutes.POST("/gift-cards", controller.IssueGiftCard())
+	incomingRoutes.POST("/gift-cards/:code/top-up", controller.TopUpGiftCard())
+	incomingRoutes.POST("/gift-cards/:code/void", controller.VoidGiftCard())
+	incomingRoutes.POST("/invoices/:invoice_id/gift-card", controller.PayWithGiftCard())
+	incomingRoutes.GET("/reports/gift-cards", controller.GetGiftCardReport())
+}
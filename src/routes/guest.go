@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+	middleware "infinity/rms/middleware"
+)
+
+// GuestRoutes are reachable without a staff token. Everything past
+// /guest/session needs a guest token and only sees that guest's table.
+func GuestRoutes(incomingRoutes *gin.Engine) {
+	guest := incomingRoutes.Group("/guest")
+	guest.Use(middleware.RateLimit(60))
+	guest.POST("/session", controller.CreateGuestSession())
+
+	session := guest.Group("")
+	session.Use(middleware.GuestAuth(), middleware.RateLimit(30))
+	session.GET("/menu", controller.GetGuestMenu())
+	session.GET("/order", controller.GetGuestOrder())
+	session.POST("/order", controller.CreateGuestOrder())
+}
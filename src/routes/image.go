@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func PublicImageRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/images/*key", controller.ServeImage())
+}
+
+func ImageRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.POST("/foods/:food_id/image", controller.UploadFoodImage())
+	incomingRoutes.POST("/users/:user_id/avatar", controller.UploadAvatar())
+}
@@ -10,4 +10,5 @@ func InvoiceRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/invoices/:invoice_id", controller.GetInvoice())
 	incomingRoutes.POST("/invoices", controller.CreateInvoice())
 	incomingRoutes.PATCH("/invoices/:invoice_id", controller.UpdateInvoice())
+	incomingRoutes.GET("/invoices/:invoice_id/receipt", controller.GetReceipt())
 }
\ No newline at end of file
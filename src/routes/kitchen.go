@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func KitchenRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/kitchen/tickets/:order_id", controller.GetKitchenTicket())
+	incomingRoutes.POST("/kitchen/tickets/:order_id/bump", controller.BumpKitchenTicket())
+	incomingRoutes.GET("/kitchen/stations/:station/tickets", controller.GetStationTickets())
+	incomingRoutes.GET("/kitchen/alerts", controller.GetKitchenAlerts())
+	incomingRoutes.GET("/reports/kitchen", controller.GetKitchenReport())
+}
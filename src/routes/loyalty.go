@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func LoyaltyRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/loyalty/program", controller.GetLoyaltyProgram())
+	incomingRoutes.PUT("/loyalty/program", controller.UpdateLoyaltyProgram())
+	incomingRoutes.GET("/customers/:customer_id/loyalty", controller.GetLoyaltyAccount())
+	incomingRoutes.POST("/customers/:customer_id/loyalty/adjust", controller.AdjustLoyaltyPoints())
+	incomingRoutes.POST("/invoices/:invoice_id/loyalty/redeem", controller.RedeemLoyaltyPoints())
+}
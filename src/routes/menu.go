@@ -10,4 +10,6 @@ func MenuRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/menus/:menu_id", controller.GetMenu())
 	incomingRoutes.POST("/menus", controller.CreateMenu())
 	incomingRoutes.PATCH("/menus/:menu_id", controller.UpdateMenu())
+	incomingRoutes.POST("/menus/import", controller.ImportMenus())
+	incomingRoutes.GET("/menus/export", controller.ExportMenus())
 }
\ No newline at end of file
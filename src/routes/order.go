@@ -10,4 +10,12 @@ func OrderRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orders/:order_id", controller.GetOrder())
 	incomingRoutes.POST("/orders", controller.CreateOrder())
 	incomingRoutes.PATCH("/orders/:order_id", controller.UpdateOrder())
+	incomingRoutes.POST("/orders/:order_id/confirm-guest-items", controller.ConfirmGuestItems())
+	incomingRoutes.POST("/orders/:order_id/move", controller.MoveOrder())
+	incomingRoutes.POST("/orders/:order_id/split", controller.SplitOrder())
+	incomingRoutes.GET("/orders/scheduled", controller.GetScheduledOrders())
+	incomingRoutes.GET("/orders/slots", controller.GetOrderSlots())
+	incomingRoutes.POST("/orders/:order_id/release", controller.ReleaseOrder())
+	incomingRoutes.POST("/orders/:order_id/courses/:course/fire", controller.FireCourse())
+	incomingRoutes.POST("/orders/:order_id/courses/:course/hold", controller.HoldCourse())
 }
\ No newline at end of file
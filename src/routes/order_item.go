@@ -11,5 +11,6 @@ func OrderItemRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orderItems/orderItems-order/:order_id", controller.GetOrderItemsByOrder())
 	incomingRoutes.POST("/orderItems", controller.CreateOrderItem())
 	incomingRoutes.PATCH("/orderItems/:orderItem_id", controller.UpdateOrderItem())
+	incomingRoutes.POST("/orderItems/:orderItem_id/void", controller.VoidOrderItem())
 	// incomingRoutes.DELETE("/orderItems/:orderItem_id", controller.DeleteOrderItem())
 }
\ No newline at end of file
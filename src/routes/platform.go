@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+// PlatformWebhookRoutes are signed by the platform rather than a staff token
+func PlatformWebhookRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.POST("/platforms/:platform/orders", controller.ReceivePlatformOrder())
+}
+
+func PlatformRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/platforms/:platform/items", controller.GetPlatformItems())
+	incomingRoutes.PUT("/platforms/:platform/items", controller.SavePlatformItem())
+	incomingRoutes.DELETE("/platforms/:platform/items/:external_item_id", controller.DeletePlatformItem())
+}
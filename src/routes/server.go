@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func ServerRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/sections/assignments", controller.GetSectionAssignments())
+	incomingRoutes.POST("/sections/assignments", controller.CreateSectionAssignment())
+	incomingRoutes.DELETE("/sections/assignments/:assignment_id", controller.DeleteSectionAssignment())
+	incomingRoutes.GET("/reports/servers", controller.GetServerReport())
+}
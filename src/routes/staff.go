@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func StaffRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/staff", controller.GetStaff())
+	incomingRoutes.GET("/staff/:staff_id", controller.GetStaffMember())
+	incomingRoutes.POST("/staff", controller.CreateStaff())
+	incomingRoutes.PATCH("/staff/:staff_id", controller.UpdateStaff())
+	incomingRoutes.POST("/staff/:staff_id/clock-in", controller.ClockIn())
+	incomingRoutes.POST("/staff/:staff_id/clock-out", controller.ClockOut())
+	incomingRoutes.POST("/staff/:staff_id/breaks/start", controller.StartBreak())
+	incomingRoutes.POST("/staff/:staff_id/breaks/end", controller.EndBreak())
+	incomingRoutes.GET("/time-entries", controller.GetTimeEntries())
+	incomingRoutes.GET("/shifts", controller.GetShifts())
+	incomingRoutes.POST("/shifts", controller.CreateShift())
+	incomingRoutes.PATCH("/shifts/:shift_id", controller.UpdateShift())
+	incomingRoutes.DELETE("/shifts/:shift_id", controller.DeleteShift())
+	incomingRoutes.GET("/reports/labor", controller.GetLaborReport())
+}
@@ -10,4 +10,9 @@ func TableRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/tables/:table_id", controller.GetTable())
 	incomingRoutes.POST("/tables", controller.CreateTable())
 	incomingRoutes.PATCH("/tables/:table_id", controller.UpdateTable())
+	incomingRoutes.PATCH("/tables/:table_id/status", controller.UpdateTableStatus())
+	incomingRoutes.POST("/tables/merge", controller.MergeTables())
+	incomingRoutes.POST("/tables/:table_id/transfer", controller.TransferTable())
+	incomingRoutes.GET("/tables/:table_id/qr", controller.GetTableQRCode())
+	incomingRoutes.POST("/tables/:table_id/qr/rotate", controller.RotateTableQRCode())
 }
\ No newline at end of file
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func TranslationRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/translations/:entity/:entity_id", controller.GetTranslations())
+	incomingRoutes.PUT("/translations/:entity/:entity_id/:locale", controller.SetTranslation())
+	incomingRoutes.DELETE("/translations/:entity/:entity_id/:locale", controller.DeleteTranslation())
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "infinity/rms/controllers"
+)
+
+func WebhookRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/webhooks", controller.GetWebhooks())
+	incomingRoutes.GET("/webhooks/events", controller.GetWebhookEvents())
+	incomingRoutes.GET("/webhooks/deliveries", controller.GetWebhookDeliveries())
+	incomingRoutes.GET("/webhooks/deliveries/:delivery_id", controller.GetWebhookDelivery())
+	incomingRoutes.POST("/webhooks/deliveries/:delivery_id/replay", controller.ReplayWebhookDelivery())
+	incomingRoutes.GET("/webhooks/dead-letters", controller.GetWebhookDeadLetters())
+	incomingRoutes.POST("/webhooks/dead-letters/replay", controller.ReplayWebhookDeadLetters())
+	incomingRoutes.GET("/webhooks/:webhook_id", controller.GetWebhook())
+	incomingRoutes.POST("/webhooks", controller.CreateWebhook())
+	incomingRoutes.PATCH("/webhooks/:webhook_id", controller.UpdateWebhook())
+	incomingRoutes.DELETE("/webhooks/:webhook_id", controller.DeleteWebhook())
+	incomingRoutes.POST("/webhooks/:webhook_id/ping", controller.PingWebhook())
+}
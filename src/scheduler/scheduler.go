@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs job straight away and then once per interval until ctx is
+// done. A failed run is logged and the job keeps its schedule.
+func Every(ctx context.Context, interval time.Duration, name string, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, interval)
+		if err := job(runCtx); err != nil {
+			log.Printf("scheduler: %s: %v", name, err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
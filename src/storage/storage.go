@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type BlobInfo struct {
+	Key          string
+	ContentType  string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// BlobStore keeps uploaded files such as food images and avatars
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv picks the backend from STORAGE_BACKEND ("local" or "s3")
+func NewFromEnv() BlobStore {
+	if os.Getenv("STORAGE_BACKEND") == "s3" {
+		return &S3Store{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    envOr("S3_REGION", "us-east-1"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+	}
+	return &LocalStore{Root: envOr("STORAGE_DIR", "uploads")}
+}
+
+func envOr(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,89 @@
+package txn
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnitOfWork runs a group of writes as one change. Everything fn writes
+// through the ctx it is given is kept if fn returns nil and rolled back
+// if it returns an error or panics; the error is passed on unchanged. fn
+// may be run again when the store retries a transient conflict, so it
+// must not have effects outside ctx, such as HTTP calls. A unit started
+// with a ctx that already belongs to a unit joins it.
+type UnitOfWork interface {
+	Run(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Mongo runs units as multi-document transactions. It needs MongoDB
+// running as a replica set.
+type Mongo struct {
+	Client *mongo.Client
+}
+
+func NewMongo(client *mongo.Client) *Mongo {
+	return &Mongo{Client: client}
+}
+
+func (u *Mongo) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	session, err := u.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+type memoryUnitKey struct{}
+
+type memoryUnit struct {
+	staged []func()
+}
+
+// Memory gives in-memory stores the same guarantees: units run one at a
+// time, and the writes staged with Stage are applied together once fn
+// succeeds and dropped otherwise. Reads inside a unit see what was there
+// before it, not its own staged writes.
+type Memory struct {
+	mu sync.Mutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (u *Memory) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryUnitKey{}) != nil {
+		return fn(ctx)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	unit := &memoryUnit{}
+	if err := fn(context.WithValue(ctx, memoryUnitKey{}, unit)); err != nil {
+		return err
+	}
+	for _, apply := range unit.staged {
+		apply()
+	}
+	return nil
+}
+
+// Stage is how an in-memory store writes: apply runs when ctx's unit
+// commits, or straight away outside a unit
+func Stage(ctx context.Context, apply func()) {
+	if unit, ok := ctx.Value(memoryUnitKey{}).(*memoryUnit); ok {
+		unit.staged = append(unit.staged, apply)
+		return
+	}
+	apply()
+}
@@ -0,0 +1,77 @@
+package txn
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryCommitAppliesStagedWrites(t *testing.T) {
+	unit := NewMemory()
+	written := []string{}
+
+	err := unit.Run(context.Background(), func(ctx context.Context) error {
+		Stage(ctx, func() { written = append(written, "order") })
+		Stage(ctx, func() { written = append(written, "items") })
+		if len(written) != 0 {
+			t.Fatalf("writes applied before commit: %v", written)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 || written[0] != "order" || written[1] != "items" {
+		t.Fatalf("got %v, want [order items]", written)
+	}
+}
+
+func TestMemoryErrorRollsBack(t *testing.T) {
+	unit := NewMemory()
+	written := 0
+	failed := errors.New("insert failed")
+
+	err := unit.Run(context.Background(), func(ctx context.Context) error {
+		Stage(ctx, func() { written++ })
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("got error %v, want it passed on unchanged", err)
+	}
+	if written != 0 {
+		t.Fatalf("rolled back unit applied %d writes", written)
+	}
+}
+
+func TestMemoryNestedUnitJoinsOuter(t *testing.T) {
+	unit := NewMemory()
+	written := 0
+
+	err := unit.Run(context.Background(), func(ctx context.Context) error {
+		err := unit.Run(ctx, func(ctx context.Context) error {
+			Stage(ctx, func() { written++ })
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if written != 0 {
+			t.Fatal("nested unit committed on its own")
+		}
+		return errors.New("outer failed")
+	})
+	if err == nil {
+		t.Fatal("want the outer error")
+	}
+	if written != 0 {
+		t.Fatal("nested writes survived the outer rollback")
+	}
+}
+
+func TestStageOutsideUnitAppliesAtOnce(t *testing.T) {
+	written := false
+	Stage(context.Background(), func() { written = true })
+	if !written {
+		t.Fatal("write outside a unit was not applied")
+	}
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	EventHeader     = "X-RMS-Event"
+	DeliveryHeader  = "X-RMS-Delivery"
+	TimestampHeader = "X-RMS-Timestamp"
+	SignatureHeader = "X-RMS-Signature"
+)
+
+var ErrBadSignature = errors.New("webhook: signature doesn't match")
+
+// Sign is the HMAC-SHA256 of "<timestamp>.<body>", written as
+// "sha256=<hex>". Signing the timestamp lets receivers reject old
+// requests that are sent again.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received request the way a subscriber should, refusing
+// timestamps further than tolerance from now
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, timestamp, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// NewSecret makes a random signing secret for a subscription
+func NewSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Result is what the subscriber answered, with the start of its body for
+// the delivery log
+type Result struct {
+	StatusCode int
+	Response   string
+	Duration   time.Duration
+}
+
+type Sender struct {
+	Client *http.Client
+}
+
+// Send posts one signed delivery. Anything but a 2xx answer is an error.
+func (s *Sender) Send(ctx context.Context, request Request) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return Result{}, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, request.Event)
+	req.Header.Set(DeliveryHeader, request.DeliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(request.Secret, timestamp, request.Body))
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	result := Result{Duration: time.Since(started)}
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	result.StatusCode = resp.StatusCode
+	result.Response = string(response)
+	if resp.StatusCode/100 != 2 {
+		return result, fmt.Errorf("webhook: subscriber answered %s", resp.Status)
+	}
+	return result, nil
+}
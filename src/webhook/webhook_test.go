@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testBody = []byte(`{"order_id":"o-1"}`)
+
+func TestSignIsHMACOfTimestampAndBody(t *testing.T) {
+	// worked out independently with Python's hmac module
+	want := "sha256=2c6212b85187db661f8e63bd32ee39ce2e2001f321c4e78f660f087fdfb3b3c6"
+	if got := Sign("whsec_test", 1700000000, testBody); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func signedHeader(secret string, timestamp int64, body []byte) http.Header {
+	header := http.Header{}
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now().Unix()
+	cases := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		ok     bool
+	}{
+		{"signed", signedHeader("whsec_test", now, testBody), testBody, true},
+		{"changed body", signedHeader("whsec_test", now, testBody), []byte(`{"order_id":"o-2"}`), false},
+		{"other secret", signedHeader("whsec_other", now, testBody), testBody, false},
+		{"too old", signedHeader("whsec_test", now-600, testBody), testBody, false},
+		{"from the future", signedHeader("whsec_test", now+600, testBody), testBody, false},
+		{"no timestamp", http.Header{SignatureHeader: {Sign("whsec_test", now, testBody)}}, testBody, false},
+	}
+	for _, c := range cases {
+		err := Verify("whsec_test", c.header, c.body, 5*time.Minute)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+	}
+}
+
+func TestSendDeliversSignedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get(EventHeader) != "order.created" || r.Header.Get(DeliveryHeader) != "d-1" {
+			t.Errorf("got event %q delivery %q", r.Header.Get(EventHeader), r.Header.Get(DeliveryHeader))
+		}
+		if err := Verify("whsec_test", r.Header, body, time.Minute); err != nil {
+			t.Errorf("subscriber can't verify the delivery: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := &Sender{Client: server.Client()}
+	result, err := sender.Send(context.Background(), Request{
+		URL:        server.URL,
+		Secret:     "whsec_test",
+		Event:      "order.created",
+		DeliveryID: "d-1",
+		Body:       testBody,
+	})
+	if err != nil || result.StatusCode != http.StatusNoContent {
+		t.Fatalf("got %+v %v", result, err)
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, strings.Repeat("x", 1000))
+	}))
+	defer server.Close()
+
+	sender := &Sender{Client: server.Client()}
+	result, err := sender.Send(context.Background(), Request{URL: server.URL, Secret: "whsec_test", Body: testBody})
+	if err == nil {
+		t.Fatal("a 500 answer counted as delivered")
+	}
+	if result.StatusCode != http.StatusInternalServerError || len(result.Response) != 512 {
+		t.Fatalf("got status %d and %d bytes of response, want 500 and 512", result.StatusCode, len(result.Response))
+	}
+}
+
+func TestSendFailsWhenUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	if _, err := (&Sender{}).Send(context.Background(), Request{URL: url, Body: testBody}); err == nil {
+		t.Fatal("delivered to a closed server")
+	}
+}